@@ -0,0 +1,394 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/acronis/go-appkit/log"
+)
+
+// ErrOpenState is returned when the request is rejected since the circuit breaker is open.
+var ErrOpenState = errors.New("circuit breaker is open")
+
+// ErrTooManyProbes is returned when the request is rejected since the circuit breaker is half-open
+// and the maximum number of probe requests is already sent.
+var ErrTooManyProbes = errors.New("circuit breaker is half-open, too many probe requests")
+
+// State represents a state of the circuit breaker.
+type State int
+
+// Circuit breaker states.
+const (
+	// StateClosed means that requests are allowed, and their results are tracked.
+	StateClosed State = iota
+	// StateOpen means that requests are rejected until the cooldown period is over.
+	StateOpen
+	// StateHalfOpen means that the limited number of probe requests is allowed to check if the downstream is recovered.
+	StateHalfOpen
+)
+
+// States contains all circuit breaker states.
+var States = []State{StateClosed, StateOpen, StateHalfOpen}
+
+// String returns a string representation of the state.
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// Result represents a result of the request that is reported to the circuit breaker.
+type Result int
+
+// Request results.
+const (
+	// ResultSuccess means that the request succeeded.
+	ResultSuccess Result = iota
+	// ResultFailure means that the request failed.
+	ResultFailure
+	// ResultIgnored means that the request is neither a success nor a failure (e.g., it's canceled by the caller).
+	// It releases the probe slot in the half-open state, but doesn't affect the state of the circuit breaker.
+	ResultIgnored
+)
+
+// Default values for Opts.
+const (
+	DefaultName                = "default"
+	DefaultConsecutiveFailures = 5
+	DefaultMinRequests         = 10
+	DefaultWindow              = 10 * time.Second
+	DefaultWindowBuckets       = 10
+	DefaultCooldown            = 5 * time.Second
+	DefaultHalfOpenProbes      = 1
+)
+
+// Opts contains optional parameters for constructing Breaker.
+type Opts struct {
+	// Name is used in logs and metrics. DefaultName is used if empty.
+	Name string
+
+	// ConsecutiveFailures is a number of consecutive failures after which the circuit breaker is opened.
+	// Zero value disables this trigger. If both triggers are disabled,
+	// DefaultConsecutiveFailures is used for this one.
+	ConsecutiveFailures int
+
+	// FailureRateThreshold is a ratio (0, 1] of failed requests within the sliding window
+	// after which the circuit breaker is opened. Zero value disables this trigger.
+	FailureRateThreshold float64
+
+	// MinRequests is a minimum number of requests within the sliding window
+	// that is required to evaluate the failure rate. DefaultMinRequests is used if zero.
+	MinRequests int
+
+	// Window is a size of the sliding window for evaluating the failure rate. DefaultWindow is used if zero.
+	Window time.Duration
+
+	// WindowBuckets is a number of buckets the sliding window is split into. DefaultWindowBuckets is used if zero.
+	// It's reduced to the number of nanoseconds in Window if it's greater, so every bucket is at least 1ns long.
+	WindowBuckets int
+
+	// Cooldown is a time during which the circuit breaker stays open before it becomes half-open.
+	// DefaultCooldown is used if zero.
+	Cooldown time.Duration
+
+	// HalfOpenProbes is a number of probe requests that are allowed in the half-open state.
+	// The circuit breaker is closed when all of them succeed, and it's opened again on the first failure.
+	// DefaultHalfOpenProbes is used if zero.
+	HalfOpenProbes int
+
+	// ClassifyResult determines the result of the request by the error returned by the function passed to Do.
+	// By default, nil error is a success, context.Canceled is ignored, and any other error is a failure.
+	ClassifyResult func(err error) Result
+
+	// OnStateChange is called when the state of the circuit breaker is changed.
+	OnStateChange func(from, to State)
+
+	// MetricsCollector collects metrics of the circuit breaker (e.g., PrometheusMetrics).
+	MetricsCollector MetricsCollector
+}
+
+type stateChange struct {
+	from State
+	to   State
+}
+
+// Breaker is a circuit breaker.
+//
+// It's closed initially and tracks results of requests. When the number of consecutive failures
+// or the failure rate within the sliding window exceeds the threshold, the circuit breaker is opened,
+// and all requests are rejected during the cooldown period. After that, it becomes half-open
+// and allows a limited number of probe requests. If all of them succeed, the circuit breaker is closed,
+// otherwise it's opened again.
+type Breaker struct {
+	name                 string
+	logger               log.FieldLogger
+	consecutiveFailures  int
+	failureRateThreshold float64
+	minRequests          int
+	cooldown             time.Duration
+	halfOpenProbes       int
+	classifyResult       func(err error) Result
+	onStateChange        func(from, to State)
+	metricsCollector     MetricsCollector
+	now                  func() time.Time
+
+	mu                  sync.Mutex
+	state               State
+	generation          uint64
+	window              *slidingWindow
+	failuresInRow       int
+	openedAt            time.Time
+	probesInFlight      int
+	probeSuccesses      int
+	pendingStateChanges []stateChange
+	notifying           bool
+}
+
+// New creates a new Breaker with default options.
+func New(logger log.FieldLogger) *Breaker {
+	return NewWithOpts(logger, Opts{})
+}
+
+// NewWithOpts creates a new Breaker with the provided options.
+func NewWithOpts(logger log.FieldLogger, opts Opts) *Breaker {
+	if opts.Name == "" {
+		opts.Name = DefaultName
+	}
+	if opts.ConsecutiveFailures <= 0 && opts.FailureRateThreshold <= 0 {
+		opts.ConsecutiveFailures = DefaultConsecutiveFailures
+	}
+	if opts.MinRequests <= 0 {
+		opts.MinRequests = DefaultMinRequests
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	if opts.WindowBuckets <= 0 {
+		opts.WindowBuckets = DefaultWindowBuckets
+	}
+	if time.Duration(opts.WindowBuckets) > opts.Window {
+		opts.WindowBuckets = int(opts.Window)
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = DefaultCooldown
+	}
+	if opts.HalfOpenProbes <= 0 {
+		opts.HalfOpenProbes = DefaultHalfOpenProbes
+	}
+	if opts.ClassifyResult == nil {
+		opts.ClassifyResult = defaultClassifyResult
+	}
+	if opts.MetricsCollector == nil {
+		opts.MetricsCollector = disabledMetrics{}
+	}
+	b := &Breaker{
+		name:                 opts.Name,
+		logger:               logger.With(log.String("circuit_breaker", opts.Name)),
+		consecutiveFailures:  opts.ConsecutiveFailures,
+		failureRateThreshold: opts.FailureRateThreshold,
+		minRequests:          opts.MinRequests,
+		cooldown:             opts.Cooldown,
+		halfOpenProbes:       opts.HalfOpenProbes,
+		classifyResult:       opts.ClassifyResult,
+		onStateChange:        opts.OnStateChange,
+		metricsCollector:     opts.MetricsCollector,
+		now:                  time.Now,
+		state:                StateClosed,
+		window:               newSlidingWindow(opts.Window, opts.WindowBuckets),
+	}
+	b.metricsCollector.SetState(b.name, StateClosed)
+	return b
+}
+
+func defaultClassifyResult(err error) Result {
+	switch {
+	case err == nil:
+		return ResultSuccess
+	case errors.Is(err, context.Canceled):
+		return ResultIgnored
+	default:
+		return ResultFailure
+	}
+}
+
+// Name returns the name of the circuit breaker.
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state of the circuit breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.unlockAndNotify()
+	b.refreshState(b.now())
+	return b.state
+}
+
+// Allow checks if the request may be sent. If it's allowed, the returned function must be called exactly once
+// with the result of the request. Otherwise, ErrOpenState or ErrTooManyProbes is returned.
+func (b *Breaker) Allow() (done func(result Result), err error) {
+	b.mu.Lock()
+	b.refreshState(b.now())
+	switch b.state {
+	case StateOpen:
+		err = ErrOpenState
+	case StateHalfOpen:
+		if b.probesInFlight+b.probeSuccesses >= b.halfOpenProbes {
+			err = ErrTooManyProbes
+		} else {
+			b.probesInFlight++
+		}
+	}
+	generation := b.generation
+	b.unlockAndNotify()
+
+	if err != nil {
+		b.metricsCollector.IncRejectedRequests(b.name)
+		return nil, err
+	}
+	return func(result Result) { b.onDone(generation, result) }, nil
+}
+
+// Do calls fn if the circuit breaker allows it and records its result.
+// Opts.ClassifyResult determines the result by the returned error. If fn panics, it's recorded as a failure.
+func (b *Breaker) Do(fn func() error) (err error) {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	result := ResultFailure
+	defer func() { done(result) }()
+	err = fn()
+	result = b.classifyResult(err)
+	return err
+}
+
+func (b *Breaker) onDone(generation uint64, result Result) {
+	b.mu.Lock()
+	defer b.unlockAndNotify()
+
+	now := b.now()
+	b.refreshState(now)
+	if generation != b.generation {
+		return // The request was allowed in the previous state, so its result is not relevant anymore.
+	}
+
+	switch b.state {
+	case StateClosed:
+		if result == ResultIgnored {
+			return
+		}
+		b.window.record(now, result == ResultSuccess)
+		if result == ResultSuccess {
+			b.failuresInRow = 0
+			return
+		}
+		b.failuresInRow++
+		if b.shouldOpen(now) {
+			b.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		b.probesInFlight--
+		switch result {
+		case ResultIgnored:
+			return
+		case ResultFailure:
+			b.setState(StateOpen, now)
+			return
+		}
+		b.probeSuccesses++
+		if b.probeSuccesses >= b.halfOpenProbes {
+			b.setState(StateClosed, now)
+		}
+	}
+}
+
+func (b *Breaker) shouldOpen(now time.Time) bool {
+	if b.consecutiveFailures > 0 && b.failuresInRow >= b.consecutiveFailures {
+		return true
+	}
+	if b.failureRateThreshold > 0 {
+		successes, failures := b.window.counts(now)
+		total := successes + failures
+		return total >= b.minRequests && float64(failures)/float64(total) >= b.failureRateThreshold
+	}
+	return false
+}
+
+func (b *Breaker) refreshState(now time.Time) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.cooldown {
+		b.setState(StateHalfOpen, now)
+	}
+}
+
+func (b *Breaker) setState(state State, now time.Time) {
+	b.pendingStateChanges = append(b.pendingStateChanges, stateChange{from: b.state, to: state})
+	b.state = state
+	b.generation++
+	b.window.reset()
+	b.failuresInRow = 0
+	b.probesInFlight = 0
+	b.probeSuccesses = 0
+	if state == StateOpen {
+		b.openedAt = now
+	}
+}
+
+// unlockAndNotify unlocks the mutex and then reports the pending state changes,
+// so callbacks may safely use the circuit breaker.
+// Changes are reported in order by one goroutine at a time. If another goroutine is already reporting them,
+// it reports the new changes as well, so the mutex is just unlocked.
+func (b *Breaker) unlockAndNotify() {
+	if b.notifying || len(b.pendingStateChanges) == 0 {
+		b.mu.Unlock()
+		return
+	}
+	b.notifying = true
+	locked := true
+	defer func() {
+		if !locked { // Callback panicked.
+			b.mu.Lock()
+		}
+		b.notifying = false
+		b.mu.Unlock()
+	}()
+	for len(b.pendingStateChanges) != 0 {
+		changes := b.pendingStateChanges
+		b.pendingStateChanges = nil
+		b.mu.Unlock()
+		locked = false
+		b.notify(changes)
+		b.mu.Lock()
+		locked = true
+	}
+}
+
+func (b *Breaker) notify(changes []stateChange) {
+	for _, change := range changes {
+		logFields := []log.Field{log.String("from", change.from.String()), log.String("to", change.to.String())}
+		if change.to == StateOpen {
+			b.logger.Warn("circuit breaker state is changed", logFields...)
+		} else {
+			b.logger.Info("circuit breaker state is changed", logFields...)
+		}
+		b.metricsCollector.SetState(b.name, change.to)
+		if b.onStateChange != nil {
+			b.onStateChange(change.from, change.to)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package breaker
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/acronis/go-appkit/log"
+	"github.com/acronis/go-appkit/log/logtest"
+)
+
+type fakeNow struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeNow) get() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeNow) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func newTestBreaker(logger log.FieldLogger, opts Opts) (*Breaker, *fakeNow) {
+	now := &fakeNow{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := NewWithOpts(logger, opts)
+	b.now = now.get
+	return b, now
+}
+
+var errTest = errors.New("test error")
+
+func failN(b *Breaker, n int) {
+	for i := 0; i < n; i++ {
+		_ = b.Do(func() error { return errTest })
+	}
+}
+
+func TestBreaker_ConsecutiveFailures(t *testing.T) {
+	logRecorder := logtest.NewRecorder()
+	promMetrics := NewPrometheusMetrics()
+	var stateChanges []State
+	b, now := newTestBreaker(logRecorder, Opts{
+		Name:                "test",
+		ConsecutiveFailures: 3,
+		Cooldown:            time.Second,
+		HalfOpenProbes:      2,
+		MetricsCollector:    promMetrics,
+		OnStateChange:       func(from, to State) { stateChanges = append(stateChanges, to) },
+	})
+
+	failN(b, 2)
+	require.NoError(t, b.Do(func() error { return nil })) // Success resets the counter.
+	failN(b, 2)
+	require.Equal(t, StateClosed, b.State())
+	failN(b, 1)
+	require.Equal(t, StateOpen, b.State())
+	require.Equal(t, 1.0, testutil.ToFloat64(promMetrics.State.WithLabelValues("test", "open")))
+	require.Equal(t, 0.0, testutil.ToFloat64(promMetrics.State.WithLabelValues("test", "closed")))
+	logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelWarn),
+		logtest.HasMessage("circuit breaker state is changed"), logtest.FieldEquals("circuit_breaker", "test"),
+		logtest.FieldEquals("from", "closed"), logtest.FieldEquals("to", "open"))
+
+	called := false
+	err := b.Do(func() error { called = true; return nil })
+	require.ErrorIs(t, err, ErrOpenState)
+	require.False(t, called)
+	require.Equal(t, 1.0, testutil.ToFloat64(promMetrics.RejectedRequests.WithLabelValues("test")))
+
+	// Cooldown is over, only 2 probes are allowed.
+	now.advance(time.Second)
+	require.Equal(t, StateHalfOpen, b.State())
+	done1, err := b.Allow()
+	require.NoError(t, err)
+	done2, err := b.Allow()
+	require.NoError(t, err)
+	_, err = b.Allow()
+	require.ErrorIs(t, err, ErrTooManyProbes)
+
+	done1(ResultSuccess)
+	require.Equal(t, StateHalfOpen, b.State())
+	done2(ResultSuccess)
+	require.Equal(t, StateClosed, b.State())
+	require.Equal(t, []State{StateOpen, StateHalfOpen, StateClosed}, stateChanges)
+	logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelInfo),
+		logtest.FieldEquals("from", "half-open"), logtest.FieldEquals("to", "closed"))
+}
+
+func TestBreaker_FailedProbe(t *testing.T) {
+	b, now := newTestBreaker(logtest.NewRecorder(), Opts{ConsecutiveFailures: 1, Cooldown: time.Second})
+
+	// The request that is started in the closed state and finished after opening is ignored.
+	staleDone, err := b.Allow()
+	require.NoError(t, err)
+	failN(b, 1)
+	require.Equal(t, StateOpen, b.State())
+	now.advance(time.Second)
+	staleDone(ResultSuccess)
+	require.Equal(t, StateHalfOpen, b.State())
+
+	failN(b, 1)
+	require.Equal(t, StateOpen, b.State())
+	now.advance(time.Millisecond * 500)
+	require.Equal(t, StateOpen, b.State())
+	now.advance(time.Millisecond * 500)
+	require.Equal(t, StateHalfOpen, b.State())
+}
+
+func TestBreaker_PanicIsFailure(t *testing.T) {
+	b, now := newTestBreaker(logtest.NewRecorder(), Opts{ConsecutiveFailures: 1, Cooldown: time.Second})
+	require.Panics(t, func() { _ = b.Do(func() error { panic("closed") }) })
+	require.Equal(t, StateOpen, b.State())
+
+	// Panicked probe releases its slot and opens the circuit breaker again.
+	now.advance(time.Second)
+	require.Panics(t, func() { _ = b.Do(func() error { panic("half-open") }) })
+	require.Equal(t, StateOpen, b.State())
+	now.advance(time.Second)
+	require.NoError(t, b.Do(func() error { return nil }))
+	require.Equal(t, StateClosed, b.State())
+}
+
+func TestBreaker_StateChangesAreReportedInOrder(t *testing.T) {
+	promMetrics := NewPrometheusMetrics()
+	var stateChanges []stateChange // Not protected by a mutex since callbacks must not be called concurrently.
+	b, now := newTestBreaker(logtest.NewRecorder(), Opts{
+		Name:                "test",
+		ConsecutiveFailures: 1,
+		Cooldown:            time.Millisecond,
+		MetricsCollector:    promMetrics,
+		OnStateChange: func(from, to State) {
+			runtime.Gosched() // Give other goroutines a chance to report their changes concurrently.
+			stateChanges = append(stateChanges, stateChange{from, to})
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				if (i+j)%2 == 0 {
+					failN(b, 1)
+				} else {
+					_ = b.Do(func() error { return nil })
+				}
+				now.advance(time.Millisecond / 2)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.NotEmpty(t, stateChanges)
+	prevState := StateClosed
+	for _, change := range stateChanges {
+		require.Equal(t, prevState, change.from)
+		prevState = change.to
+	}
+	state := b.State()
+	require.Equal(t, state, stateChanges[len(stateChanges)-1].to)
+	require.Equal(t, 1.0, testutil.ToFloat64(promMetrics.State.WithLabelValues("test", state.String())))
+}
+
+func TestBreaker_FailureRate(t *testing.T) {
+	b, now := newTestBreaker(logtest.NewRecorder(), Opts{
+		FailureRateThreshold: 0.5,
+		MinRequests:          4,
+		Window:               time.Second * 10,
+		WindowBuckets:        10,
+	})
+	succeed := func(n int) {
+		for i := 0; i < n; i++ {
+			require.NoError(t, b.Do(func() error { return nil }))
+		}
+	}
+
+	// Not enough requests.
+	failN(b, 3)
+	require.Equal(t, StateClosed, b.State())
+
+	// Old failures leave the window.
+	now.advance(time.Second * 10)
+	succeed(3)
+	failN(b, 2)
+	require.Equal(t, StateClosed, b.State())
+
+	now.advance(time.Second * 5)
+	failN(b, 1)
+	require.Equal(t, StateOpen, b.State())
+}
+
+func TestBreaker_WindowSmallerThanBuckets(t *testing.T) {
+	b, now := newTestBreaker(logtest.NewRecorder(), Opts{
+		FailureRateThreshold: 0.5,
+		MinRequests:          2,
+		Window:               time.Nanosecond * 5,
+		WindowBuckets:        10,
+	})
+	require.Len(t, b.window.buckets, 5)
+	require.Equal(t, time.Nanosecond, b.window.bucketDuration)
+
+	failN(b, 1)
+	now.advance(time.Nanosecond * 3)
+	require.NotPanics(t, func() { failN(b, 1) })
+	require.Equal(t, StateOpen, b.State())
+
+	w := newSlidingWindow(time.Nanosecond*5, 10)
+	require.Equal(t, time.Nanosecond, w.bucketDuration)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	w.record(start, false)
+	require.NotPanics(t, func() { w.record(start.Add(time.Nanosecond), true) })
+	successes, failures := w.counts(start.Add(time.Nanosecond * 2))
+	require.Equal(t, 1, successes)
+	require.Equal(t, 1, failures)
+}
+
+func TestBreaker_CanceledContextIsIgnored(t *testing.T) {
+	b, now := newTestBreaker(logtest.NewRecorder(), Opts{ConsecutiveFailures: 2, Cooldown: time.Second})
+	require.ErrorIs(t, b.Do(func() error { return context.Canceled }), context.Canceled)
+	require.Equal(t, StateClosed, b.State())
+
+	// Cancellation doesn't reset the consecutive failures counter.
+	failN(b, 1)
+	require.ErrorIs(t, b.Do(func() error { return context.Canceled }), context.Canceled)
+	failN(b, 1)
+	require.Equal(t, StateOpen, b.State())
+
+	// Canceled probe releases its slot but doesn't close the circuit breaker.
+	now.advance(time.Second)
+	require.ErrorIs(t, b.Do(func() error { return context.Canceled }), context.Canceled)
+	require.Equal(t, StateHalfOpen, b.State())
+	require.NoError(t, b.Do(func() error { return nil }))
+	require.Equal(t, StateClosed, b.State())
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	b, _ := newTestBreaker(logtest.NewRecorder(), Opts{ConsecutiveFailures: 2})
+	interceptor := UnaryClientInterceptor(b)
+	invoke := func(err error) error {
+		return interceptor(context.Background(), "/test.Service/Method", nil, nil, nil,
+			func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				return err
+			})
+	}
+
+	require.Error(t, invoke(status.Error(codes.NotFound, "not found")))
+	require.Error(t, invoke(status.Error(codes.InvalidArgument, "invalid argument")))
+	require.Error(t, invoke(status.Error(codes.Unavailable, "unavailable")))
+	require.Error(t, invoke(status.Error(codes.Canceled, "canceled")))
+	require.Equal(t, StateClosed, b.State())
+
+	require.Error(t, invoke(errTest))
+	require.Equal(t, StateOpen, b.State())
+
+	err := invoke(nil)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Contains(t, err.Error(), ErrOpenState.Error())
+}
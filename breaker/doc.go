@@ -0,0 +1,10 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+// Package breaker provides the circuit breaker that prevents sending requests to the failing downstream service
+// and gives it time to recover. See httpclient.CircuitBreakerRoundTripper and UnaryClientInterceptor
+// for integration with HTTP and gRPC clients.
+package breaker
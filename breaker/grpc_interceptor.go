@@ -0,0 +1,47 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package breaker
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor returns a gRPC unary client interceptor that protects calls with the circuit breaker.
+// Results of calls are determined by ClassifyGRPCResult. Rejected calls end with the Unavailable code.
+func UnaryClientInterceptor(b *Breaker) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		done, err := b.Allow()
+		if err != nil {
+			return status.Error(codes.Unavailable, err.Error())
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		done(ClassifyGRPCResult(err))
+		return err
+	}
+}
+
+// ClassifyGRPCResult determines the result of the gRPC call for the circuit breaker by the returned error.
+// Calls that end with Unknown, DeadlineExceeded, Internal, Unavailable or DataLoss codes are treated as failures,
+// canceled calls are ignored, and all other calls are treated as successes.
+func ClassifyGRPCResult(err error) Result {
+	if err == nil {
+		return ResultSuccess
+	}
+	switch status.Code(err) {
+	case codes.Canceled:
+		return ResultIgnored
+	case codes.Unknown, codes.DeadlineExceeded, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return ResultFailure
+	}
+	return ResultSuccess
+}
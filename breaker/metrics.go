@@ -0,0 +1,92 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package breaker
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/acronis/go-appkit/internal/libinfo"
+)
+
+// MetricsCollector represents a collector of metrics for Breaker.
+type MetricsCollector interface {
+	// SetState sets the current state of the circuit breaker.
+	SetState(breakerName string, state State)
+	// IncRejectedRequests increments the total number of requests rejected by the circuit breaker.
+	IncRejectedRequests(breakerName string)
+}
+
+// PrometheusMetricsOpts represents options for PrometheusMetrics.
+type PrometheusMetricsOpts struct {
+	// Namespace is a namespace for metrics. It will be prepended to all metric names.
+	Namespace string
+
+	// ConstLabels is a set of labels that will be applied to all metrics.
+	ConstLabels prometheus.Labels
+}
+
+// PrometheusMetrics represents a Prometheus metrics for Breaker.
+type PrometheusMetrics struct {
+	State            *prometheus.GaugeVec
+	RejectedRequests *prometheus.CounterVec
+}
+
+// NewPrometheusMetrics creates a new instance of PrometheusMetrics with default options.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return NewPrometheusMetricsWithOpts(PrometheusMetricsOpts{})
+}
+
+// NewPrometheusMetricsWithOpts creates a new instance of PrometheusMetrics with the provided options.
+func NewPrometheusMetricsWithOpts(opts PrometheusMetricsOpts) *PrometheusMetrics {
+	constLabels := libinfo.AddPrometheusLibVersionLabel(opts.ConstLabels)
+	return &PrometheusMetrics{
+		State: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "circuit_breaker_state",
+			Help:        "Current state of the circuit breaker (1 for the current state, 0 for others).",
+			ConstLabels: constLabels,
+		}, []string{"breaker", "state"}),
+		RejectedRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "circuit_breaker_rejected_requests_total",
+			Help:        "Number of requests rejected by the circuit breaker.",
+			ConstLabels: constLabels,
+		}, []string{"breaker"}),
+	}
+}
+
+// SetState sets the current state of the circuit breaker.
+func (pm *PrometheusMetrics) SetState(breakerName string, state State) {
+	for _, s := range States {
+		val := 0.0
+		if s == state {
+			val = 1
+		}
+		pm.State.WithLabelValues(breakerName, s.String()).Set(val)
+	}
+}
+
+// IncRejectedRequests increments the total number of requests rejected by the circuit breaker.
+func (pm *PrometheusMetrics) IncRejectedRequests(breakerName string) {
+	pm.RejectedRequests.WithLabelValues(breakerName).Inc()
+}
+
+// MustRegister does registration of metrics collector in Prometheus and panics if any error occurs.
+func (pm *PrometheusMetrics) MustRegister() {
+	prometheus.MustRegister(pm.State, pm.RejectedRequests)
+}
+
+// Unregister cancels registration of metrics collector in Prometheus.
+func (pm *PrometheusMetrics) Unregister() {
+	prometheus.Unregister(pm.State)
+	prometheus.Unregister(pm.RejectedRequests)
+}
+
+type disabledMetrics struct{}
+
+func (disabledMetrics) SetState(string, State)     {}
+func (disabledMetrics) IncRejectedRequests(string) {}
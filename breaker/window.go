@@ -0,0 +1,82 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package breaker
+
+import "time"
+
+type windowBucket struct {
+	successes int
+	failures  int
+}
+
+// slidingWindow counts successes and failures over the sliding time window split into the fixed number of buckets.
+// It's not safe for concurrent use.
+type slidingWindow struct {
+	buckets        []windowBucket
+	bucketDuration time.Duration
+	curIdx         int
+	curStart       time.Time
+	successes      int
+	failures       int
+}
+
+func newSlidingWindow(size time.Duration, bucketsNum int) *slidingWindow {
+	bucketDuration := size / time.Duration(bucketsNum)
+	if bucketDuration <= 0 {
+		bucketDuration = 1 // Prevents division by zero in advance().
+	}
+	return &slidingWindow{
+		buckets:        make([]windowBucket, bucketsNum),
+		bucketDuration: bucketDuration,
+	}
+}
+
+func (w *slidingWindow) record(now time.Time, success bool) {
+	w.advance(now)
+	if success {
+		w.buckets[w.curIdx].successes++
+		w.successes++
+	} else {
+		w.buckets[w.curIdx].failures++
+		w.failures++
+	}
+}
+
+func (w *slidingWindow) counts(now time.Time) (successes, failures int) {
+	w.advance(now)
+	return w.successes, w.failures
+}
+
+func (w *slidingWindow) reset() {
+	clear(w.buckets)
+	w.curStart = time.Time{}
+	w.successes, w.failures = 0, 0
+}
+
+// advance moves the current bucket forward, clearing the expired ones.
+func (w *slidingWindow) advance(now time.Time) {
+	if w.curStart.IsZero() {
+		w.curStart = now
+		return
+	}
+	elapsed := int(now.Sub(w.curStart) / w.bucketDuration)
+	if elapsed <= 0 {
+		return
+	}
+	if elapsed >= len(w.buckets) {
+		w.reset()
+		w.curStart = now
+		return
+	}
+	for i := 0; i < elapsed; i++ {
+		w.curIdx = (w.curIdx + 1) % len(w.buckets)
+		w.successes -= w.buckets[w.curIdx].successes
+		w.failures -= w.buckets[w.curIdx].failures
+		w.buckets[w.curIdx] = windowBucket{}
+	}
+	w.curStart = w.curStart.Add(time.Duration(elapsed) * w.bucketDuration)
+}
@@ -47,6 +47,8 @@ type loggingOptions struct {
 	timeSlotsThreshold         time.Duration
 	unaryCustomLoggerProvider  UnaryCustomLoggerProvider
 	streamCustomLoggerProvider StreamCustomLoggerProvider
+	debugTokenValidator        log.DebugTokenValidator
+	debugTokenMetadataKey      string
 }
 
 // WithLoggingCallStart enables logging of call start events.
@@ -105,6 +107,18 @@ func WithLoggingStreamCustomLoggerProvider(provider StreamCustomLoggerProvider)
 	}
 }
 
+// WithLoggingDebugEscalation enables escalation of the call-scoped logger level to debug
+// for calls with a valid token in the metadata by the given key (log.DebugTokenHeader is used if it's empty).
+// The logger should support escalation (see log.WithDebugEscalation).
+// The valid token is also put into the call context (see log.NewContextWithDebugToken),
+// so it may be propagated to downstream services by httpclient.
+func WithLoggingDebugEscalation(validator log.DebugTokenValidator, metadataKey string) LoggingOption {
+	return func(opts *loggingOptions) {
+		opts.debugTokenValidator = validator
+		opts.debugTokenMetadataKey = metadataKey
+	}
+}
+
 // LoggingUnaryInterceptor is a gRPC unary interceptor that logs the start and end of each RPC call.
 func LoggingUnaryInterceptor(logger log.FieldLogger, options ...LoggingOption) func(
 	ctx context.Context,
@@ -192,6 +206,13 @@ func loggingServerInterceptor(
 	}
 
 	loggerForNext := loggerProvider(ctx)
+	if opts.debugTokenValidator != nil {
+		if token := getDebugTokenFromMetadata(ctx, opts.debugTokenMetadataKey); token != "" &&
+			opts.debugTokenValidator.Validate(token) {
+			loggerForNext, _ = log.EscalateToDebug(loggerForNext)
+			ctx = log.NewContextWithDebugToken(ctx, token)
+		}
+	}
 	loggerForNext = loggerForNext.With(
 		log.String("request_id", GetRequestIDFromContext(ctx)),
 		log.String("int_request_id", GetInternalRequestIDFromContext(ctx)),
@@ -292,6 +313,18 @@ func buildCallInfoLogFields(
 	return logFields
 }
 
+func getDebugTokenFromMetadata(ctx context.Context, metadataKey string) string {
+	if metadataKey == "" {
+		metadataKey = log.DebugTokenHeader
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(metadataKey); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
 func splitFullMethodName(fullMethod string) (service string, method string) {
 	const unknown = "unknown"
 	fullMethod = strings.TrimPrefix(fullMethod, "/") // remove leading slash
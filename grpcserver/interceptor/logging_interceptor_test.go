@@ -635,6 +635,67 @@ func (s *LoggingInterceptorTestSuite) TestLoggingServerInterceptor_Errors() {
 	s.requireLogFieldString(logEntry, "grpc_error", "rpc error: code = Internal desc = test internal error")
 }
 
+func (s *LoggingInterceptorTestSuite) TestLoggingServerInterceptor_DebugEscalation() {
+	secret := []byte("hmac-secret")
+
+	tests := []struct {
+		name          string
+		token         string
+		wantEscalated bool
+	}{
+		{name: "no token", token: "", wantEscalated: false},
+		{name: "invalid token", token: log.NewHMACDebugToken([]byte("another-secret"), time.Now()), wantEscalated: false},
+		{name: "expired token", token: log.NewHMACDebugToken(secret, time.Now().Add(-time.Hour)), wantEscalated: false},
+		{name: "valid token", token: log.NewHMACDebugToken(secret, time.Now()), wantEscalated: true},
+	}
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			logger := logtest.NewRecorderWithLevel(log.LevelInfo)
+			svc, client, closeSvc, err := s.setupTestService(logger, "", []LoggingOption{
+				WithLoggingDebugEscalation(log.NewHMACDebugTokenValidator(secret, time.Minute), ""),
+			})
+			s.Require().NoError(err)
+			defer func() { s.Require().NoError(closeSvc()) }()
+
+			var ctxDebugToken string
+			handleCtx := func(ctx context.Context) {
+				ctxDebugToken = log.GetDebugTokenFromContext(ctx)
+				GetLoggerFromContext(ctx).Debug("debug message")
+			}
+			svc.SwitchUnaryCallHandler(func(ctx context.Context, req *grpc_testing.SimpleRequest) (*grpc_testing.SimpleResponse, error) {
+				handleCtx(ctx)
+				return &grpc_testing.SimpleResponse{}, nil
+			})
+			svc.SwitchStreamingOutputCallHandler(func(req *grpc_testing.StreamingOutputCallRequest, stream grpc_testing.TestService_StreamingOutputCallServer) error {
+				handleCtx(stream.Context())
+				return stream.Send(&grpc_testing.StreamingOutputCallResponse{})
+			})
+
+			reqCtx := context.Background()
+			if tt.token != "" {
+				reqCtx = metadata.NewOutgoingContext(reqCtx, metadata.Pairs(strings.ToLower(log.DebugTokenHeader), tt.token))
+			}
+			if s.IsUnary {
+				_, err = client.UnaryCall(reqCtx, &grpc_testing.SimpleRequest{})
+				s.Require().NoError(err)
+			} else {
+				stream, streamErr := client.StreamingOutputCall(reqCtx, &grpc_testing.StreamingOutputCallRequest{})
+				s.Require().NoError(streamErr)
+				_, recvErr := stream.Recv()
+				s.Require().NoError(recvErr)
+			}
+
+			_, found := logger.FindEntry("debug message")
+			s.Require().Equal(tt.wantEscalated, found)
+			if tt.wantEscalated {
+				s.Require().Equal(tt.token, ctxDebugToken)
+			} else {
+				s.Require().Empty(ctxDebugToken)
+			}
+		})
+	}
+}
+
 // Helper methods for the test suite
 func (s *LoggingInterceptorTestSuite) setupTestService(logger *logtest.Recorder, userAgent string, options []LoggingOption) (*testService, grpc_testing.TestServiceClient, func() error, error) {
 	var serverOptions []grpc.ServerOption
@@ -0,0 +1,146 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/acronis/go-appkit/breaker"
+	"github.com/acronis/go-appkit/log"
+)
+
+// CircuitBreakerRoundTripperOpts represents an options for CircuitBreakerRoundTripper.
+type CircuitBreakerRoundTripperOpts struct {
+	// Logger is used for logging state changes of circuit breakers.
+	Logger log.FieldLogger
+
+	// BreakerOpts is used for creating circuit breakers. Name is ignored since the host is used instead.
+	BreakerOpts breaker.Opts
+
+	// KeyFunc returns a key of the circuit breaker for the request.
+	// By default, the host of the request URL is used.
+	KeyFunc func(r *http.Request) string
+
+	// ClassifyResult determines the result of the request for the circuit breaker.
+	// By default, DefaultCircuitBreakerClassifyResult function is used.
+	ClassifyResult func(resp *http.Response, roundTripErr error) breaker.Result
+}
+
+// CircuitBreakerRoundTripper wraps an object that implements http.RoundTripper interface
+// and protects the downstream services with circuit breakers. Each host has its own circuit breaker.
+type CircuitBreakerRoundTripper struct {
+	// Delegate is an object that implements http.RoundTripper interface
+	// and is used for sending HTTP requests under the hood.
+	Delegate http.RoundTripper
+
+	logger         log.FieldLogger
+	breakerOpts    breaker.Opts
+	keyFunc        func(r *http.Request) string
+	classifyResult func(resp *http.Response, roundTripErr error) breaker.Result
+
+	breakersMu sync.RWMutex
+	breakers   map[string]*breaker.Breaker
+}
+
+// NewCircuitBreakerRoundTripper creates a new CircuitBreakerRoundTripper with default options.
+func NewCircuitBreakerRoundTripper(delegate http.RoundTripper) *CircuitBreakerRoundTripper {
+	return NewCircuitBreakerRoundTripperWithOpts(delegate, CircuitBreakerRoundTripperOpts{})
+}
+
+// NewCircuitBreakerRoundTripperWithOpts creates a new CircuitBreakerRoundTripper with specified options.
+func NewCircuitBreakerRoundTripperWithOpts(
+	delegate http.RoundTripper, opts CircuitBreakerRoundTripperOpts,
+) *CircuitBreakerRoundTripper {
+	if opts.Logger == nil {
+		opts.Logger = disableLogger
+	}
+	if opts.KeyFunc == nil {
+		opts.KeyFunc = func(r *http.Request) string { return r.URL.Host }
+	}
+	if opts.ClassifyResult == nil {
+		opts.ClassifyResult = DefaultCircuitBreakerClassifyResult
+	}
+	return &CircuitBreakerRoundTripper{
+		Delegate:       delegate,
+		logger:         opts.Logger,
+		breakerOpts:    opts.BreakerOpts,
+		keyFunc:        opts.KeyFunc,
+		classifyResult: opts.ClassifyResult,
+		breakers:       make(map[string]*breaker.Breaker),
+	}
+}
+
+// RoundTrip executes a single HTTP transaction if the circuit breaker for the request host allows it.
+func (rt *CircuitBreakerRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	key := rt.keyFunc(r)
+	done, err := rt.Breaker(key).Allow()
+	if err != nil {
+		if r.Body != nil {
+			_ = r.Body.Close() // Per RoundTripper contract.
+		}
+		return nil, &CircuitBreakerError{Key: key, Inner: err}
+	}
+	resp, err := rt.Delegate.RoundTrip(r)
+	done(rt.classifyResult(resp, err))
+	return resp, err
+}
+
+// Breaker returns the circuit breaker for the given key (host by default), creating it if needed.
+func (rt *CircuitBreakerRoundTripper) Breaker(key string) *breaker.Breaker {
+	rt.breakersMu.RLock()
+	b, ok := rt.breakers[key]
+	rt.breakersMu.RUnlock()
+	if ok {
+		return b
+	}
+
+	rt.breakersMu.Lock()
+	defer rt.breakersMu.Unlock()
+	if b, ok = rt.breakers[key]; ok {
+		return b
+	}
+	opts := rt.breakerOpts
+	opts.Name = key
+	b = breaker.NewWithOpts(rt.logger, opts)
+	rt.breakers[key] = b
+	return b
+}
+
+// DefaultCircuitBreakerClassifyResult treats transport errors and 5xx responses as failures.
+// Requests canceled by the caller are ignored, so they don't affect the state of the circuit breaker.
+func DefaultCircuitBreakerClassifyResult(resp *http.Response, roundTripErr error) breaker.Result {
+	if roundTripErr != nil {
+		if errors.Is(roundTripErr, context.Canceled) {
+			return breaker.ResultIgnored
+		}
+		return breaker.ResultFailure
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return breaker.ResultFailure
+	}
+	return breaker.ResultSuccess
+}
+
+// CircuitBreakerError is returned in RoundTrip method of CircuitBreakerRoundTripper
+// when the request is rejected by the circuit breaker.
+type CircuitBreakerError struct {
+	Key   string
+	Inner error
+}
+
+func (e *CircuitBreakerError) Error() string {
+	return fmt.Sprintf("circuit breaker for %q: %s", e.Key, e.Inner.Error())
+}
+
+// Unwrap returns the next error in the error chain.
+func (e *CircuitBreakerError) Unwrap() error {
+	return e.Inner
+}
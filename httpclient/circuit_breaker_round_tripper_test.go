@@ -0,0 +1,84 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/acronis/go-appkit/breaker"
+	"github.com/acronis/go-appkit/log"
+	"github.com/acronis/go-appkit/log/logtest"
+)
+
+func TestCircuitBreakerRoundTripper(t *testing.T) {
+	var failingReqs atomic.Int32
+	failingSrv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		failingReqs.Add(1)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failingSrv.Close()
+	healthySrv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNotFound) // 4xx is not a failure.
+	}))
+	defer healthySrv.Close()
+
+	logRecorder := logtest.NewRecorder()
+	cbRT := NewCircuitBreakerRoundTripperWithOpts(http.DefaultTransport, CircuitBreakerRoundTripperOpts{
+		Logger:      logRecorder,
+		BreakerOpts: breaker.Opts{ConsecutiveFailures: 3, Cooldown: time.Minute},
+	})
+	client := &http.Client{Transport: cbRT}
+
+	for i := 0; i < 5; i++ {
+		resp := doGet(client, healthySrv.URL)
+		require.NoError(t, resp.err)
+		require.Equal(t, http.StatusNotFound, resp.resp.StatusCode)
+	}
+
+	for i := 0; i < 3; i++ {
+		resp := doGet(client, failingSrv.URL)
+		require.NoError(t, resp.err)
+		require.Equal(t, http.StatusServiceUnavailable, resp.resp.StatusCode)
+	}
+
+	failingSrvURL, err := url.Parse(failingSrv.URL)
+	require.NoError(t, err)
+	resp := doGet(client, failingSrv.URL)
+	var cbErr *CircuitBreakerError
+	require.ErrorAs(t, resp.err, &cbErr)
+	require.ErrorIs(t, resp.err, breaker.ErrOpenState)
+	require.Equal(t, failingSrvURL.Host, cbErr.Key)
+	require.Equal(t, int32(3), failingReqs.Load())
+	require.Equal(t, breaker.StateOpen, cbRT.Breaker(failingSrvURL.Host).State())
+	logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelWarn),
+		logtest.HasMessage("circuit breaker state is changed"),
+		logtest.FieldEquals("circuit_breaker", failingSrvURL.Host), logtest.FieldEquals("to", "open"))
+
+	// Circuit breaker of another host is not affected.
+	resp = doGet(client, healthySrv.URL)
+	require.NoError(t, resp.err)
+}
+
+func TestDefaultCircuitBreakerClassifyResult(t *testing.T) {
+	require.Equal(t, breaker.ResultSuccess,
+		DefaultCircuitBreakerClassifyResult(&http.Response{StatusCode: http.StatusBadRequest}, nil))
+	require.Equal(t, breaker.ResultFailure,
+		DefaultCircuitBreakerClassifyResult(&http.Response{StatusCode: http.StatusBadGateway}, nil))
+	require.Equal(t, breaker.ResultFailure, DefaultCircuitBreakerClassifyResult(nil, errors.New("connection refused")))
+	require.Equal(t, breaker.ResultIgnored,
+		DefaultCircuitBreakerClassifyResult(nil, fmt.Errorf("round trip: %w", context.Canceled)))
+}
@@ -8,10 +8,12 @@ package httpclient
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 
+	"github.com/acronis/go-appkit/breaker"
 	"github.com/acronis/go-appkit/config"
 	"github.com/acronis/go-appkit/retry"
 )
@@ -34,11 +36,21 @@ const (
 	cfgKeyRetriesPolicy                           = "retries.policy"
 	cfgKeyRetriesPolicyExponentialInitialInterval = "retries.exponentialBackoff.initialInterval"
 	cfgKeyRetriesPolicyExponentialMultiplier      = "retries.exponentialBackoff.multiplier"
+	cfgKeyRetriesPolicyExponentialMaxInterval     = "retries.exponentialBackoff.maxInterval"
+	cfgKeyRetriesPolicyExponentialMaxElapsedTime  = "retries.exponentialBackoff.maxElapsedTime"
+	cfgKeyRetriesPolicyExponentialJitter          = "retries.exponentialBackoff.jitter"
 	cfgKeyRetriesPolicyConstantInternal           = "retries.constantBackoff.interval"
 	cfgKeyRateLimitsEnabled                       = "rateLimits.enabled"
 	cfgKeyRateLimitsLimit                         = "rateLimits.limit"
 	cfgKeyRateLimitsBurst                         = "rateLimits.burst"
 	cfgKeyRateLimitsWaitTimeout                   = "rateLimits.waitTimeout"
+	cfgKeyCircuitBreakerEnabled                   = "circuitBreaker.enabled"
+	cfgKeyCircuitBreakerConsecutiveFailures       = "circuitBreaker.consecutiveFailures"
+	cfgKeyCircuitBreakerFailureRateThreshold      = "circuitBreaker.failureRateThreshold"
+	cfgKeyCircuitBreakerMinRequests               = "circuitBreaker.minRequests"
+	cfgKeyCircuitBreakerWindow                    = "circuitBreaker.window"
+	cfgKeyCircuitBreakerCooldown                  = "circuitBreaker.cooldown"
+	cfgKeyCircuitBreakerHalfOpenProbes            = "circuitBreaker.halfOpenProbes"
 	cfgKeyLogEnabled                              = "log.enabled"
 	cfgKeyLogMode                                 = "log.mode"
 	cfgKeyLogSlowRequestThreshold                 = "log.slowRequestThreshold"
@@ -59,6 +71,9 @@ type Config struct {
 	// RateLimits is a configuration for HTTP client rate limits.
 	RateLimits RateLimitsConfig `mapstructure:"rateLimits" yaml:"rateLimits" json:"rateLimits"`
 
+	// CircuitBreaker is a configuration for HTTP client circuit breakers.
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuitBreaker" yaml:"circuitBreaker" json:"circuitBreaker"`
+
 	// Log is a configuration for HTTP client logs.
 	Log LogConfig `mapstructure:"log" yaml:"log" json:"log"`
 
@@ -157,6 +172,16 @@ type ExponentialBackoffConfig struct {
 
 	// Multiplier is the multiplier for exponential backoff.
 	Multiplier float64 `mapstructure:"multiplier" yaml:"multiplier" json:"multiplier"`
+
+	// MaxInterval caps the interval between retries (1 minute by default).
+	MaxInterval config.TimeDuration `mapstructure:"maxInterval" yaml:"maxInterval" json:"maxInterval"`
+
+	// MaxElapsedTime limits the total time of retries (15 minutes by default).
+	MaxElapsedTime config.TimeDuration `mapstructure:"maxElapsedTime" yaml:"maxElapsedTime" json:"maxElapsedTime"`
+
+	// Jitter is a strategy of randomizing intervals: [full, equal, decorrelated].
+	// If empty, intervals are randomized by ±50%.
+	Jitter retry.JitterStrategy `mapstructure:"jitter" yaml:"jitter" json:"jitter"`
 }
 
 // ConstantBackoffConfig represents configuration options for constant backoff.
@@ -165,6 +190,49 @@ type ConstantBackoffConfig struct {
 	Interval config.TimeDuration `mapstructure:"interval" yaml:"interval" json:"interval"`
 }
 
+// CircuitBreakerConfig represents configuration options for HTTP client circuit breakers (one per host).
+type CircuitBreakerConfig struct {
+	// Enabled is a flag that enables circuit breakers.
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+
+	// ConsecutiveFailures is a number of consecutive failures after which the circuit breaker is opened.
+	ConsecutiveFailures int `mapstructure:"consecutiveFailures" yaml:"consecutiveFailures" json:"consecutiveFailures"`
+
+	// FailureRateThreshold is a ratio (0, 1] of failed requests within the sliding window
+	// after which the circuit breaker is opened.
+	FailureRateThreshold float64 `mapstructure:"failureRateThreshold" yaml:"failureRateThreshold" json:"failureRateThreshold"`
+
+	// MinRequests is a minimum number of requests within the sliding window that is required to evaluate the failure rate.
+	MinRequests int `mapstructure:"minRequests" yaml:"minRequests" json:"minRequests"`
+
+	// Window is a size of the sliding window for evaluating the failure rate.
+	// It's split into breaker.DefaultWindowBuckets buckets, so it must be at least 1ms per bucket (10ms).
+	Window config.TimeDuration `mapstructure:"window" yaml:"window" json:"window"`
+
+	// Cooldown is a time during which the circuit breaker stays open before it becomes half-open.
+	Cooldown config.TimeDuration `mapstructure:"cooldown" yaml:"cooldown" json:"cooldown"`
+
+	// HalfOpenProbes is a number of probe requests that are allowed in the half-open state.
+	HalfOpenProbes int `mapstructure:"halfOpenProbes" yaml:"halfOpenProbes" json:"halfOpenProbes"`
+}
+
+// minCircuitBreakerWindow is a minimum size of the circuit breaker's sliding window (1ms per bucket).
+const minCircuitBreakerWindow = time.Duration(breaker.DefaultWindowBuckets) * time.Millisecond
+
+// TransportOpts returns transport options.
+func (c *CircuitBreakerConfig) TransportOpts() CircuitBreakerRoundTripperOpts {
+	return CircuitBreakerRoundTripperOpts{
+		BreakerOpts: breaker.Opts{
+			ConsecutiveFailures:  c.ConsecutiveFailures,
+			FailureRateThreshold: c.FailureRateThreshold,
+			MinRequests:          c.MinRequests,
+			Window:               time.Duration(c.Window),
+			Cooldown:             time.Duration(c.Cooldown),
+			HalfOpenProbes:       c.HalfOpenProbes,
+		},
+	}
+}
+
 // RetriesConfig represents configuration options for HTTP client retries policy.
 type RetriesConfig struct {
 	// Enabled is a flag that enables retries.
@@ -187,13 +255,26 @@ type RetriesConfig struct {
 func (c *RetriesConfig) GetPolicy() retry.Policy {
 	switch c.Policy {
 	case RetryPolicyExponential:
-		return retry.PolicyFunc(func() backoff.BackOff {
-			bf := backoff.NewExponentialBackOff()
-			bf.InitialInterval = time.Duration(c.ExponentialBackoff.InitialInterval)
-			bf.Multiplier = c.ExponentialBackoff.Multiplier
-			bf.Reset()
-			return bf
-		})
+		cfg := c.ExponentialBackoff
+		if cfg.Jitter != "" {
+			// The same default as in the exponential backoff without jitter, since JitterBackoffPolicy is unlimited by default.
+			maxElapsedTime := time.Duration(cfg.MaxElapsedTime)
+			if maxElapsedTime == 0 {
+				maxElapsedTime = backoff.DefaultMaxElapsedTime
+			}
+			return retry.NewJitterBackoffPolicyWithOpts(cfg.Jitter, time.Duration(cfg.InitialInterval), 0,
+				retry.JitterBackoffPolicyOpts{
+					Multiplier:     cfg.Multiplier,
+					MaxInterval:    time.Duration(cfg.MaxInterval),
+					MaxElapsedTime: maxElapsedTime,
+				})
+		}
+		return retry.NewExponentialBackoffPolicyWithOpts(time.Duration(cfg.InitialInterval), 0,
+			retry.ExponentialBackoffPolicyOpts{
+				Multiplier:     cfg.Multiplier,
+				MaxInterval:    time.Duration(cfg.MaxInterval),
+				MaxElapsedTime: time.Duration(cfg.MaxElapsedTime),
+			})
 	case RetryPolicyConstant:
 		return retry.PolicyFunc(func() backoff.BackOff {
 			bf := backoff.NewConstantBackOff(time.Duration(c.ConstantBackoff.Interval))
@@ -251,6 +332,9 @@ func (c *Config) Set(dp config.DataProvider) error {
 	if err := c.setRateLimits(dp); err != nil {
 		return err
 	}
+	if err := c.setCircuitBreaker(dp); err != nil {
+		return err
+	}
 	if err := c.setLog(dp); err != nil {
 		return err
 	}
@@ -295,30 +379,7 @@ func (c *Config) setRetriesPolicy(dp config.DataProvider) error {
 	}
 
 	if c.Retries.Policy == RetryPolicyExponential {
-		var interval time.Duration
-		interval, err = dp.GetDuration(cfgKeyRetriesPolicyExponentialInitialInterval)
-		if err != nil {
-			return err
-		}
-		if interval < 0 {
-			return dp.WrapKeyErr(cfgKeyRetriesPolicyExponentialInitialInterval, errors.New("must be positive"))
-		}
-
-		var multiplier float64
-		multiplier, err = dp.GetFloat64(cfgKeyRetriesPolicyExponentialMultiplier)
-		if err != nil {
-			return err
-		}
-		if multiplier <= 1 {
-			return dp.WrapKeyErr(cfgKeyRetriesPolicyExponentialMultiplier, errors.New("must be greater than 1"))
-		}
-
-		c.Retries.ExponentialBackoff = ExponentialBackoffConfig{
-			InitialInterval: config.TimeDuration(interval),
-			Multiplier:      multiplier,
-		}
-
-		return nil
+		return c.setRetriesExponentialBackoff(dp)
 	}
 
 	if c.Retries.Policy == RetryPolicyConstant {
@@ -336,6 +397,62 @@ func (c *Config) setRetriesPolicy(dp config.DataProvider) error {
 	return nil
 }
 
+func (c *Config) setRetriesExponentialBackoff(dp config.DataProvider) error {
+	interval, err := dp.GetDuration(cfgKeyRetriesPolicyExponentialInitialInterval)
+	if err != nil {
+		return err
+	}
+	if interval < 0 {
+		return dp.WrapKeyErr(cfgKeyRetriesPolicyExponentialInitialInterval, errors.New("must be positive"))
+	}
+
+	var multiplier float64
+	multiplier, err = dp.GetFloat64(cfgKeyRetriesPolicyExponentialMultiplier)
+	if err != nil {
+		return err
+	}
+	if multiplier <= 1 {
+		return dp.WrapKeyErr(cfgKeyRetriesPolicyExponentialMultiplier, errors.New("must be greater than 1"))
+	}
+
+	var maxInterval time.Duration
+	maxInterval, err = dp.GetDuration(cfgKeyRetriesPolicyExponentialMaxInterval)
+	if err != nil {
+		return err
+	}
+	if maxInterval < 0 {
+		return dp.WrapKeyErr(cfgKeyRetriesPolicyExponentialMaxInterval, errors.New("must be positive"))
+	}
+
+	var maxElapsedTime time.Duration
+	maxElapsedTime, err = dp.GetDuration(cfgKeyRetriesPolicyExponentialMaxElapsedTime)
+	if err != nil {
+		return err
+	}
+	if maxElapsedTime < 0 {
+		return dp.WrapKeyErr(cfgKeyRetriesPolicyExponentialMaxElapsedTime, errors.New("must be positive"))
+	}
+
+	var jitter string
+	jitter, err = dp.GetString(cfgKeyRetriesPolicyExponentialJitter)
+	if err != nil {
+		return err
+	}
+	if jitter != "" && !retry.JitterStrategy(jitter).IsValid() {
+		return dp.WrapKeyErr(cfgKeyRetriesPolicyExponentialJitter, errors.New("must be one of: [full, equal, decorrelated]"))
+	}
+
+	c.Retries.ExponentialBackoff = ExponentialBackoffConfig{
+		InitialInterval: config.TimeDuration(interval),
+		Multiplier:      multiplier,
+		MaxInterval:     config.TimeDuration(maxInterval),
+		MaxElapsedTime:  config.TimeDuration(maxElapsedTime),
+		Jitter:          retry.JitterStrategy(jitter),
+	}
+
+	return nil
+}
+
 func (c *Config) setRateLimits(dp config.DataProvider) (err error) {
 	enabled, err := dp.GetBool(cfgKeyRateLimitsEnabled)
 	if err != nil {
@@ -377,6 +494,77 @@ func (c *Config) setRateLimits(dp config.DataProvider) (err error) {
 	return nil
 }
 
+func (c *Config) setCircuitBreaker(dp config.DataProvider) error {
+	enabled, err := dp.GetBool(cfgKeyCircuitBreakerEnabled)
+	if err != nil {
+		return err
+	}
+	c.CircuitBreaker.Enabled = enabled
+
+	if !c.CircuitBreaker.Enabled {
+		return nil
+	}
+
+	consecutiveFailures, err := dp.GetInt(cfgKeyCircuitBreakerConsecutiveFailures)
+	if err != nil {
+		return err
+	}
+	if consecutiveFailures < 0 {
+		return dp.WrapKeyErr(cfgKeyCircuitBreakerConsecutiveFailures, errors.New("can not be negative"))
+	}
+	c.CircuitBreaker.ConsecutiveFailures = consecutiveFailures
+
+	failureRateThreshold, err := dp.GetFloat64(cfgKeyCircuitBreakerFailureRateThreshold)
+	if err != nil {
+		return err
+	}
+	if failureRateThreshold < 0 || failureRateThreshold > 1 {
+		return dp.WrapKeyErr(cfgKeyCircuitBreakerFailureRateThreshold, errors.New("must be in range [0..1]"))
+	}
+	c.CircuitBreaker.FailureRateThreshold = failureRateThreshold
+
+	minRequests, err := dp.GetInt(cfgKeyCircuitBreakerMinRequests)
+	if err != nil {
+		return err
+	}
+	if minRequests < 0 {
+		return dp.WrapKeyErr(cfgKeyCircuitBreakerMinRequests, errors.New("can not be negative"))
+	}
+	c.CircuitBreaker.MinRequests = minRequests
+
+	window, err := dp.GetDuration(cfgKeyCircuitBreakerWindow)
+	if err != nil {
+		return err
+	}
+	if window < 0 {
+		return dp.WrapKeyErr(cfgKeyCircuitBreakerWindow, errors.New("can not be negative"))
+	}
+	if window > 0 && window < minCircuitBreakerWindow {
+		return dp.WrapKeyErr(cfgKeyCircuitBreakerWindow, fmt.Errorf("must be at least %s", minCircuitBreakerWindow))
+	}
+	c.CircuitBreaker.Window = config.TimeDuration(window)
+
+	cooldown, err := dp.GetDuration(cfgKeyCircuitBreakerCooldown)
+	if err != nil {
+		return err
+	}
+	if cooldown < 0 {
+		return dp.WrapKeyErr(cfgKeyCircuitBreakerCooldown, errors.New("can not be negative"))
+	}
+	c.CircuitBreaker.Cooldown = config.TimeDuration(cooldown)
+
+	halfOpenProbes, err := dp.GetInt(cfgKeyCircuitBreakerHalfOpenProbes)
+	if err != nil {
+		return err
+	}
+	if halfOpenProbes < 0 {
+		return dp.WrapKeyErr(cfgKeyCircuitBreakerHalfOpenProbes, errors.New("can not be negative"))
+	}
+	c.CircuitBreaker.HalfOpenProbes = halfOpenProbes
+
+	return nil
+}
+
 func (c *Config) setLog(dp config.DataProvider) error {
 	enabled, err := dp.GetBool(cfgKeyLogEnabled)
 	if err != nil {
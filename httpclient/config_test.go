@@ -12,12 +12,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 
 	"github.com/acronis/go-appkit/config"
+	"github.com/acronis/go-appkit/retry"
 )
 
 type AppConfig struct {
@@ -33,6 +35,9 @@ func TestConfig(t *testing.T) {
 		ExponentialBackoff: ExponentialBackoffConfig{
 			InitialInterval: config.TimeDuration(3 * time.Second),
 			Multiplier:      2,
+			MaxInterval:     config.TimeDuration(30 * time.Second),
+			MaxElapsedTime:  config.TimeDuration(5 * time.Minute),
+			Jitter:          retry.JitterStrategyFull,
 		},
 	}
 	expectedAppCfg.HTTPClient.RateLimits = RateLimitsConfig{
@@ -41,6 +46,15 @@ func TestConfig(t *testing.T) {
 		Burst:       3000,
 		WaitTimeout: config.TimeDuration(3 * time.Second),
 	}
+	expectedAppCfg.HTTPClient.CircuitBreaker = CircuitBreakerConfig{
+		Enabled:              true,
+		ConsecutiveFailures:  5,
+		FailureRateThreshold: 0.5,
+		MinRequests:          20,
+		Window:               config.TimeDuration(30 * time.Second),
+		Cooldown:             config.TimeDuration(10 * time.Second),
+		HalfOpenProbes:       2,
+	}
 	expectedAppCfg.HTTPClient.Log = LogConfig{
 		Enabled:              true,
 		SlowRequestThreshold: config.TimeDuration(5 * time.Second),
@@ -66,11 +80,22 @@ httpClient:
     exponentialBackoff:
       initialInterval: 3s
       multiplier: 2
+      maxInterval: 30s
+      maxElapsedTime: 5m
+      jitter: full
   rateLimits:
     enabled: true
     limit: 300
     burst: 3000
     waitTimeout: 3s
+  circuitBreaker:
+    enabled: true
+    consecutiveFailures: 5
+    failureRateThreshold: 0.5
+    minRequests: 20
+    window: 30s
+    cooldown: 10s
+    halfOpenProbes: 2
   log:
     enabled: true
     slowRequestThreshold: 5s
@@ -92,7 +117,10 @@ httpClient:
 			"policy": "exponential",
 			"exponentialBackoff": {
 				"initialInterval": "3s",
-				"multiplier": 2
+				"multiplier": 2,
+				"maxInterval": "30s",
+				"maxElapsedTime": "5m",
+				"jitter": "full"
 			}
 		},
 		"rateLimits": {
@@ -101,6 +129,15 @@ httpClient:
 			"burst": 3000,
 			"waitTimeout": "3s"
 		},
+		"circuitBreaker": {
+			"enabled": true,
+			"consecutiveFailures": 5,
+			"failureRateThreshold": 0.5,
+			"minRequests": 20,
+			"window": "30s",
+			"cooldown": "10s",
+			"halfOpenProbes": 2
+		},
 		"log": {
 			"enabled": true,
 			"slowRequestThreshold": "5s",
@@ -279,6 +316,85 @@ httpClient:
 `,
 			expectedErrMsg: `httpClient.retries.exponentialBackoff.multiplier: must be greater than 1`,
 		},
+		{
+			name: "error, exponentialBackoff maxInterval must be positive",
+			yamlData: `
+httpClient:
+  retries:
+    enabled: true
+    policy: exponential
+    exponentialBackoff:
+      multiplier: 2
+      maxInterval: -1s
+`,
+			expectedErrMsg: `httpClient.retries.exponentialBackoff.maxInterval: must be positive`,
+		},
+		{
+			name: "error, exponentialBackoff maxElapsedTime must be positive",
+			yamlData: `
+httpClient:
+  retries:
+    enabled: true
+    policy: exponential
+    exponentialBackoff:
+      multiplier: 2
+      maxElapsedTime: -1s
+`,
+			expectedErrMsg: `httpClient.retries.exponentialBackoff.maxElapsedTime: must be positive`,
+		},
+		{
+			name: "error, unknown exponentialBackoff jitter",
+			yamlData: `
+httpClient:
+  retries:
+    enabled: true
+    policy: exponential
+    exponentialBackoff:
+      multiplier: 2
+      jitter: invalid-jitter
+`,
+			expectedErrMsg: `httpClient.retries.exponentialBackoff.jitter: must be one of: [full, equal, decorrelated]`,
+		},
+		{
+			name: "error, circuitBreaker consecutiveFailures can not be negative",
+			yamlData: `
+httpClient:
+  circuitBreaker:
+    enabled: true
+    consecutiveFailures: -1
+`,
+			expectedErrMsg: `httpClient.circuitBreaker.consecutiveFailures: can not be negative`,
+		},
+		{
+			name: "error, circuitBreaker failureRateThreshold out of range",
+			yamlData: `
+httpClient:
+  circuitBreaker:
+    enabled: true
+    failureRateThreshold: 1.5
+`,
+			expectedErrMsg: `httpClient.circuitBreaker.failureRateThreshold: must be in range [0..1]`,
+		},
+		{
+			name: "error, circuitBreaker cooldown can not be negative",
+			yamlData: `
+httpClient:
+  circuitBreaker:
+    enabled: true
+    cooldown: -1s
+`,
+			expectedErrMsg: `httpClient.circuitBreaker.cooldown: can not be negative`,
+		},
+		{
+			name: "error, circuitBreaker window is too small",
+			yamlData: `
+httpClient:
+  circuitBreaker:
+    enabled: true
+    window: 5ns
+`,
+			expectedErrMsg: `httpClient.circuitBreaker.window: must be at least 10ms`,
+		},
 		{
 			name: "error, constantBackoff interval must be positive",
 			yamlData: `
@@ -300,3 +416,50 @@ httpClient:
 		})
 	}
 }
+
+func TestRetriesConfig_GetPolicy(t *testing.T) {
+	expCfg := ExponentialBackoffConfig{
+		InitialInterval: config.TimeDuration(100 * time.Millisecond),
+		Multiplier:      2,
+		MaxInterval:     config.TimeDuration(time.Second),
+	}
+
+	t.Run("exponential without jitter", func(t *testing.T) {
+		cfg := RetriesConfig{Policy: RetryPolicyExponential, ExponentialBackoff: expCfg}
+		require.Equal(t, retry.NewExponentialBackoffPolicyWithOpts(100*time.Millisecond, 0,
+			retry.ExponentialBackoffPolicyOpts{Multiplier: 2, MaxInterval: time.Second}), cfg.GetPolicy())
+	})
+
+	t.Run("exponential with jitter", func(t *testing.T) {
+		jitterCfg := expCfg
+		jitterCfg.Jitter = retry.JitterStrategyDecorrelated
+		cfg := RetriesConfig{Policy: RetryPolicyExponential, ExponentialBackoff: jitterCfg}
+		require.Equal(t, retry.NewJitterBackoffPolicyWithOpts(retry.JitterStrategyDecorrelated, 100*time.Millisecond, 0,
+			retry.JitterBackoffPolicyOpts{Multiplier: 2, MaxInterval: time.Second, MaxElapsedTime: backoff.DefaultMaxElapsedTime}),
+			cfg.GetPolicy())
+	})
+
+	t.Run("exponential with jitter from config without maxElapsedTime", func(t *testing.T) {
+		cfg := NewConfig(WithKeyPrefix("httpClient"))
+		err := config.NewDefaultLoader("").LoadFromReader(bytes.NewBuffer([]byte(`
+httpClient:
+  retries:
+    enabled: true
+    maxAttempts: 3
+    policy: exponential
+    exponentialBackoff:
+      initialInterval: 100ms
+      multiplier: 2
+      maxInterval: 1s
+      jitter: full
+`)), config.DataTypeYAML, cfg)
+		require.NoError(t, err)
+		require.Equal(t, retry.NewJitterBackoffPolicyWithOpts(retry.JitterStrategyFull, 100*time.Millisecond, 0,
+			retry.JitterBackoffPolicyOpts{Multiplier: 2, MaxInterval: time.Second, MaxElapsedTime: backoff.DefaultMaxElapsedTime}),
+			cfg.Retries.GetPolicy())
+	})
+
+	t.Run("no policy", func(t *testing.T) {
+		require.Nil(t, (&RetriesConfig{}).GetPolicy())
+	})
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package httpclient
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/acronis/go-appkit/log"
+)
+
+// DebugTokenRoundTripper propagates the debug token from the request context to the downstream service.
+// The token is put into the context by the logging middleware (or interceptor) when the incoming request
+// contains a valid token, and it escalates the level of the request-scoped logger to debug in the downstream service.
+//
+// Note that the token is a credential. Unless AllowedHosts is specified, it's sent to every host the client calls,
+// including third-party services outside the trust boundary.
+type DebugTokenRoundTripper struct {
+	// Delegate is the next RoundTripper in the chain.
+	Delegate http.RoundTripper
+
+	// Opts are the options for the debug token round tripper.
+	Opts DebugTokenRoundTripperOpts
+}
+
+// DebugTokenRoundTripperOpts represents options for DebugTokenRoundTripper.
+type DebugTokenRoundTripperOpts struct {
+	// HeaderName is a name of the header with the debug token.
+	// log.DebugTokenHeader is used by default.
+	HeaderName string
+
+	// AllowedHosts is a list of hosts (e.g., "api.internal" or "api.internal:8080") the debug token may be sent to.
+	// Hosts are matched case-insensitively against the request URL host, with and without the port.
+	// If it's empty, the token is sent to any host.
+	AllowedHosts []string
+}
+
+// NewDebugTokenRoundTripper creates an HTTP transport that propagates the debug token.
+func NewDebugTokenRoundTripper(delegate http.RoundTripper) http.RoundTripper {
+	return NewDebugTokenRoundTripperWithOpts(delegate, DebugTokenRoundTripperOpts{})
+}
+
+// NewDebugTokenRoundTripperWithOpts creates an HTTP transport that propagates the debug token with options.
+func NewDebugTokenRoundTripperWithOpts(delegate http.RoundTripper, opts DebugTokenRoundTripperOpts) http.RoundTripper {
+	if opts.HeaderName == "" {
+		opts.HeaderName = log.DebugTokenHeader
+	}
+	return &DebugTokenRoundTripper{Delegate: delegate, Opts: opts}
+}
+
+// RoundTrip adds the debug token header to the request if the token is present in the request context.
+func (rt *DebugTokenRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	token := log.GetDebugTokenFromContext(r.Context())
+	if token == "" || r.Header.Get(rt.Opts.HeaderName) != "" || !rt.isHostAllowed(r) {
+		return rt.Delegate.RoundTrip(r)
+	}
+
+	r = r.Clone(r.Context())
+	r.Header.Set(rt.Opts.HeaderName, token)
+	return rt.Delegate.RoundTrip(r)
+}
+
+func (rt *DebugTokenRoundTripper) isHostAllowed(r *http.Request) bool {
+	if len(rt.Opts.AllowedHosts) == 0 {
+		return true
+	}
+	for _, host := range rt.Opts.AllowedHosts {
+		if strings.EqualFold(host, r.URL.Host) || strings.EqualFold(host, r.URL.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/acronis/go-appkit/log"
+)
+
+func TestDebugTokenRoundTripper(t *testing.T) {
+	var receivedToken string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		receivedToken = r.Header.Get(log.DebugTokenHeader)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewDebugTokenRoundTripper(http.DefaultTransport)}
+
+	doRequest := func(ctx context.Context) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	doRequest(log.NewContextWithDebugToken(context.Background(), "debug-token"))
+	require.Equal(t, "debug-token", receivedToken)
+
+	doRequest(context.Background())
+	require.Empty(t, receivedToken)
+}
+
+func TestDebugTokenRoundTripper_AllowedHosts(t *testing.T) {
+	var receivedToken string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		receivedToken = r.Header.Get(log.DebugTokenHeader)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	doRequest := func(allowedHosts []string) {
+		client := &http.Client{Transport: NewDebugTokenRoundTripperWithOpts(http.DefaultTransport,
+			DebugTokenRoundTripperOpts{AllowedHosts: allowedHosts})}
+		ctx := log.NewContextWithDebugToken(context.Background(), "debug-token")
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	doRequest([]string{"example.com"})
+	require.Empty(t, receivedToken)
+
+	doRequest([]string{"example.com", "127.0.0.1"})
+	require.Equal(t, "debug-token", receivedToken)
+
+	doRequest([]string{strings.TrimPrefix(server.URL, "http://")})
+	require.Equal(t, "debug-token", receivedToken)
+}
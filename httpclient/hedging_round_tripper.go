@@ -0,0 +1,367 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Default parameter values for HedgingRoundTripper.
+const (
+	DefaultHedgingDelay              = 100 * time.Millisecond
+	DefaultHedgingMaxHedgedRequests  = 1
+	DefaultHedgingLatencyWindowSize  = 1000
+	DefaultHedgingLatencyMinSamples  = 100
+	maxHedgingMaxHedgedRequests      = 2
+	hedgingLatencyRecomputeFrequency = 10 // percentile is recomputed every window size / frequency samples
+)
+
+// HedgingMetricsCollector is an optional interface that may be implemented by MetricsCollector
+// for collecting metrics of HedgingRoundTripper.
+type HedgingMetricsCollector interface {
+	// IncHedgedRequests increments the number of hedged requests (additional copies of the original request).
+	IncHedgedRequests(clientType, remoteAddress string)
+	// IncHedgedRequestWins increments the number of times when the response to the hedged request is used.
+	IncHedgedRequestWins(clientType, remoteAddress string)
+}
+
+// HedgingRoundTripperOpts represents an options for HedgingRoundTripper.
+type HedgingRoundTripperOpts struct {
+	// Delay is a time after which the next copy of the request is sent if there is no response yet.
+	// If LatencyPercentile is set, Delay is used only until enough latencies are observed.
+	// By default, DefaultHedgingDelay const is used.
+	Delay time.Duration
+
+	// LatencyPercentile (0, 100) enables computing the delay as the percentile of the latencies
+	// of the recent successful requests (e.g., 95 means that copies are sent for 5% of the slowest requests).
+	LatencyPercentile float64
+
+	// LatencyWindowSize is a number of the recent latencies used for computing the percentile.
+	// By default, DefaultHedgingLatencyWindowSize const is used.
+	LatencyWindowSize int
+
+	// LatencyMinSamples is a minimum number of observed latencies required for computing the percentile.
+	// By default, DefaultHedgingLatencyMinSamples const is used.
+	LatencyMinSamples int
+
+	// MaxHedgedRequests is a maximum number of additional copies of the request (1 or 2).
+	// By default, DefaultHedgingMaxHedgedRequests const is used.
+	MaxHedgedRequests int
+
+	// ClientType represents a type of client, it's used in metrics.
+	ClientType string
+
+	// MetricsCollector is used for collecting hedging metrics if it implements HedgingMetricsCollector interface.
+	MetricsCollector MetricsCollector
+}
+
+// HedgingRoundTripper wraps an object that implements http.RoundTripper interface
+// and reduces tail latency by sending additional copies of idempotent requests
+// (GET, HEAD, OPTIONS or marked with NewContextWithIdempotentHint) if the response is not received in time.
+// The first successful response (no error and status code < 500) is returned,
+// other requests are canceled, and their responses are drained.
+type HedgingRoundTripper struct {
+	// Delegate is an object that implements http.RoundTripper interface
+	// and is used for sending HTTP requests under the hood.
+	Delegate http.RoundTripper
+
+	delay             time.Duration
+	maxHedgedRequests int
+	clientType        string
+	metricsCollector  HedgingMetricsCollector
+	latencies         *hedgingLatencyTracker
+}
+
+// NewHedgingRoundTripper creates a new HedgingRoundTripper with default options.
+func NewHedgingRoundTripper(delegate http.RoundTripper) (*HedgingRoundTripper, error) {
+	return NewHedgingRoundTripperWithOpts(delegate, HedgingRoundTripperOpts{})
+}
+
+// NewHedgingRoundTripperWithOpts creates a new HedgingRoundTripper with specified options.
+func NewHedgingRoundTripperWithOpts(delegate http.RoundTripper, opts HedgingRoundTripperOpts) (*HedgingRoundTripper, error) {
+	if opts.Delay < 0 {
+		return nil, fmt.Errorf("delay must be positive")
+	}
+	if opts.Delay == 0 {
+		opts.Delay = DefaultHedgingDelay
+	}
+	if opts.MaxHedgedRequests < 0 || opts.MaxHedgedRequests > maxHedgingMaxHedgedRequests {
+		return nil, fmt.Errorf("max hedged requests must be in range [1..%d]", maxHedgingMaxHedgedRequests)
+	}
+	if opts.MaxHedgedRequests == 0 {
+		opts.MaxHedgedRequests = DefaultHedgingMaxHedgedRequests
+	}
+	if opts.LatencyPercentile < 0 || opts.LatencyPercentile >= 100 {
+		return nil, fmt.Errorf("latency percentile must be in range (0..100)")
+	}
+	if opts.LatencyWindowSize <= 0 {
+		opts.LatencyWindowSize = DefaultHedgingLatencyWindowSize
+	}
+	if opts.LatencyMinSamples <= 0 {
+		opts.LatencyMinSamples = DefaultHedgingLatencyMinSamples
+	}
+
+	rt := &HedgingRoundTripper{
+		Delegate:          delegate,
+		delay:             opts.Delay,
+		maxHedgedRequests: opts.MaxHedgedRequests,
+		clientType:        opts.ClientType,
+		metricsCollector:  disabledHedgingMetrics{},
+	}
+	if mc, ok := opts.MetricsCollector.(HedgingMetricsCollector); ok {
+		rt.metricsCollector = mc
+	}
+	if opts.LatencyPercentile > 0 {
+		rt.latencies = newHedgingLatencyTracker(opts.LatencyPercentile, opts.LatencyWindowSize, opts.LatencyMinSamples)
+	}
+	return rt, nil
+}
+
+type hedgedResult struct {
+	idx  int
+	resp *http.Response
+	err  error
+}
+
+func (r hedgedResult) isSuccess() bool {
+	return r.err == nil && r.resp.StatusCode < http.StatusInternalServerError
+}
+
+// RoundTrip sends the request and its hedged copies if needed, and returns the first successful response.
+func (rt *HedgingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isIdempotentRequest(req) {
+		return rt.Delegate.RoundTrip(req)
+	}
+
+	getBody, err := makeRequestBodyReproducible(req)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	maxRequests := rt.maxHedgedRequests + 1
+	results := make(chan hedgedResult, maxRequests)
+	cancels := make([]context.CancelFunc, 0, maxRequests)
+	send := func() {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+		idx := len(cancels) - 1
+		go func() {
+			r := hedgedResult{idx: idx}
+			attemptReq := req.Clone(ctx) // Per RoundTripper contract.
+			if attemptReq.Body, r.err = getBody(); r.err == nil {
+				r.resp, r.err = rt.Delegate.RoundTrip(attemptReq) //nolint:bodyclose // body is closed by the receiver
+			}
+			results <- r
+		}()
+	}
+
+	send()
+	inFlight := 1
+	timer := time.NewTimer(rt.hedgingDelay())
+	defer timer.Stop()
+
+	var lastResult *hedgedResult
+	for {
+		select {
+		case <-timer.C:
+			if len(cancels) < maxRequests {
+				send()
+				inFlight++
+				rt.metricsCollector.IncHedgedRequests(rt.clientType, req.Host)
+				timer.Reset(rt.hedgingDelay())
+			}
+
+		case res := <-results:
+			inFlight--
+			if res.isSuccess() {
+				discardHedgedResult(lastResult, cancels)
+				return rt.handleSuccess(req, res, time.Since(startTime), cancels, results, inFlight), nil
+			}
+			// Keep the latest failed response. The response is preferred to the error.
+			if lastResult == nil || res.resp != nil || lastResult.resp == nil {
+				discardHedgedResult(lastResult, cancels)
+				lastResult = &res
+			} else {
+				discardHedgedResult(&res, cancels)
+			}
+			if inFlight == 0 {
+				return wrapHedgedResponse(*lastResult, cancels[lastResult.idx]), lastResult.err
+			}
+		}
+	}
+}
+
+func discardHedgedResult(res *hedgedResult, cancels []context.CancelFunc) {
+	if res == nil {
+		return
+	}
+	if res.resp != nil {
+		drainAndCloseResponseBody(res.resp)
+	}
+	cancels[res.idx]()
+}
+
+func (rt *HedgingRoundTripper) handleSuccess(
+	req *http.Request, res hedgedResult, latency time.Duration,
+	cancels []context.CancelFunc, results <-chan hedgedResult, inFlight int,
+) *http.Response {
+	// Latency is measured from sending the original request, so hedging doesn't shrink the observed latencies.
+	if rt.latencies != nil {
+		rt.latencies.observe(latency)
+	}
+	if res.idx > 0 {
+		rt.metricsCollector.IncHedgedRequestWins(rt.clientType, req.Host)
+	}
+
+	// Cancel other requests and drain their responses in background to allow connections reuse.
+	for i, cancel := range cancels {
+		if i != res.idx {
+			cancel()
+		}
+	}
+	if inFlight > 0 {
+		go func() {
+			for i := 0; i < inFlight; i++ {
+				if r := <-results; r.resp != nil {
+					drainAndCloseResponseBody(r.resp)
+				}
+			}
+		}()
+	}
+
+	return wrapHedgedResponse(res, cancels[res.idx])
+}
+
+// wrapHedgedResponse makes the context of the request to be canceled only after its response body is closed.
+func wrapHedgedResponse(res hedgedResult, cancel context.CancelFunc) *http.Response {
+	if res.resp == nil {
+		cancel()
+		return nil
+	}
+	res.resp.Body = &cancelOnCloseReadCloser{ReadCloser: res.resp.Body, cancel: cancel}
+	return res.resp
+}
+
+func (rt *HedgingRoundTripper) hedgingDelay() time.Duration {
+	if rt.latencies != nil {
+		if delay, ok := rt.latencies.percentile(); ok {
+			return delay
+		}
+	}
+	return rt.delay
+}
+
+func isIdempotentRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return GetIdempotentHintFromContext(req.Context())
+}
+
+// makeRequestBodyReproducible returns a function that provides an independent body for each copy of the request.
+// Unlike makeRequestBodyRewindable, the copies may be sent concurrently, so the body is buffered in memory
+// if http.Request.GetBody is not available.
+func makeRequestBodyReproducible(req *http.Request) (func() (io.ReadCloser, error), error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return func() (io.ReadCloser, error) { return req.Body, nil }, nil
+	}
+	defer func() {
+		_ = req.Body.Close() // Per RoundTripper contract.
+	}()
+	if req.GetBody != nil {
+		return req.GetBody, nil
+	}
+	bufferedReqBody, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read all request body before doing hedged requests: %w", err)
+	}
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(bufferedReqBody)), nil
+	}, nil
+}
+
+func drainAndCloseResponseBody(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
+
+type cancelOnCloseReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (rc *cancelOnCloseReadCloser) Close() error {
+	defer rc.cancel()
+	return rc.ReadCloser.Close()
+}
+
+// hedgingLatencyTracker keeps the recent latencies and computes their percentile.
+type hedgingLatencyTracker struct {
+	percentileRank   float64
+	minSamples       int
+	recomputeSamples int
+
+	mu                  sync.Mutex
+	samples             []time.Duration
+	next                int
+	sinceRecompute      int
+	cachedPercentile    time.Duration
+	hasCachedPercentile bool
+}
+
+func newHedgingLatencyTracker(percentileRank float64, windowSize, minSamples int) *hedgingLatencyTracker {
+	return &hedgingLatencyTracker{
+		percentileRank:   percentileRank,
+		minSamples:       min(minSamples, windowSize),
+		recomputeSamples: max(windowSize/hedgingLatencyRecomputeFrequency, 1),
+		samples:          make([]time.Duration, 0, windowSize),
+	}
+}
+
+func (t *hedgingLatencyTracker) observe(latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.samples) < cap(t.samples) {
+		t.samples = append(t.samples, latency)
+	} else {
+		t.samples[t.next] = latency
+		t.next = (t.next + 1) % len(t.samples)
+	}
+	t.sinceRecompute++
+}
+
+func (t *hedgingLatencyTracker) percentile() (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.samples) < t.minSamples {
+		return 0, false
+	}
+	if !t.hasCachedPercentile || t.sinceRecompute >= t.recomputeSamples {
+		sorted := slices.Clone(t.samples)
+		slices.Sort(sorted)
+		idx := int(math.Ceil(t.percentileRank/100*float64(len(sorted)))) - 1
+		t.cachedPercentile = sorted[max(idx, 0)]
+		t.hasCachedPercentile = true
+		t.sinceRecompute = 0
+	}
+	return t.cachedPercentile, true
+}
+
+type disabledHedgingMetrics struct{}
+
+func (disabledHedgingMetrics) IncHedgedRequests(string, string)    {}
+func (disabledHedgingMetrics) IncHedgedRequestWins(string, string) {}
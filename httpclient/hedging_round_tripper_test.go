@@ -0,0 +1,271 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type testServerForHedgingRoundTripper struct {
+	*httptest.Server
+	reqsNum        atomic.Int32
+	canceledReqs   atomic.Int32
+	mu             sync.Mutex
+	reqBodies      []string
+	handleAttempts func(attempt int) (delay time.Duration, statusCode int)
+}
+
+func newTestServerForHedgingRoundTripper(
+	handleAttempts func(attempt int) (delay time.Duration, statusCode int),
+) *testServerForHedgingRoundTripper {
+	srv := &testServerForHedgingRoundTripper{handleAttempts: handleAttempts}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		attempt := int(srv.reqsNum.Add(1))
+		reqBody, _ := io.ReadAll(r.Body)
+		srv.mu.Lock()
+		srv.reqBodies = append(srv.reqBodies, string(reqBody))
+		srv.mu.Unlock()
+
+		delay, statusCode := srv.handleAttempts(attempt)
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			srv.canceledReqs.Add(1)
+			return
+		}
+		rw.WriteHeader(statusCode)
+		_, _ = rw.Write([]byte("attempt " + strconv.Itoa(attempt)))
+	}))
+	return srv
+}
+
+func (s *testServerForHedgingRoundTripper) ReqBodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.reqBodies...)
+}
+
+func readResponseBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer func() { require.NoError(t, resp.Body.Close()) }()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestHedgingRoundTripper_RoundTrip(t *testing.T) {
+	t.Run("hedged request wins", func(t *testing.T) {
+		srv := newTestServerForHedgingRoundTripper(func(attempt int) (time.Duration, int) {
+			if attempt == 1 {
+				return time.Second * 10, http.StatusOK
+			}
+			return 0, http.StatusOK
+		})
+		defer srv.Close()
+
+		collector := NewPrometheusMetricsCollector("")
+		rt, err := NewHedgingRoundTripperWithOpts(http.DefaultTransport, HedgingRoundTripperOpts{
+			Delay:            time.Millisecond * 20,
+			ClientType:       "test-client",
+			MetricsCollector: collector,
+		})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		resp, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "attempt 2", readResponseBody(t, resp))
+
+		require.Eventually(t, func() bool { return srv.canceledReqs.Load() == 1 }, time.Second, time.Millisecond*10)
+		require.Equal(t, int32(2), srv.reqsNum.Load())
+		require.Equal(t, 1.0, promtestutil.ToFloat64(collector.HedgedRequests.WithLabelValues("test-client", req.Host)))
+		require.Equal(t, 1.0, promtestutil.ToFloat64(collector.HedgedRequestWins.WithLabelValues("test-client", req.Host)))
+	})
+
+	t.Run("latency of hedged request is measured from the original request", func(t *testing.T) {
+		srv := newTestServerForHedgingRoundTripper(func(attempt int) (time.Duration, int) {
+			if attempt == 1 {
+				return time.Second * 10, http.StatusOK
+			}
+			return 0, http.StatusOK
+		})
+		defer srv.Close()
+
+		const delay = time.Millisecond * 50
+		rt, err := NewHedgingRoundTripperWithOpts(http.DefaultTransport, HedgingRoundTripperOpts{
+			Delay:             delay,
+			LatencyPercentile: 50,
+			LatencyMinSamples: 1,
+		})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		resp, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, "attempt 2", readResponseBody(t, resp))
+
+		latency, ok := rt.latencies.percentile()
+		require.True(t, ok)
+		require.GreaterOrEqual(t, latency, delay)
+	})
+
+	t.Run("fast response, no hedging", func(t *testing.T) {
+		srv := newTestServerForHedgingRoundTripper(func(attempt int) (time.Duration, int) {
+			return 0, http.StatusOK
+		})
+		defer srv.Close()
+
+		rt, err := NewHedgingRoundTripperWithOpts(http.DefaultTransport, HedgingRoundTripperOpts{Delay: time.Second})
+		require.NoError(t, err)
+
+		resp, err := (&http.Client{Transport: rt}).Get(srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, "attempt 1", readResponseBody(t, resp))
+		require.Equal(t, int32(1), srv.reqsNum.Load())
+	})
+
+	t.Run("up to 2 hedged requests", func(t *testing.T) {
+		srv := newTestServerForHedgingRoundTripper(func(attempt int) (time.Duration, int) {
+			if attempt < 3 {
+				return time.Second * 10, http.StatusOK
+			}
+			return 0, http.StatusOK
+		})
+		defer srv.Close()
+
+		rt, err := NewHedgingRoundTripperWithOpts(http.DefaultTransport, HedgingRoundTripperOpts{
+			Delay:             time.Millisecond * 20,
+			MaxHedgedRequests: 2,
+		})
+		require.NoError(t, err)
+
+		resp, err := (&http.Client{Transport: rt}).Get(srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, "attempt 3", readResponseBody(t, resp))
+		require.Eventually(t, func() bool { return srv.canceledReqs.Load() == 2 }, time.Second, time.Millisecond*10)
+	})
+
+	t.Run("non-idempotent request is not hedged", func(t *testing.T) {
+		srv := newTestServerForHedgingRoundTripper(func(attempt int) (time.Duration, int) {
+			return time.Millisecond * 100, http.StatusOK
+		})
+		defer srv.Close()
+
+		rt, err := NewHedgingRoundTripperWithOpts(http.DefaultTransport, HedgingRoundTripperOpts{Delay: time.Millisecond * 10})
+		require.NoError(t, err)
+
+		resp, err := (&http.Client{Transport: rt}).Post(srv.URL, "text/plain", bytes.NewReader([]byte("data")))
+		require.NoError(t, err)
+		require.Equal(t, "attempt 1", readResponseBody(t, resp))
+		require.Equal(t, int32(1), srv.reqsNum.Load())
+	})
+
+	t.Run("request with idempotent hint is hedged with body", func(t *testing.T) {
+		srv := newTestServerForHedgingRoundTripper(func(attempt int) (time.Duration, int) {
+			if attempt == 1 {
+				return time.Second * 10, http.StatusOK
+			}
+			return 0, http.StatusOK
+		})
+		defer srv.Close()
+
+		rt, err := NewHedgingRoundTripperWithOpts(http.DefaultTransport, HedgingRoundTripperOpts{Delay: time.Millisecond * 20})
+		require.NoError(t, err)
+
+		ctx := NewContextWithIdempotentHint(context.Background(), true)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, io.NopCloser(bytes.NewReader([]byte("data"))))
+		require.NoError(t, err)
+		resp, err := (&http.Client{Transport: rt}).Do(req)
+		require.NoError(t, err)
+		require.Equal(t, "attempt 2", readResponseBody(t, resp))
+		require.Equal(t, []string{"data", "data"}, srv.ReqBodies())
+	})
+
+	t.Run("all requests failed", func(t *testing.T) {
+		srv := newTestServerForHedgingRoundTripper(func(attempt int) (time.Duration, int) {
+			if attempt == 1 {
+				return time.Millisecond * 100, http.StatusServiceUnavailable
+			}
+			return 0, http.StatusInternalServerError
+		})
+		defer srv.Close()
+
+		rt, err := NewHedgingRoundTripperWithOpts(http.DefaultTransport, HedgingRoundTripperOpts{Delay: time.Millisecond * 20})
+		require.NoError(t, err)
+
+		resp, err := (&http.Client{Transport: rt}).Get(srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		require.Equal(t, "attempt 1", readResponseBody(t, resp))
+	})
+}
+
+func TestNewHedgingRoundTripperWithOpts(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Opts       HedgingRoundTripperOpts
+		WantErrMsg string
+	}{
+		{Name: "negative delay", Opts: HedgingRoundTripperOpts{Delay: -1}, WantErrMsg: "delay must be positive"},
+		{
+			Name:       "too many hedged requests",
+			Opts:       HedgingRoundTripperOpts{MaxHedgedRequests: 3},
+			WantErrMsg: "max hedged requests must be in range [1..2]",
+		},
+		{
+			Name:       "invalid latency percentile",
+			Opts:       HedgingRoundTripperOpts{LatencyPercentile: 100},
+			WantErrMsg: "latency percentile must be in range (0..100)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := NewHedgingRoundTripperWithOpts(http.DefaultTransport, tt.Opts)
+			require.EqualError(t, err, tt.WantErrMsg)
+		})
+	}
+}
+
+func TestHedgingLatencyTracker(t *testing.T) {
+	tracker := newHedgingLatencyTracker(90, 10, 5)
+
+	for i := 1; i <= 4; i++ {
+		tracker.observe(time.Duration(i) * time.Millisecond)
+	}
+	_, ok := tracker.percentile()
+	require.False(t, ok)
+
+	for i := 5; i <= 10; i++ {
+		tracker.observe(time.Duration(i) * time.Millisecond)
+	}
+	p, ok := tracker.percentile()
+	require.True(t, ok)
+	require.Equal(t, 9*time.Millisecond, p)
+
+	// Old samples are replaced by the new ones.
+	for i := 0; i < 10; i++ {
+		tracker.observe(time.Second)
+	}
+	p, ok = tracker.percentile()
+	require.True(t, ok)
+	require.Equal(t, time.Second, p)
+}
@@ -12,16 +12,18 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/acronis/go-appkit/breaker"
 	"github.com/acronis/go-appkit/log"
+	"github.com/acronis/go-appkit/retry"
 )
 
-// New wraps delegate transports with logging, rate limiting, retryable, request id
+// New wraps delegate transports with logging, rate limiting, circuit breaking, retryable, request id
 // and returns an error if any occurs.
 func New(cfg *Config) (*http.Client, error) {
 	return NewWithOpts(cfg, Opts{})
 }
 
-// MustNew wraps delegate transports with logging, rate limiting, retryable, request id
+// MustNew wraps delegate transports with logging, rate limiting, circuit breaking, retryable, request id
 // and panics if any error occurs.
 func MustNew(cfg *Config) *http.Client {
 	client, err := New(cfg)
@@ -57,10 +59,31 @@ type Opts struct {
 
 	// ClassifyRequest does request classification, producing non-parameterized summary in metrics round tripper.
 	ClassifyRequest func(r *http.Request, clientType string) string
+
+	// RetryBudget limits the number of retries (see RetryableRoundTripperOpts.RetryBudget).
+	// It's used only if retries are enabled in the config.
+	RetryBudget *retry.Budget
+
+	// CircuitBreakerLogger is used for logging state changes of circuit breakers.
+	// It's used only if circuit breakers are enabled in the config.
+	CircuitBreakerLogger log.FieldLogger
+
+	// CircuitBreakerMetricsCollector collects metrics of circuit breakers (e.g., breaker.PrometheusMetrics).
+	// It's used only if circuit breakers are enabled in the config.
+	CircuitBreakerMetricsCollector breaker.MetricsCollector
+
+	// PropagateDebugToken enables propagation of the debug token (see log.GetDebugTokenFromContext)
+	// to the downstream service, so debug logs are enabled there for the request as well.
+	// The token is sent to every host the client calls unless DebugTokenAllowedHosts is specified.
+	PropagateDebugToken bool
+
+	// DebugTokenAllowedHosts restricts the hosts the debug token is propagated to
+	// (see DebugTokenRoundTripperOpts.AllowedHosts). It's used only if PropagateDebugToken is true.
+	DebugTokenAllowedHosts []string
 }
 
 // NewWithOpts wraps delegate transports with options
-// logging, metrics, rate limiting, retryable, user agent, request id
+// logging, metrics, rate limiting, circuit breaking, retryable, user agent, request id
 // and returns an error if any occurs.
 func NewWithOpts(cfg *Config, opts Opts) (*http.Client, error) {
 	if cfg == nil {
@@ -83,6 +106,13 @@ func NewWithOpts(cfg *Config, opts Opts) (*http.Client, error) {
 		}
 	}
 
+	if cfg.CircuitBreaker.Enabled {
+		cbOpts := cfg.CircuitBreaker.TransportOpts()
+		cbOpts.Logger = opts.CircuitBreakerLogger
+		cbOpts.BreakerOpts.MetricsCollector = opts.CircuitBreakerMetricsCollector
+		delegate = NewCircuitBreakerRoundTripperWithOpts(delegate, cbOpts)
+	}
+
 	if opts.UserAgent != "" {
 		delegate = NewUserAgentRoundTripper(delegate, opts.UserAgent)
 	}
@@ -97,10 +127,17 @@ func NewWithOpts(cfg *Config, opts Opts) (*http.Client, error) {
 		RequestIDProvider: opts.RequestIDProvider,
 	})
 
+	if opts.PropagateDebugToken {
+		delegate = NewDebugTokenRoundTripperWithOpts(delegate, DebugTokenRoundTripperOpts{
+			AllowedHosts: opts.DebugTokenAllowedHosts,
+		})
+	}
+
 	if cfg.Retries.Enabled {
 		retryOpts := cfg.Retries.TransportOpts()
 		retryOpts.LoggerProvider = opts.LoggerProvider
 		retryOpts.BackoffPolicy = cfg.Retries.GetPolicy()
+		retryOpts.RetryBudget = opts.RetryBudget
 		delegate, err = NewRetryableRoundTripperWithOpts(delegate, retryOpts)
 		if err != nil {
 			return nil, fmt.Errorf("create retryable round tripper: %w", err)
@@ -125,7 +162,7 @@ func NewWithOpts(cfg *Config, opts Opts) (*http.Client, error) {
 }
 
 // MustNewWithOpts wraps delegate transports with options
-// logging, metrics, rate limiting, retryable, user agent, request id
+// logging, metrics, rate limiting, circuit breaking, retryable, user agent, request id
 // and panics if any error occurs.
 func MustNewWithOpts(cfg *Config, opts Opts) *http.Client {
 	client, err := NewWithOpts(cfg, opts)
@@ -12,12 +12,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 
+	"github.com/acronis/go-appkit/breaker"
 	"github.com/acronis/go-appkit/config"
 	"github.com/acronis/go-appkit/httpserver/middleware"
 	"github.com/acronis/go-appkit/log/logtest"
@@ -191,3 +194,27 @@ func TestMustHTTPClientWithOptsRoundTripperMetrics(t *testing.T) {
 	hist := collector.Durations.With(labels).(prometheus.Histogram)
 	testutil.AssertSamplesCountInHistogram(t, hist, 1)
 }
+
+func TestMustHTTPClientWithOptsCircuitBreaker(t *testing.T) {
+	var reqsCount atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		reqsCount.Add(1)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := NewConfig()
+	cfg.Retries.Enabled = true
+	cfg.Retries.MaxAttempts = 5
+	cfg.Retries.Policy = RetryPolicyConstant
+	cfg.Retries.ConstantBackoff = ConstantBackoffConfig{Interval: config.TimeDuration(time.Millisecond)}
+	cfg.CircuitBreaker = CircuitBreakerConfig{Enabled: true, ConsecutiveFailures: 2}
+
+	cbMetrics := breaker.NewPrometheusMetrics()
+	client := MustNewWithOpts(cfg, Opts{CircuitBreakerMetricsCollector: cbMetrics})
+
+	_, err := client.Get(server.URL) //nolint:bodyclose // error is expected
+	require.ErrorIs(t, err, breaker.ErrOpenState)
+	require.Equal(t, int32(2), reqsCount.Load())
+	require.Equal(t, 1.0, promtestutil.ToFloat64(cbMetrics.RejectedRequests.WithLabelValues(server.Listener.Addr().String())))
+}
@@ -27,26 +27,45 @@ type MetricsCollector interface {
 type PrometheusMetricsCollector struct {
 	// Durations is a histogram of the http client requests durations.
 	Durations *prometheus.HistogramVec
+
+	// HedgedRequests is a counter of the hedged requests sent by HedgingRoundTripper.
+	HedgedRequests *prometheus.CounterVec
+
+	// HedgedRequestWins is a counter of the hedged requests which responses are used by HedgingRoundTripper.
+	HedgedRequestWins *prometheus.CounterVec
 }
 
+var _ HedgingMetricsCollector = (*PrometheusMetricsCollector)(nil)
+
 // NewPrometheusMetricsCollector creates a new Prometheus metrics collector.
 func NewPrometheusMetricsCollector(namespace string) *PrometheusMetricsCollector {
+	constLabels := prometheus.Labels{libinfo.PrometheusLibVersionLabel: libinfo.GetLibVersion()}
 	return &PrometheusMetricsCollector{
 		Durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
-			Namespace: namespace,
-			Name:      "http_client_request_duration_seconds",
-			Help:      "A histogram of the http client requests durations.",
-			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 150, 300, 600},
-			ConstLabels: prometheus.Labels{
-				libinfo.PrometheusLibVersionLabel: libinfo.GetLibVersion(),
-			},
+			Namespace:   namespace,
+			Name:        "http_client_request_duration_seconds",
+			Help:        "A histogram of the http client requests durations.",
+			Buckets:     []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 150, 300, 600},
+			ConstLabels: constLabels,
 		}, []string{"client_type", "remote_address", "summary", "status", "request_type"}),
+		HedgedRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "http_client_hedged_requests_total",
+			Help:        "Number of hedged requests (additional copies of the original requests) sent by the http client.",
+			ConstLabels: constLabels,
+		}, []string{"client_type", "remote_address"}),
+		HedgedRequestWins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "http_client_hedged_request_wins_total",
+			Help:        "Number of hedged requests which responses are used by the http client.",
+			ConstLabels: constLabels,
+		}, []string{"client_type", "remote_address"}),
 	}
 }
 
 // MustRegister registers the Prometheus metrics.
 func (p *PrometheusMetricsCollector) MustRegister() {
-	prometheus.MustRegister(p.Durations)
+	prometheus.MustRegister(p.Durations, p.HedgedRequests, p.HedgedRequestWins)
 }
 
 // RequestDuration observes the duration of the request and the status code.
@@ -59,6 +78,18 @@ func (p *PrometheusMetricsCollector) RequestDuration(
 // Unregister the Prometheus metrics.
 func (p *PrometheusMetricsCollector) Unregister() {
 	prometheus.Unregister(p.Durations)
+	prometheus.Unregister(p.HedgedRequests)
+	prometheus.Unregister(p.HedgedRequestWins)
+}
+
+// IncHedgedRequests increments the number of hedged requests.
+func (p *PrometheusMetricsCollector) IncHedgedRequests(clientType, remoteAddress string) {
+	p.HedgedRequests.WithLabelValues(clientType, remoteAddress).Inc()
+}
+
+// IncHedgedRequestWins increments the number of hedged requests which responses are used.
+func (p *PrometheusMetricsCollector) IncHedgedRequestWins(clientType, remoteAddress string) {
+	p.HedgedRequestWins.WithLabelValues(clientType, remoteAddress).Inc()
 }
 
 // MetricsRoundTripper is an HTTP transport that measures requests done.
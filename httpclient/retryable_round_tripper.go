@@ -75,6 +75,11 @@ type RetryableRoundTripper struct {
 	// when the given response doesn't contain Retry-After HTTP header or IgnoreRetryAfter is true.
 	// By default, DefaultBackoffPolicy is used.
 	BackoffPolicy retry.Policy
+
+	// RetryBudget limits the number of retries to prevent retry storms (nil means no limit).
+	// It's refilled by successful requests (i.e., requests that are not retried and don't end with 5xx status)
+	// and may be shared between multiple clients.
+	RetryBudget *retry.Budget
 }
 
 // RetryableRoundTripperOpts represents an options for RetryableRoundTripper.
@@ -107,6 +112,11 @@ type RetryableRoundTripperOpts struct {
 	// when the given response doesn't contain Retry-After HTTP header or IgnoreRetryAfter is true.
 	// By default, DefaultBackoffPolicy is used.
 	BackoffPolicy retry.Policy
+
+	// RetryBudget limits the number of retries to prevent retry storms (nil means no limit).
+	// It's refilled by successful requests (i.e., requests that are not retried and don't end with 5xx status)
+	// and may be shared between multiple clients.
+	RetryBudget *retry.Budget
 }
 
 // NewRetryableRoundTripper returns a new instance of RetryableRoundTripper.
@@ -145,6 +155,7 @@ func NewRetryableRoundTripperWithOpts(
 		CheckRetry:       opts.CheckRetryFunc,
 		BackoffPolicy:    opts.BackoffPolicy,
 		IgnoreRetryAfter: opts.IgnoreRetryAfter,
+		RetryBudget:      opts.RetryBudget,
 	}, nil
 }
 
@@ -208,6 +219,9 @@ func (rt *RetryableRoundTripper) RoundTrip(req *http.Request) (*http.Response, e
 			return resp, roundTripErr
 		}
 		if !needRetry {
+			if rt.RetryBudget != nil && roundTripErr == nil && resp.StatusCode < http.StatusInternalServerError {
+				rt.RetryBudget.RecordSuccess()
+			}
 			return resp, roundTripErr
 		}
 
@@ -221,6 +235,10 @@ func (rt *RetryableRoundTripper) RoundTrip(req *http.Request) (*http.Response, e
 		if stop {
 			return resp, roundTripErr
 		}
+		if rt.RetryBudget != nil && !rt.RetryBudget.TryRetry() {
+			rt.logger(reqCtx).Warnf("retry budget exhausted, %d request(s) done", curRetryAttemptNum+1)
+			return resp, roundTripErr
+		}
 
 		select {
 		case <-reqCtx.Done():
@@ -501,6 +501,50 @@ func TestRetryableRoundTripper_RoundTrip_Logging(t *testing.T) {
 	})
 }
 
+func TestRetryableRoundTripper_RoundTrip_RetryBudget(t *testing.T) {
+	testSrv := newTestServerForRetryableRoundTripper()
+	defer testSrv.Close()
+
+	logRecorder := logtest.NewRecorder()
+	budget := retry.NewBudgetWithOpts(0.5, retry.BudgetOpts{MaxTokens: 2})
+	countingRT := &countingRoundTripper{delegate: http.DefaultTransport}
+	retryableRT, err := NewRetryableRoundTripperWithOpts(countingRT, RetryableRoundTripperOpts{
+		Logger:           logRecorder,
+		MaxRetryAttempts: 5,
+		BackoffPolicy:    retry.NewConstantBackoffPolicy(time.Millisecond, 0),
+		RetryBudget:      budget,
+	})
+	require.NoError(t, err)
+	httpClient := &http.Client{Transport: retryableRT}
+
+	doRequest := func(wantRespCode int) {
+		resp, reqErr := httpClient.Get(testSrv.URL)
+		require.NoError(t, reqErr)
+		require.Equal(t, wantRespCode, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	// Only 2 retries are allowed by the budget.
+	testSrv.Reset([]int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable})
+	doRequest(http.StatusServiceUnavailable)
+	require.Equal(t, 3, countingRT.reqsNum)
+	require.Equal(t, 0.0, budget.Tokens())
+	logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelWarn),
+		logtest.HasMessage("retry budget exhausted, 3 request(s) done"))
+
+	// Successful requests refill the budget.
+	countingRT.reqsNum = 0
+	testSrv.Reset(nil)
+	doRequest(http.StatusOK)
+	doRequest(http.StatusOK)
+	require.Equal(t, 1.0, budget.Tokens())
+
+	testSrv.Reset([]int{http.StatusOK, http.StatusServiceUnavailable})
+	doRequest(http.StatusOK)
+	require.Equal(t, 4, countingRT.reqsNum)
+	require.Equal(t, 0.5, budget.Tokens())
+}
+
 func TestRetryableRoundTripper_RoundTrip_IdempotentFlag(t *testing.T) {
 	testSrv := newTestServerForRetryableRoundTripper()
 	defer testSrv.Close()
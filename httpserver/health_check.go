@@ -14,6 +14,7 @@ import (
 	"github.com/acronis/go-appkit/httpserver/middleware"
 	"github.com/acronis/go-appkit/log"
 	"github.com/acronis/go-appkit/restapi"
+	"github.com/acronis/go-appkit/service"
 )
 
 // StatusClientClosedRequest is a special HTTP status code used by Nginx to show that the client
@@ -41,6 +42,66 @@ type HealthCheck = func() (HealthCheckResult, error)
 // HealthCheckContext is a type alias for health-check operation that has access to the request Context
 type HealthCheckContext = func(ctx context.Context) (HealthCheckResult, error)
 
+// HealthCheckComponentServiceLifecycle is a name of the health-check component that reflects
+// the lifecycle state of the service (see NewLivenessHealthCheck and NewReadinessHealthCheck).
+const HealthCheckComponentServiceLifecycle HealthCheckComponentName = "service_lifecycle"
+
+// NewLivenessHealthCheck creates a HealthCheck that fails when the service is stopped.
+// It's supposed to be used for the /healthz endpoint. Note that the draining service is still alive.
+//
+// Since the service usually wraps the HTTP server, it may be created first and its unit may be set later:
+//
+//	svc := service.New(logger, nil)
+//	srv, err := httpserver.New(cfg, logger, httpserver.Opts{
+//		HealthCheck:    httpserver.NewLivenessHealthCheck(svc),
+//		ReadinessCheck: httpserver.NewReadinessHealthCheck(svc),
+//	})
+//	svc.Unit = srv
+func NewLivenessHealthCheck(stateProvider service.LifecycleStateProvider) HealthCheck {
+	return func() (HealthCheckResult, error) {
+		return HealthCheckResult{
+			HealthCheckComponentServiceLifecycle: healthCheckStatusFromBool(stateProvider.State().IsAlive()),
+		}, nil
+	}
+}
+
+// NewReadinessHealthCheck creates a HealthCheck that succeeds only when the service is ready.
+// It's supposed to be used for the /readyz endpoint.
+// The check fails while the service is starting, and flips to failure before the graceful stop begins,
+// so load balancers stop sending new requests to the instance.
+func NewReadinessHealthCheck(stateProvider service.LifecycleStateProvider) HealthCheck {
+	return func() (HealthCheckResult, error) {
+		return HealthCheckResult{
+			HealthCheckComponentServiceLifecycle: healthCheckStatusFromBool(stateProvider.State().IsReady()),
+		}, nil
+	}
+}
+
+// CombineHealthChecks creates a HealthCheck that runs all the given health-checks and merges their results.
+// The first occurred error is returned.
+func CombineHealthChecks(checks ...HealthCheck) HealthCheck {
+	return func() (HealthCheckResult, error) {
+		combined := HealthCheckResult{}
+		for _, check := range checks {
+			result, err := check()
+			if err != nil {
+				return nil, err
+			}
+			for name, status := range result {
+				combined[name] = status
+			}
+		}
+		return combined, nil
+	}
+}
+
+func healthCheckStatusFromBool(ok bool) HealthCheckStatus {
+	if ok {
+		return HealthCheckStatusOK
+	}
+	return HealthCheckStatusFail
+}
+
 type healthCheckResponseData struct {
 	Components map[string]bool `json:"components"`
 }
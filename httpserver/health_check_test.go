@@ -21,6 +21,7 @@ import (
 	"github.com/acronis/go-appkit/log"
 	"github.com/acronis/go-appkit/log/logtest"
 	"github.com/acronis/go-appkit/restapi"
+	"github.com/acronis/go-appkit/service"
 )
 
 func TestHealthCheckHandler_ServeHTTP(t *testing.T) {
@@ -241,3 +242,76 @@ func requireHealthCheckErrorWasLogged(t *testing.T, logRecorder *logtest.Recorde
 	require.True(t, found)
 	require.Equal(t, wantErr, loggedErrorField.Any)
 }
+
+type lifecycleStateStub service.LifecycleState
+
+func (s lifecycleStateStub) State() service.LifecycleState {
+	return service.LifecycleState(s)
+}
+
+func TestLivenessAndReadinessHealthChecks(t *testing.T) {
+	tests := []struct {
+		state         service.LifecycleState
+		wantLiveness  HealthCheckStatus
+		wantReadiness HealthCheckStatus
+	}{
+		{service.LifecycleStateNew, HealthCheckStatusOK, HealthCheckStatusFail},
+		{service.LifecycleStateStarting, HealthCheckStatusOK, HealthCheckStatusFail},
+		{service.LifecycleStateReady, HealthCheckStatusOK, HealthCheckStatusOK},
+		{service.LifecycleStateDraining, HealthCheckStatusOK, HealthCheckStatusFail},
+		{service.LifecycleStateStopped, HealthCheckStatusFail, HealthCheckStatusFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.state.String(), func(t *testing.T) {
+			stateProvider := lifecycleStateStub(tt.state)
+
+			result, err := NewLivenessHealthCheck(stateProvider)()
+			require.NoError(t, err)
+			require.Equal(t, HealthCheckResult{HealthCheckComponentServiceLifecycle: tt.wantLiveness}, result)
+
+			result, err = NewReadinessHealthCheck(stateProvider)()
+			require.NoError(t, err)
+			require.Equal(t, HealthCheckResult{HealthCheckComponentServiceLifecycle: tt.wantReadiness}, result)
+		})
+	}
+}
+
+func TestCombineHealthChecks(t *testing.T) {
+	dbCheck := func() (HealthCheckResult, error) {
+		return HealthCheckResult{"db": HealthCheckStatusOK}, nil
+	}
+	cacheCheck := func() (HealthCheckResult, error) {
+		return HealthCheckResult{"cache": HealthCheckStatusFail}, nil
+	}
+
+	result, err := CombineHealthChecks(dbCheck, cacheCheck, NewReadinessHealthCheck(lifecycleStateStub(service.LifecycleStateReady)))()
+	require.NoError(t, err)
+	require.Equal(t, HealthCheckResult{
+		"db":                                 HealthCheckStatusOK,
+		"cache":                              HealthCheckStatusFail,
+		HealthCheckComponentServiceLifecycle: HealthCheckStatusOK,
+	}, result)
+
+	internalErr := errors.New("internal error")
+	_, err = CombineHealthChecks(dbCheck, func() (HealthCheckResult, error) { return nil, internalErr })()
+	require.ErrorIs(t, err, internalErr)
+}
+
+func TestRouter_ReadinessEndpoint(t *testing.T) {
+	serveReadyz := func(opts RouterOpts) int {
+		router := NewRouter(logtest.NewLogger(), opts)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return resp.Code
+	}
+
+	require.Equal(t, http.StatusNotFound, serveReadyz(RouterOpts{}))
+	require.Equal(t, http.StatusOK, serveReadyz(RouterOpts{ReadinessCheck: func() (HealthCheckResult, error) {
+		return HealthCheckResult{}, nil
+	}}))
+	require.Equal(t, http.StatusServiceUnavailable, serveReadyz(RouterOpts{
+		ReadinessCheckContext: func(ctx context.Context) (HealthCheckResult, error) {
+			return HealthCheckResult{"db": HealthCheckStatusFail}, nil
+		},
+	}))
+}
@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -31,7 +32,7 @@ const (
 )
 
 // systemEndpoints is a list of endpoints which are not involved in metrics collecting, and in-flight requests limiting.
-var systemEndpoints = []string{"/metrics", "/healthz"}
+var systemEndpoints = []string{"/metrics", "/healthz", "/readyz"}
 
 // APIVersion is a type alias for API version.
 type APIVersion = int
@@ -66,6 +67,11 @@ type Opts struct {
 	HealthCheck HealthCheck
 	// HealthCheckContext is a function that performs context-aware health check logic.
 	HealthCheckContext HealthCheckContext
+	// ReadinessCheck is a function that performs readiness check logic for the /readyz endpoint.
+	// The /readyz endpoint is registered only if ReadinessCheck or ReadinessCheckContext is set.
+	ReadinessCheck HealthCheck
+	// ReadinessCheckContext is a function that performs context-aware readiness check logic for the /readyz endpoint.
+	ReadinessCheckContext HealthCheckContext
 	// MetricsHandler is a custom handler for the /metrics endpoint (e.g., Prometheus handler).
 	MetricsHandler http.Handler
 	// HTTPRequestMetrics contains options for configuring HTTP request metrics middleware.
@@ -80,13 +86,15 @@ type Opts struct {
 
 func (opts Opts) routerOpts() RouterOpts {
 	return RouterOpts{
-		ServiceNameInURL:   opts.ServiceNameInURL,
-		APIRoutes:          opts.APIRoutes,
-		RootMiddlewares:    opts.RootMiddlewares,
-		ErrorDomain:        opts.ErrorDomain,
-		HealthCheck:        opts.HealthCheck,
-		HealthCheckContext: opts.HealthCheckContext,
-		MetricsHandler:     opts.MetricsHandler,
+		ServiceNameInURL:      opts.ServiceNameInURL,
+		APIRoutes:             opts.APIRoutes,
+		RootMiddlewares:       opts.RootMiddlewares,
+		ErrorDomain:           opts.ErrorDomain,
+		HealthCheck:           opts.HealthCheck,
+		HealthCheckContext:    opts.HealthCheckContext,
+		ReadinessCheck:        opts.ReadinessCheck,
+		ReadinessCheckContext: opts.ReadinessCheckContext,
+		MetricsHandler:        opts.MetricsHandler,
 	}
 }
 
@@ -107,10 +115,13 @@ type HTTPServer struct {
 	port                     int32
 	httpServerDone           atomic.Value
 	httpReqPrometheusMetrics *middleware.HTTPRequestPrometheusMetrics
+	readyOnce                sync.Once
+	ready                    chan struct{}
 }
 
 var _ service.Unit = (*HTTPServer)(nil)
 var _ service.MetricsRegisterer = (*HTTPServer)(nil)
+var _ service.ReadinessNotifier = (*HTTPServer)(nil)
 
 // New creates a new HTTPServer with predefined logging, metrics collecting,
 // recovering after panics and health-checking functionality.
@@ -234,6 +245,8 @@ func (s *HTTPServer) Start(fatalError chan<- error) {
 		atomic.StoreInt32(&s.port, int32(port))
 	}
 
+	close(s.readyChan())
+
 	if s.TLS.Enabled {
 		err = s.HTTPServer.ServeTLS(s.listener, s.TLS.Certificate, s.TLS.Key)
 	} else {
@@ -251,6 +264,18 @@ func (s *HTTPServer) Start(fatalError chan<- error) {
 	}
 }
 
+// Ready returns a channel that is closed when the server starts listening for incoming connections.
+func (s *HTTPServer) Ready() <-chan struct{} {
+	return s.readyChan()
+}
+
+func (s *HTTPServer) readyChan() chan struct{} {
+	s.readyOnce.Do(func() {
+		s.ready = make(chan struct{})
+	})
+	return s.ready
+}
+
 // Stop stops application HTTP server (gracefully or not).
 func (s *HTTPServer) Stop(gracefully bool) error {
 	if !gracefully {
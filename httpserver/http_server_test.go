@@ -38,6 +38,7 @@ import (
 	"github.com/acronis/go-appkit/log"
 	"github.com/acronis/go-appkit/log/logtest"
 	"github.com/acronis/go-appkit/restapi"
+	"github.com/acronis/go-appkit/service"
 	"github.com/acronis/go-appkit/testutil"
 )
 
@@ -774,3 +775,42 @@ func TestHTTPServer_NewWithAPIRoutesHasMetrics(t *testing.T) {
 	defer func() { require.NoError(t, resp.Body.Close()) }()
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 }
+
+func TestHTTPServer_LivenessAndReadiness(t *testing.T) {
+	addr := testutil.GetLocalAddrWithFreeTCPPort()
+
+	states := make(chan service.LifecycleState, 10)
+	svc := service.NewWithOpts(logtest.NewLogger(), nil, service.Opts{
+		OnStateChange: func(state service.LifecycleState) { states <- state },
+	})
+	cfg := NewDefaultConfig()
+	cfg.Address = addr
+	httpServer, err := New(cfg, logtest.NewLogger(), Opts{
+		HealthCheck:    NewLivenessHealthCheck(svc),
+		ReadinessCheck: NewReadinessHealthCheck(svc),
+	})
+	require.NoError(t, err)
+	svc.Unit = httpServer
+
+	ctx, cancel := context.WithCancel(context.Background())
+	svcErr := make(chan error, 1)
+	go func() { svcErr <- svc.StartContext(ctx) }()
+
+	require.Equal(t, service.LifecycleStateStarting, <-states)
+	require.Equal(t, service.LifecycleStateReady, <-states)
+	require.Equal(t, service.LifecycleStateReady, svc.State())
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	for _, endpoint := range []string{"/healthz", "/readyz"} {
+		resp, getErr := client.Get(httpServer.URL + endpoint)
+		require.NoError(t, getErr)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode, endpoint)
+	}
+
+	cancel()
+	require.NoError(t, <-svcErr)
+	require.Equal(t, service.LifecycleStateDraining, <-states)
+	require.Equal(t, service.LifecycleStateStopped, <-states)
+	require.Equal(t, service.LifecycleStateStopped, svc.State())
+}
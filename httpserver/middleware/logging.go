@@ -43,6 +43,14 @@ type LoggingOpts struct {
 	TimeSlotsThreshold     time.Duration // controls when to include "time_slots" field group into final log message
 	// If CustomLoggerProvider is not set or returns nil, loggingHandler.logger will be used.
 	CustomLoggerProvider CustomLoggerProvider
+	// DebugTokenValidator enables escalation of the request-scoped logger level to debug
+	// for requests with a valid token in the DebugTokenHeader header.
+	// The logger should support escalation (see log.WithDebugEscalation).
+	// The valid token is also put into the request context (see log.NewContextWithDebugToken),
+	// so it may be propagated to downstream services by httpclient.
+	DebugTokenValidator log.DebugTokenValidator
+	// DebugTokenHeader is a name of the header with the debug token. log.DebugTokenHeader is used by default.
+	DebugTokenHeader string
 }
 
 type loggingHandler struct {
@@ -65,6 +73,9 @@ func LoggingWithOpts(logger log.FieldLogger, opts LoggingOpts) func(next http.Ha
 	if opts.TimeSlotsThreshold == 0 {
 		opts.TimeSlotsThreshold = opts.SlowRequestThreshold
 	}
+	if opts.DebugTokenHeader == "" {
+		opts.DebugTokenHeader = log.DebugTokenHeader
+	}
 	return func(next http.Handler) http.Handler {
 		return &loggingHandler{next: next, logger: logger, opts: opts}
 	}
@@ -85,6 +96,12 @@ func (h *loggingHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 			loggerForNext = l
 		}
 	}
+	if h.opts.DebugTokenValidator != nil {
+		if token := r.Header.Get(h.opts.DebugTokenHeader); token != "" && h.opts.DebugTokenValidator.Validate(token) {
+			loggerForNext, _ = log.EscalateToDebug(loggerForNext)
+			ctx = log.NewContextWithDebugToken(ctx, token)
+		}
+	}
 	loggerForNext = loggerForNext.With(
 		log.String("request_id", GetRequestIDFromContext(ctx)),
 		log.String("int_request_id", GetInternalRequestIDFromContext(ctx)),
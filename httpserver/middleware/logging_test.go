@@ -342,6 +342,51 @@ func TestLoggingHandler_ServeHTTP_CustomLogger(t *testing.T) {
 	require.Len(t, customLogger.Entries(), 1)
 }
 
+func TestLoggingHandler_ServeHTTP_DebugEscalation(t *testing.T) {
+	const debugToken = "secret-token"
+
+	tests := []struct {
+		name          string
+		token         string
+		wantEscalated bool
+	}{
+		{name: "no token", token: "", wantEscalated: false},
+		{name: "invalid token", token: "invalid-token", wantEscalated: false},
+		{name: "valid token", token: debugToken, wantEscalated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logtest.NewRecorderWithLevel(log.LevelInfo)
+			var ctxDebugToken string
+			next := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				ctxDebugToken = log.GetDebugTokenFromContext(r.Context())
+				GetLoggerFromContext(r.Context()).Debug("debug message")
+				rw.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/endpoint", nil)
+			if tt.token != "" {
+				req.Header.Set(log.DebugTokenHeader, tt.token)
+			}
+			LoggingWithOpts(logger, LoggingOpts{
+				DebugTokenValidator: log.NewSharedDebugTokenValidator(debugToken),
+			})(next).ServeHTTP(httptest.NewRecorder(), req)
+
+			_, found := logger.FindEntry("debug message")
+			require.Equal(t, tt.wantEscalated, found)
+			if tt.wantEscalated {
+				require.Equal(t, debugToken, ctxDebugToken)
+			} else {
+				require.Empty(t, ctxDebugToken)
+			}
+			wantEntriesNum := 1 // "response completed" entry
+			if tt.wantEscalated {
+				wantEntriesNum++
+			}
+			require.Len(t, logger.Entries(), wantEntriesNum)
+		})
+	}
+}
+
 func TestLoggingHandler_ServeHTTP_HeadersOriginAddr(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("payload")))
 
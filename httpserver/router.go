@@ -22,13 +22,15 @@ import (
 
 // RouterOpts represents options for creating chi.Router.
 type RouterOpts struct {
-	ServiceNameInURL   string
-	APIRoutes          map[APIVersion]APIRoute
-	RootMiddlewares    []func(http.Handler) http.Handler
-	ErrorDomain        string
-	HealthCheck        HealthCheck
-	HealthCheckContext HealthCheckContext
-	MetricsHandler     http.Handler
+	ServiceNameInURL      string
+	APIRoutes             map[APIVersion]APIRoute
+	RootMiddlewares       []func(http.Handler) http.Handler
+	ErrorDomain           string
+	HealthCheck           HealthCheck
+	HealthCheckContext    HealthCheckContext
+	ReadinessCheck        HealthCheck
+	ReadinessCheckContext HealthCheckContext
+	MetricsHandler        http.Handler
 }
 
 // NewRouter creates a new chi.Router and performs its basic configuration.
@@ -55,6 +57,14 @@ func configureRouter(router chi.Router, logger log.FieldLogger, opts RouterOpts)
 		router.Method(http.MethodGet, "/healthz", NewHealthCheckHandler(opts.HealthCheck))
 	}
 
+	// Readiness endpoint is exposed only if the readiness check is provided,
+	// otherwise it would always report the service as ready.
+	if opts.ReadinessCheckContext != nil {
+		router.Method(http.MethodGet, "/readyz", NewHealthCheckHandlerContext(opts.ReadinessCheckContext))
+	} else if opts.ReadinessCheck != nil {
+		router.Method(http.MethodGet, "/readyz", NewHealthCheckHandler(opts.ReadinessCheck))
+	}
+
 	router.Route(fmt.Sprintf("/api/%s", opts.ServiceNameInURL), func(router chi.Router) {
 		for ver, r := range opts.APIRoutes {
 			router.Route(fmt.Sprintf("/v%d", ver), r)
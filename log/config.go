@@ -34,6 +34,11 @@ const (
 	cfgKeyMaskingEnabled               = "masking.enabled"
 	cfgKeyMaskingUseDefaultRules       = "masking.useDefaultRules"
 	cfgKeyMaskingRules                 = "masking.rules"
+	cfgKeyFieldNamesTime               = "fieldNames.time"
+	cfgKeyFieldNamesLevel              = "fieldNames.level"
+	cfgKeyFieldNamesMsg                = "fieldNames.msg"
+	cfgKeyFieldNamesCaller             = "fieldNames.caller"
+	cfgKeyFieldNamesError              = "fieldNames.error"
 )
 
 // Default and restriction values.
@@ -67,6 +72,11 @@ type Config struct {
 
 	Masking MaskingConfig `mapstructure:"masking" yaml:"masking" json:"masking"`
 
+	// FieldNames allows overriding the names of the standard fields (time, level, message, caller and error).
+	// Empty names mean that the default names for the chosen format are used.
+	// It's not applicable for the "text" format.
+	FieldNames FieldNamesConfig `mapstructure:"fieldNames" yaml:"fieldNames" json:"fieldNames"`
+
 	keyPrefix string
 }
 
@@ -164,6 +174,15 @@ type Format string
 const (
 	FormatJSON Format = "json"
 	FormatText Format = "text"
+
+	// FormatLogfmt is a format where each entry is a single line of space-separated key=value pairs.
+	FormatLogfmt Format = "logfmt"
+
+	// FormatECS is a JSON format with field names following the Elastic Common Schema.
+	FormatECS Format = "ecs"
+
+	// FormatGELF is a Graylog Extended Log Format (GELF) JSON format.
+	FormatGELF Format = "gelf"
 )
 
 // Output defines possible values for log outputs.
@@ -218,6 +237,15 @@ type MaskingConfig struct {
 	Rules           []MaskingRuleConfig `mapstructure:"rules" yaml:"rules" json:"rules"`
 }
 
+// FieldNamesConfig is a configuration for names of the standard log entry fields.
+type FieldNamesConfig struct {
+	Time   string `mapstructure:"time" yaml:"time" json:"time"`
+	Level  string `mapstructure:"level" yaml:"level" json:"level"`
+	Msg    string `mapstructure:"msg" yaml:"msg" json:"msg"`
+	Caller string `mapstructure:"caller" yaml:"caller" json:"caller"`
+	Error  string `mapstructure:"error" yaml:"error" json:"error"`
+}
+
 // MaskingRuleConfig is a configuration for a single masking rule.
 type MaskingRuleConfig struct {
 	Field   string            `mapstructure:"field" yaml:"field" json:"field"`
@@ -242,7 +270,8 @@ func (c *Config) KeyPrefix() string {
 
 var (
 	availableLevels  = []string{string(LevelError), string(LevelWarn), string(LevelInfo), string(LevelDebug)}
-	availableFormats = []string{string(FormatJSON), string(FormatText)}
+	availableFormats = []string{
+		string(FormatJSON), string(FormatText), string(FormatLogfmt), string(FormatECS), string(FormatGELF)}
 	availableOutputs = []string{string(OutputStdout), string(OutputStderr), string(OutputFile)}
 )
 
@@ -291,6 +320,10 @@ func (c *Config) Set(dp config.DataProvider) error {
 	if err := c.setMaskingConfig(dp); err != nil {
 		return err
 	}
+
+	if err := c.setFieldNamesConfig(dp); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -351,3 +384,22 @@ func (c *Config) setMaskingConfig(dp config.DataProvider) (err error) {
 	}
 	return nil
 }
+
+func (c *Config) setFieldNamesConfig(dp config.DataProvider) (err error) {
+	if c.FieldNames.Time, err = dp.GetString(cfgKeyFieldNamesTime); err != nil {
+		return err
+	}
+	if c.FieldNames.Level, err = dp.GetString(cfgKeyFieldNamesLevel); err != nil {
+		return err
+	}
+	if c.FieldNames.Msg, err = dp.GetString(cfgKeyFieldNamesMsg); err != nil {
+		return err
+	}
+	if c.FieldNames.Caller, err = dp.GetString(cfgKeyFieldNamesCaller); err != nil {
+		return err
+	}
+	if c.FieldNames.Error, err = dp.GetString(cfgKeyFieldNamesError); err != nil {
+		return err
+	}
+	return nil
+}
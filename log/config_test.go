@@ -96,6 +96,27 @@ log:
 				return cfg
 			},
 		},
+		{
+			name:        "yaml config with logfmt format and custom field names",
+			cfgDataType: config.DataTypeYAML,
+			cfgData: `
+log:
+  format: logfmt
+  fieldNames:
+    time: ts
+    level: severity
+    msg: message
+    caller: source
+    error: err
+`,
+			expectedCfg: func() *Config {
+				cfg := NewDefaultConfig()
+				cfg.Format = FormatLogfmt
+				cfg.FieldNames = FieldNamesConfig{
+					Time: "ts", Level: "severity", Msg: "message", Caller: "source", Error: "err"}
+				return cfg
+			},
+		},
 		{
 			name:        "json config",
 			cfgDataType: config.DataTypeJSON,
@@ -292,7 +313,7 @@ log:
 log:
   format: invalid-format
 `,
-			expectedErrMsg: `log.format: unknown value "invalid-format", should be one of [json text]`,
+			expectedErrMsg: `log.format: unknown value "invalid-format", should be one of [json text logfmt ecs gelf]`,
 		},
 		{
 			name: "error, unknown log output",
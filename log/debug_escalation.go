@@ -0,0 +1,112 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package log
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DebugTokenHeader is a default name of the HTTP header (and gRPC metadata key)
+// that is used for passing a token which escalates the level of the request-scoped logger to debug.
+const DebugTokenHeader = "X-Debug-Log-Token"
+
+// DebugLevelEscalator is an interface for loggers that support escalating their level to debug.
+// It allows enabling debug logs for a single request without enabling them globally.
+type DebugLevelEscalator interface {
+	// EscalateToDebug returns a new logger that logs messages at all levels including debug.
+	// The second returned value is false if escalation is not supported, the logger itself is returned in this case.
+	EscalateToDebug() (FieldLogger, bool)
+}
+
+// EscalateToDebug returns a new logger with the level escalated to debug.
+// If the logger doesn't support escalation, it's returned as is, and the second returned value is false.
+func EscalateToDebug(logger FieldLogger) (FieldLogger, bool) {
+	if escalator, ok := logger.(DebugLevelEscalator); ok {
+		return escalator.EscalateToDebug()
+	}
+	return logger, false
+}
+
+// DebugTokenValidator validates tokens that are used for escalating the level of the request-scoped logger to debug.
+type DebugTokenValidator interface {
+	Validate(token string) bool
+}
+
+// DebugTokenValidatorFunc is an adapter to allow the use of ordinary functions as DebugTokenValidator.
+type DebugTokenValidatorFunc func(token string) bool
+
+// Validate calls f(token).
+func (f DebugTokenValidatorFunc) Validate(token string) bool {
+	return f(token)
+}
+
+// NewSharedDebugTokenValidator creates a DebugTokenValidator that accepts only the given shared token.
+// Comparison is done in constant time.
+func NewSharedDebugTokenValidator(sharedToken string) DebugTokenValidator {
+	return DebugTokenValidatorFunc(func(token string) bool {
+		return sharedToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(sharedToken)) == 1
+	})
+}
+
+// NewHMACDebugTokenValidator creates a DebugTokenValidator that accepts tokens created by NewHMACDebugToken
+// with the same secret. Tokens that were issued more than maxAge ago (or in the future) are rejected.
+func NewHMACDebugTokenValidator(secret []byte, maxAge time.Duration) DebugTokenValidator {
+	return DebugTokenValidatorFunc(func(token string) bool {
+		tsStr, sig, ok := strings.Cut(token, ".")
+		if !ok {
+			return false
+		}
+		ts, err := strconv.ParseInt(tsStr, 10, 64)
+		if err != nil {
+			return false
+		}
+		if age := time.Since(time.Unix(ts, 0)); age < -maxAge || age > maxAge {
+			return false
+		}
+		gotSig, err := hex.DecodeString(sig)
+		if err != nil {
+			return false
+		}
+		return hmac.Equal(gotSig, makeHMACDebugTokenSignature(secret, tsStr))
+	})
+}
+
+// NewHMACDebugToken creates a new token for escalating the level of the request-scoped logger to debug.
+// The token has the "<unix-timestamp>.<hex-encoded HMAC-SHA256 of the timestamp>" format.
+func NewHMACDebugToken(secret []byte, issuedAt time.Time) string {
+	tsStr := strconv.FormatInt(issuedAt.Unix(), 10)
+	return tsStr + "." + hex.EncodeToString(makeHMACDebugTokenSignature(secret, tsStr))
+}
+
+func makeHMACDebugTokenSignature(secret []byte, tsStr string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(tsStr))
+	return mac.Sum(nil)
+}
+
+type ctxKey int
+
+const ctxKeyDebugToken ctxKey = iota
+
+// NewContextWithDebugToken creates a new context with the validated debug token.
+// The token is propagated to downstream services (e.g., by httpclient.DebugTokenRoundTripper).
+func NewContextWithDebugToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, ctxKeyDebugToken, token)
+}
+
+// GetDebugTokenFromContext extracts the debug token from the context.
+func GetDebugTokenFromContext(ctx context.Context) string {
+	value, _ := ctx.Value(ctxKeyDebugToken).(string)
+	return value
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package log
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscalateToDebug(t *testing.T) {
+	var mu sync.Mutex
+	var texts []string
+	logger, closeFunc := newTestFileLogger(t, LevelInfo, WithDebugEscalation(), WithHooks(HookFunc(func(entry Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+		texts = append(texts, entry.Text)
+	})))
+
+	logger.Debug("not escalated")
+
+	escalated, ok := EscalateToDebug(logger.With(String("k", "v")))
+	require.True(t, ok)
+	escalated.Debug("escalated")
+
+	prefixed, ok := EscalateToDebug(NewPrefixedLogger(logger, "[prefix] "))
+	require.True(t, ok)
+	prefixed.Debug("escalated")
+
+	masked, ok := EscalateToDebug(NewMaskingLogger(logger, NewMasker(nil)))
+	require.True(t, ok)
+	masked.Debug("escalated masked")
+
+	// WithLevel applied after escalation still narrows the level.
+	escalated.WithLevel(LevelWarn).Info("filtered by level")
+
+	closeFunc()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"escalated", "[prefix] escalated", "escalated masked"}, texts)
+}
+
+func TestEscalateToDebug_NotSupported(t *testing.T) {
+	logger, closeFunc := newTestFileLogger(t, LevelInfo)
+	defer closeFunc()
+
+	got, ok := EscalateToDebug(logger)
+	require.False(t, ok)
+	require.Equal(t, logger, got)
+}
+
+func TestSharedDebugTokenValidator(t *testing.T) {
+	validator := NewSharedDebugTokenValidator("secret")
+	require.True(t, validator.Validate("secret"))
+	require.False(t, validator.Validate("another"))
+	require.False(t, validator.Validate(""))
+
+	require.False(t, NewSharedDebugTokenValidator("").Validate(""))
+}
+
+func TestHMACDebugTokenValidator(t *testing.T) {
+	secret := []byte("hmac-secret")
+	validator := NewHMACDebugTokenValidator(secret, time.Minute)
+
+	require.True(t, validator.Validate(NewHMACDebugToken(secret, time.Now())))
+	require.False(t, validator.Validate(NewHMACDebugToken(secret, time.Now().Add(-time.Hour))))
+	require.False(t, validator.Validate(NewHMACDebugToken(secret, time.Now().Add(time.Hour))))
+	require.False(t, validator.Validate(NewHMACDebugToken([]byte("another-secret"), time.Now())))
+	require.False(t, validator.Validate("invalid"))
+	require.False(t, validator.Validate("123.not-hex"))
+}
+
+func TestDebugTokenContext(t *testing.T) {
+	require.Empty(t, GetDebugTokenFromContext(context.Background()))
+	ctx := NewContextWithDebugToken(context.Background(), "token")
+	require.Equal(t, "token", GetDebugTokenFromContext(ctx))
+}
@@ -0,0 +1,73 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package log
+
+import (
+	"github.com/ssgreg/logf"
+)
+
+// ECSVersion is a version of the Elastic Common Schema that is used by the "ecs" log format.
+const ECSVersion = "1.6.0"
+
+// Default names of the standard log entry fields in the Elastic Common Schema.
+const (
+	ECSFieldNameTime   = "@timestamp"
+	ECSFieldNameLevel  = "log.level"
+	ECSFieldNameMsg    = "message"
+	ECSFieldNameCaller = "log.origin.file.name"
+	ECSFieldNameError  = "error.message"
+
+	ecsFieldNameCallerLine = "log.origin.file.line"
+	ecsFieldNameLogger     = "log.logger"
+	ecsFieldNameVersion    = "ecs.version"
+)
+
+// ecsEncoder encodes log entries in JSON format with field names following the Elastic Common Schema
+// (https://www.elastic.co/guide/en/ecs-logging/overview/current/intro.html).
+type ecsEncoder struct {
+	json         logf.Encoder
+	callerKey    string
+	headerFields []logf.Field
+}
+
+func newECSEncoder(cfg encoderConfig) logf.Encoder {
+	names := cfg.FieldNames.withDefaults(FieldNamesConfig{
+		Time:   ECSFieldNameTime,
+		Level:  ECSFieldNameLevel,
+		Msg:    ECSFieldNameMsg,
+		Caller: ECSFieldNameCaller,
+		Error:  ECSFieldNameError,
+	})
+	return &ecsEncoder{
+		json: logf.NewJSONEncoder(logf.JSONEncoderConfig{
+			FieldKeyTime:       names.Time,
+			FieldKeyLevel:      names.Level,
+			FieldKeyMsg:        names.Msg,
+			FieldKeyName:       ecsFieldNameLogger,
+			DisableFieldCaller: true, // Caller is split into file name and line fields.
+			EncodeTime:         cfg.EncodeTime,
+			EncodeError:        renameErrorEncoder(cfg.EncodeError, errorFieldKey, names.Error),
+		}),
+		callerKey:    names.Caller,
+		headerFields: []logf.Field{logf.String(ecsFieldNameVersion, ECSVersion)},
+	}
+}
+
+// Encode implements logf.Encoder interface.
+func (e *ecsEncoder) Encode(buf *logf.Buffer, entry logf.Entry) error {
+	derivedFields := make([]logf.Field, 0, len(e.headerFields)+len(entry.DerivedFields))
+	derivedFields = append(derivedFields, e.headerFields...)
+	entry.DerivedFields = append(derivedFields, entry.DerivedFields...)
+	if entry.Caller.Specified {
+		fields := make([]logf.Field, 0, len(entry.Fields)+2)
+		fields = append(fields,
+			logf.String(e.callerKey, entry.Caller.FileWithPackage()),
+			logf.Int(ecsFieldNameCallerLine, entry.Caller.Line))
+		entry.Fields = append(fields, entry.Fields...)
+	}
+	return e.json.Encode(buf, entry)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package log
+
+import (
+	"os"
+
+	"github.com/ssgreg/logf"
+)
+
+// Default names of the standard log entry fields.
+const (
+	DefaultFieldNameTime   = "time"
+	DefaultFieldNameLevel  = "level"
+	DefaultFieldNameMsg    = "msg"
+	DefaultFieldNameCaller = "caller"
+	DefaultFieldNameError  = "error"
+)
+
+const defaultFieldNameLogger = "logger"
+
+// errorFieldKey is a key that is used by the Error function for the error field.
+const errorFieldKey = "error"
+
+type encoderConfig struct {
+	Format      Format
+	FieldNames  FieldNamesConfig
+	EncodeTime  logf.TimeEncoder
+	EncodeError logf.ErrorEncoder
+	Hostname    string // Used only by the GELF encoder.
+}
+
+// newEncoder creates a new logf.Encoder for the given format.
+// Text format is not supported here since it's handled by logftext.NewAppender.
+func newEncoder(cfg encoderConfig) logf.Encoder {
+	if cfg.EncodeError == nil {
+		cfg.EncodeError = logf.DefaultErrorEncoder
+	}
+	switch cfg.Format {
+	case FormatLogfmt:
+		return newLogfmtEncoder(cfg)
+	case FormatECS:
+		return newECSEncoder(cfg)
+	case FormatGELF:
+		if cfg.Hostname == "" {
+			cfg.Hostname, _ = os.Hostname()
+		}
+		return newGELFEncoder(cfg)
+	}
+	return newJSONEncoder(cfg)
+}
+
+func newJSONEncoder(cfg encoderConfig) logf.Encoder {
+	names := cfg.FieldNames.withDefaults(FieldNamesConfig{
+		Time:   DefaultFieldNameTime,
+		Level:  DefaultFieldNameLevel,
+		Msg:    DefaultFieldNameMsg,
+		Caller: DefaultFieldNameCaller,
+		Error:  DefaultFieldNameError,
+	})
+	return logf.NewJSONEncoder(logf.JSONEncoderConfig{
+		FieldKeyTime:   names.Time,
+		FieldKeyLevel:  names.Level,
+		FieldKeyMsg:    names.Msg,
+		FieldKeyCaller: names.Caller,
+		EncodeTime:     cfg.EncodeTime,
+		EncodeError:    renameErrorEncoder(cfg.EncodeError, errorFieldKey, names.Error),
+	})
+}
+
+// withDefaults returns a copy of the FieldNamesConfig where all empty names are replaced with the given defaults.
+func (c FieldNamesConfig) withDefaults(defaults FieldNamesConfig) FieldNamesConfig {
+	if c.Time == "" {
+		c.Time = defaults.Time
+	}
+	if c.Level == "" {
+		c.Level = defaults.Level
+	}
+	if c.Msg == "" {
+		c.Msg = defaults.Msg
+	}
+	if c.Caller == "" {
+		c.Caller = defaults.Caller
+	}
+	if c.Error == "" {
+		c.Error = defaults.Error
+	}
+	return c
+}
+
+// renameErrorEncoder returns logf.ErrorEncoder that encodes errors logged with the "from" key under the "to" key.
+func renameErrorEncoder(encodeError logf.ErrorEncoder, from, to string) logf.ErrorEncoder {
+	if from == to {
+		return encodeError
+	}
+	return func(key string, err error, enc logf.FieldEncoder) {
+		if key == from {
+			key = to
+		}
+		encodeError(key, err, enc)
+	}
+}
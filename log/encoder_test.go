@@ -0,0 +1,130 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package log
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ssgreg/logf"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+type testUser struct {
+	Name string
+	Age  int
+}
+
+func (u testUser) EncodeLogfObject(enc logf.FieldEncoder) error {
+	enc.EncodeFieldString("name", u.Name)
+	enc.EncodeFieldInt64("age", int64(u.Age))
+	return nil
+}
+
+func makeTestEntries() []logf.Entry {
+	entryTime := time.Date(2024, 3, 15, 10, 20, 30, 123456789, time.UTC)
+	caller := logf.EntryCaller{File: "/src/github.com/acronis/app/handler.go", Line: 42, Specified: true}
+	derivedFields := []logf.Field{logf.Int("pid", 1234), logf.String("component", "api")}
+	return []logf.Entry{
+		{
+			LoggerID:      1,
+			DerivedFields: derivedFields,
+			Level:         logf.LevelInfo,
+			Time:          entryTime,
+			Text:          "request handled",
+			Fields: []logf.Field{
+				logf.String("method", "GET"),
+				logf.String("uri", "/api/v1/users?name=John Doe"),
+				logf.Int("status", 200),
+				logf.Duration("duration", 1500*time.Millisecond),
+				logf.Bool("cached", false),
+			},
+		},
+		{
+			LoggerID:      1,
+			DerivedFields: derivedFields,
+			Level:         logf.LevelError,
+			Time:          entryTime.Add(time.Second),
+			Text:          "failed to process request",
+			Caller:        caller,
+			Fields: []logf.Field{
+				logf.Error(errors.New(`unexpected "EOF"`)),
+				logf.Strings("tags", []string{"a", "b c"}),
+				logf.Object("user", testUser{Name: "John", Age: 42}),
+				logf.Float64("ratio", 0.25),
+			},
+		},
+		{
+			LoggerID:   2,
+			LoggerName: "worker",
+			Level:      logf.LevelDebug,
+			Time:       entryTime.Add(2 * time.Second),
+			Text:       "",
+			Fields:     []logf.Field{logf.String("empty", ""), logf.Int64("id", -7)},
+		},
+		{
+			LoggerID: 3,
+			Level:    logf.LevelWarn,
+			Time:     entryTime.Add(3 * time.Second),
+			Text:     "multi-line\nwarning",
+		},
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     Format
+		fieldNames FieldNamesConfig
+	}{
+		{name: "json", format: FormatJSON},
+		{name: "json_field_names", format: FormatJSON, fieldNames: FieldNamesConfig{
+			Time: "ts", Level: "severity", Msg: "message", Caller: "source", Error: "err"}},
+		{name: "logfmt", format: FormatLogfmt},
+		{name: "logfmt_field_names", format: FormatLogfmt, fieldNames: FieldNamesConfig{
+			Time: "ts", Level: "severity", Msg: "message", Caller: "source", Error: "err"}},
+		{name: "ecs", format: FormatECS},
+		{name: "ecs_field_names", format: FormatECS, fieldNames: FieldNamesConfig{
+			Caller: "log.origin.function", Error: "error.type"}},
+		{name: "gelf", format: FormatGELF},
+		{name: "gelf_field_names", format: FormatGELF, fieldNames: FieldNamesConfig{
+			Caller: "source", Error: "err"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc := newEncoder(encoderConfig{
+				Format:      tt.format,
+				FieldNames:  tt.fieldNames,
+				EncodeTime:  logf.RFC3339NanoTimeEncoder,
+				EncodeError: logf.NewErrorEncoder(logf.ErrorEncoderConfig{VerboseFieldSuffix: defaultErrorVerboseSuffix}),
+				Hostname:    "test-host",
+			})
+			buf := logf.NewBuffer()
+			for _, entry := range makeTestEntries() {
+				require.NoError(t, enc.Encode(buf, entry))
+			}
+			// Encode the same entries once again to check that cached derived fields are used correctly.
+			for _, entry := range makeTestEntries()[:1] {
+				require.NoError(t, enc.Encode(buf, entry))
+			}
+
+			goldenFile := filepath.Join("testdata", "encoder_"+tt.name+".golden")
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(goldenFile, buf.Bytes(), 0o644)) //nolint:gosec // test data
+			}
+			expected, err := os.ReadFile(goldenFile) //nolint:gosec // test data
+			require.NoError(t, err)
+			require.Equal(t, string(expected), buf.String())
+		})
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package log
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/ssgreg/logf"
+)
+
+// GELFVersion is a version of the Graylog Extended Log Format that is used by the "gelf" log format.
+const GELFVersion = "1.1"
+
+const (
+	gelfFieldNameVersion      = "version"
+	gelfFieldNameHost         = "host"
+	gelfFieldNameShortMessage = "short_message"
+	gelfFieldNameTimestamp    = "timestamp"
+	gelfFieldNameLevel        = "level"
+
+	// gelfAdditionalFieldPrefix is a prefix that must be added to all non-standard GELF fields.
+	gelfAdditionalFieldPrefix = "_"
+
+	// gelfReservedFieldName is an additional field name that is forbidden by the GELF specification.
+	gelfReservedFieldName = "_id"
+)
+
+// gelfEncoder encodes log entries in the Graylog Extended Log Format (https://go2docs.graylog.org/current/getting_in_log_data/gelf.html).
+// The names of the "version", "host", "short_message", "timestamp" and "level" fields are fixed by the GELF specification,
+// so only caller and error field names can be configured. All non-standard fields are prefixed with "_".
+// Since GELF forbids the "_id" additional field, a field named "id" is encoded as "__id".
+type gelfEncoder struct {
+	json         logf.Encoder
+	headerFields []logf.Field
+}
+
+func newGELFEncoder(cfg encoderConfig) logf.Encoder {
+	names := cfg.FieldNames.withDefaults(FieldNamesConfig{
+		Caller: DefaultFieldNameCaller,
+		Error:  DefaultFieldNameError,
+	})
+	return &gelfEncoder{
+		json: logf.NewJSONEncoder(logf.JSONEncoderConfig{
+			FieldKeyTime:   gelfFieldNameTimestamp,
+			FieldKeyLevel:  gelfFieldNameLevel,
+			FieldKeyMsg:    gelfFieldNameShortMessage,
+			FieldKeyName:   gelfAdditionalFieldKey(defaultFieldNameLogger),
+			FieldKeyCaller: gelfAdditionalFieldKey(names.Caller),
+			EncodeTime:     gelfTimeEncoder,
+			EncodeLevel:    gelfLevelEncoder,
+			EncodeError: renameErrorEncoder(cfg.EncodeError,
+				gelfAdditionalFieldKey(errorFieldKey), gelfAdditionalFieldKey(names.Error)),
+		}),
+		headerFields: []logf.Field{
+			logf.String(gelfFieldNameVersion, GELFVersion),
+			logf.String(gelfFieldNameHost, cfg.Hostname),
+		},
+	}
+}
+
+// Encode implements logf.Encoder interface.
+func (e *gelfEncoder) Encode(buf *logf.Buffer, entry logf.Entry) error {
+	derivedFields := make([]logf.Field, 0, len(e.headerFields)+len(entry.DerivedFields))
+	derivedFields = append(derivedFields, e.headerFields...)
+	entry.DerivedFields = appendGELFAdditionalFields(derivedFields, entry.DerivedFields)
+	if len(entry.Fields) != 0 {
+		entry.Fields = appendGELFAdditionalFields(make([]logf.Field, 0, len(entry.Fields)), entry.Fields)
+	}
+	return e.json.Encode(buf, entry)
+}
+
+func appendGELFAdditionalFields(dst []logf.Field, fields []logf.Field) []logf.Field {
+	for _, field := range fields {
+		field.Key = gelfAdditionalFieldKey(field.Key)
+		dst = append(dst, field)
+	}
+	return dst
+}
+
+// gelfAdditionalFieldKey returns the name of the GELF additional field for the passed key.
+func gelfAdditionalFieldKey(key string) string {
+	key = gelfAdditionalFieldPrefix + key
+	if key == gelfReservedFieldName {
+		return gelfAdditionalFieldPrefix + key
+	}
+	return key
+}
+
+// gelfTimeEncoder encodes time as seconds since UNIX epoch with optional decimal places for milliseconds.
+func gelfTimeEncoder(t time.Time, enc logf.TypeEncoder) {
+	sec := float64(t.UnixMilli()) / 1000
+	// json.Number is used to write the number as is, without switching to the exponent notation for large values.
+	enc.EncodeTypeAny(json.Number(strconv.FormatFloat(sec, 'f', -1, 64)))
+}
+
+// gelfLevelEncoder encodes level as the standard syslog level.
+func gelfLevelEncoder(lvl logf.Level, enc logf.TypeEncoder) {
+	const (
+		syslogLevelError   = 3
+		syslogLevelWarning = 4
+		syslogLevelInfo    = 6
+		syslogLevelDebug   = 7
+	)
+	switch lvl {
+	case logf.LevelError:
+		enc.EncodeTypeInt8(syslogLevelError)
+	case logf.LevelWarn:
+		enc.EncodeTypeInt8(syslogLevelWarning)
+	case logf.LevelInfo:
+		enc.EncodeTypeInt8(syslogLevelInfo)
+	default:
+		enc.EncodeTypeInt8(syslogLevelDebug)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package log
+
+import (
+	"time"
+
+	"github.com/ssgreg/logf"
+)
+
+// Entry represents a single log entry that is passed to hooks.
+type Entry struct {
+	LoggerName string
+	Level      Level
+	Time       time.Time
+	Text       string
+
+	// Fields contains fields of the log entry followed by fields of the logger (added via With method).
+	Fields []Field
+}
+
+// FindField tries to find field in logging entry by key.
+func (e *Entry) FindField(key string) (*Field, bool) {
+	for i := range e.Fields {
+		if e.Fields[i].Key == key {
+			return &e.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// Hook is an interface for receiving log entries.
+// OnEntry is called for every log entry that passed level filtering, before it is written to the output.
+// Hooks are called sequentially from a single goroutine, so they should not block for a long time.
+type Hook interface {
+	OnEntry(entry Entry)
+}
+
+// HookFunc is an adapter to allow the use of ordinary functions as Hook.
+type HookFunc func(entry Entry)
+
+// OnEntry calls f(entry).
+func (f HookFunc) OnEntry(entry Entry) {
+	f(entry)
+}
+
+// ErrorHook is a Hook that forwards error-level log entries to the callback.
+// It may be used for sending errors to an alerting sink.
+type ErrorHook struct {
+	callback func(entry Entry)
+}
+
+// NewErrorHook creates a new ErrorHook that calls the given callback for each error-level log entry.
+func NewErrorHook(callback func(entry Entry)) *ErrorHook {
+	return &ErrorHook{callback: callback}
+}
+
+// OnEntry calls the callback if the entry has error level.
+func (h *ErrorHook) OnEntry(entry Entry) {
+	if entry.Level == LevelError {
+		h.callback(entry)
+	}
+}
+
+// hookAppender is a logf.Appender that calls hooks before delegating entries to the next appender.
+type hookAppender struct {
+	logf.Appender
+	hooks []Hook
+}
+
+func newHookAppender(appender logf.Appender, hooks []Hook) logf.Appender {
+	if len(hooks) == 0 {
+		return appender
+	}
+	return &hookAppender{Appender: appender, hooks: hooks}
+}
+
+//nolint:gocritic // logf.Appender interface requires passing entry by value
+func (a *hookAppender) Append(e logf.Entry) error {
+	fields := make([]Field, 0, len(e.Fields)+len(e.DerivedFields))
+	fields = append(fields, e.Fields...)
+	fields = append(fields, e.DerivedFields...)
+	entry := Entry{
+		LoggerName: e.LoggerName,
+		Level:      convertLogfLevelToLevel(e.Level),
+		Time:       e.Time,
+		Text:       e.Text,
+		Fields:     fields,
+	}
+	for _, hook := range a.hooks {
+		hook.OnEntry(entry)
+	}
+	return a.Appender.Append(e)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package log
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFileLogger(t *testing.T, level Level, options ...LoggerOption) (FieldLogger, CloseFunc) {
+	t.Helper()
+	cfg := NewDefaultConfig()
+	cfg.Level = level
+	cfg.Output = OutputFile
+	cfg.File.Path = filepath.Join(t.TempDir(), "test.log")
+	return NewLogger(cfg, options...)
+}
+
+func TestHooks(t *testing.T) {
+	var mu sync.Mutex
+	var entries []Entry
+	logger, closeFunc := newTestFileLogger(t, LevelInfo, WithHooks(HookFunc(func(entry Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, entry)
+	})))
+
+	logger = logger.With(String("component", "test"))
+	logger.Debug("debug message")
+	logger.Info("info message", Int("n", 42))
+	logger.Errorf("error message: %d", 500)
+	closeFunc()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, entries, 2)
+
+	require.Equal(t, LevelInfo, entries[0].Level)
+	require.Equal(t, "info message", entries[0].Text)
+	nField, found := entries[0].FindField("n")
+	require.True(t, found)
+	require.Equal(t, int64(42), nField.Int)
+	componentField, found := entries[0].FindField("component")
+	require.True(t, found)
+	require.Equal(t, "test", string(componentField.Bytes))
+
+	require.Equal(t, LevelError, entries[1].Level)
+	require.Equal(t, "error message: 500", entries[1].Text)
+}
+
+func TestErrorHook(t *testing.T) {
+	var mu sync.Mutex
+	var errEntries []Entry
+	errHook := NewErrorHook(func(entry Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+		errEntries = append(errEntries, entry)
+	})
+	logger, closeFunc := newTestFileLogger(t, LevelDebug, WithHooks(errHook))
+
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Warn("warn message")
+	logger.Error("error message", Error(errors.New("internal error")))
+	closeFunc()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, errEntries, 1)
+	require.Equal(t, "error message", errEntries[0].Text)
+	errField, found := errEntries[0].FindField("error")
+	require.True(t, found)
+	require.EqualError(t, errField.Any.(error), "internal error")
+}
+
+func TestPrometheusHook(t *testing.T) {
+	promHook := NewPrometheusHookWithOpts(PrometheusHookOpts{MessagePrefixes: []string{"[worker] "}})
+	logger, closeFunc := newTestFileLogger(t, LevelInfo, WithHooks(promHook))
+
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Error("error message")
+	logger.Error("error message")
+	workerLogger := NewPrefixedLogger(logger, "[worker] ")
+	workerLogger.Error("error message")
+	workerLogger.Warn("warn message")
+	namedLogger := &LogfAdapter{Logger: logger.(*LogfAdapter).Logger.WithName("cache")}
+	namedLogger.Error("error message")
+	closeFunc()
+
+	require.Equal(t, 5, testutil.CollectAndCount(promHook.EntriesTotal))
+	require.Equal(t, 0.0, testutil.ToFloat64(promHook.EntriesTotal.WithLabelValues("debug", "")))
+	require.Equal(t, 1.0, testutil.ToFloat64(promHook.EntriesTotal.WithLabelValues("info", "")))
+	require.Equal(t, 2.0, testutil.ToFloat64(promHook.EntriesTotal.WithLabelValues("error", "")))
+	require.Equal(t, 1.0, testutil.ToFloat64(promHook.EntriesTotal.WithLabelValues("error", "[worker]")))
+	require.Equal(t, 1.0, testutil.ToFloat64(promHook.EntriesTotal.WithLabelValues("warn", "[worker]")))
+	require.Equal(t, 1.0, testutil.ToFloat64(promHook.EntriesTotal.WithLabelValues("error", "cache")))
+}
@@ -0,0 +1,433 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package log
+
+import (
+	"strconv"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/ssgreg/logf"
+)
+
+// logfmtEncoder encodes log entries in the logfmt format (https://brandur.org/logfmt).
+// Scalar values are written as is (quoted if necessary), while arrays, objects and arbitrary values
+// are encoded as JSON and then written as a quoted string.
+type logfmtEncoder struct {
+	names       FieldNamesConfig
+	encodeTime  logf.TimeEncoder
+	encodeError logf.ErrorEncoder
+	jsonFactory logf.TypeEncoderFactory
+	cache       *logf.Cache
+
+	buf    *logf.Buffer
+	tmpBuf *logf.Buffer
+}
+
+var (
+	_ logf.FieldEncoder = (*logfmtEncoder)(nil)
+	_ logf.TypeEncoder  = (*logfmtEncoder)(nil)
+)
+
+func newLogfmtEncoder(cfg encoderConfig) logf.Encoder {
+	names := cfg.FieldNames.withDefaults(FieldNamesConfig{
+		Time:   DefaultFieldNameTime,
+		Level:  DefaultFieldNameLevel,
+		Msg:    DefaultFieldNameMsg,
+		Caller: DefaultFieldNameCaller,
+		Error:  DefaultFieldNameError,
+	})
+	encodeTime := cfg.EncodeTime
+	if encodeTime == nil {
+		encodeTime = logf.RFC3339NanoTimeEncoder
+	}
+	encodeError := renameErrorEncoder(cfg.EncodeError, errorFieldKey, names.Error)
+	return &logfmtEncoder{
+		names:       names,
+		encodeTime:  encodeTime,
+		encodeError: encodeError,
+		jsonFactory: logf.NewJSONTypeEncoderFactory(logf.JSONEncoderConfig{
+			EncodeTime:  encodeTime,
+			EncodeError: encodeError,
+		}),
+		cache:  logf.NewCache(100),
+		tmpBuf: logf.NewBufferWithCapacity(256),
+	}
+}
+
+// Encode implements logf.Encoder interface.
+func (e *logfmtEncoder) Encode(buf *logf.Buffer, entry logf.Entry) error {
+	e.buf = buf
+
+	e.EncodeFieldTime(e.names.Time, entry.Time)
+	e.EncodeFieldString(e.names.Level, entry.Level.String())
+	if entry.LoggerName != "" {
+		e.EncodeFieldString(defaultFieldNameLogger, entry.LoggerName)
+	}
+	e.EncodeFieldString(e.names.Msg, entry.Text)
+	if entry.Caller.Specified {
+		e.addKey(e.names.Caller)
+		logf.ShortCallerEncoder(entry.Caller, e)
+	}
+
+	if bytes, ok := e.cache.Get(entry.LoggerID); ok {
+		buf.AppendBytes(bytes)
+	} else {
+		startLen := buf.Len()
+		for _, field := range entry.DerivedFields {
+			field.Accept(e)
+		}
+		bytes = make([]byte, buf.Len()-startLen)
+		copy(bytes, buf.Data[startLen:])
+		e.cache.Set(entry.LoggerID, bytes)
+	}
+
+	for _, field := range entry.Fields {
+		field.Accept(e)
+	}
+
+	buf.AppendByte('\n')
+	return nil
+}
+
+func (e *logfmtEncoder) addKey(k string) {
+	if e.buf.Len() != 0 && e.buf.Back() != '\n' {
+		e.buf.AppendByte(' ')
+	}
+	e.appendString(k)
+	e.buf.AppendByte('=')
+}
+
+// appendString appends the given string to the buffer, quoting it if it's required by the logfmt format.
+func (e *logfmtEncoder) appendString(s string) {
+	if logfmtNeedsQuoting(s) {
+		e.buf.Data = strconv.AppendQuote(e.buf.Data, s)
+		return
+	}
+	e.buf.AppendString(s)
+}
+
+func logfmtNeedsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || !strconv.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// appendJSON encodes a value using the JSON type encoder and appends the result as a string.
+func (e *logfmtEncoder) appendJSON(encode func(enc logf.TypeEncoder)) {
+	e.tmpBuf.Reset()
+	encode(e.jsonFactory.TypeEncoder(e.tmpBuf))
+	e.appendString(string(e.tmpBuf.Bytes()))
+}
+
+// FieldEncoder interface implementation.
+
+func (e *logfmtEncoder) EncodeFieldAny(k string, v interface{}) {
+	e.addKey(k)
+	e.EncodeTypeAny(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldBool(k string, v bool) {
+	e.addKey(k)
+	e.EncodeTypeBool(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldInt64(k string, v int64) {
+	e.addKey(k)
+	e.EncodeTypeInt64(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldInt32(k string, v int32) {
+	e.addKey(k)
+	e.EncodeTypeInt32(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldInt16(k string, v int16) {
+	e.addKey(k)
+	e.EncodeTypeInt16(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldInt8(k string, v int8) {
+	e.addKey(k)
+	e.EncodeTypeInt8(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldUint64(k string, v uint64) {
+	e.addKey(k)
+	e.EncodeTypeUint64(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldUint32(k string, v uint32) {
+	e.addKey(k)
+	e.EncodeTypeUint32(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldUint16(k string, v uint16) {
+	e.addKey(k)
+	e.EncodeTypeUint16(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldUint8(k string, v uint8) {
+	e.addKey(k)
+	e.EncodeTypeUint8(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldFloat64(k string, v float64) {
+	e.addKey(k)
+	e.EncodeTypeFloat64(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldFloat32(k string, v float32) {
+	e.addKey(k)
+	e.EncodeTypeFloat32(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldDuration(k string, v time.Duration) {
+	e.addKey(k)
+	e.EncodeTypeDuration(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldError(k string, v error) {
+	e.encodeError(k, v, e)
+}
+
+func (e *logfmtEncoder) EncodeFieldTime(k string, v time.Time) {
+	e.addKey(k)
+	e.EncodeTypeTime(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldString(k string, v string) {
+	e.addKey(k)
+	e.EncodeTypeString(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldStrings(k string, v []string) {
+	e.addKey(k)
+	e.EncodeTypeStrings(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldBytes(k string, v []byte) {
+	e.addKey(k)
+	e.EncodeTypeBytes(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldBools(k string, v []bool) {
+	e.addKey(k)
+	e.EncodeTypeBools(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldInts64(k string, v []int64) {
+	e.addKey(k)
+	e.EncodeTypeInts64(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldInts32(k string, v []int32) {
+	e.addKey(k)
+	e.EncodeTypeInts32(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldInts16(k string, v []int16) {
+	e.addKey(k)
+	e.EncodeTypeInts16(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldInts8(k string, v []int8) {
+	e.addKey(k)
+	e.EncodeTypeInts8(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldUints64(k string, v []uint64) {
+	e.addKey(k)
+	e.EncodeTypeUints64(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldUints32(k string, v []uint32) {
+	e.addKey(k)
+	e.EncodeTypeUints32(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldUints16(k string, v []uint16) {
+	e.addKey(k)
+	e.EncodeTypeUints16(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldUints8(k string, v []uint8) {
+	e.addKey(k)
+	e.EncodeTypeUints8(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldFloats64(k string, v []float64) {
+	e.addKey(k)
+	e.EncodeTypeFloats64(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldFloats32(k string, v []float32) {
+	e.addKey(k)
+	e.EncodeTypeFloats32(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldDurations(k string, v []time.Duration) {
+	e.addKey(k)
+	e.EncodeTypeDurations(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldArray(k string, v logf.ArrayEncoder) {
+	e.addKey(k)
+	e.EncodeTypeArray(v)
+}
+
+func (e *logfmtEncoder) EncodeFieldObject(k string, v logf.ObjectEncoder) {
+	e.addKey(k)
+	e.EncodeTypeObject(v)
+}
+
+// TypeEncoder interface implementation.
+
+func (e *logfmtEncoder) EncodeTypeAny(v interface{}) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeAny(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeBool(v bool) {
+	logf.AppendBool(e.buf, v)
+}
+
+func (e *logfmtEncoder) EncodeTypeInt64(v int64) {
+	logf.AppendInt(e.buf, v)
+}
+
+func (e *logfmtEncoder) EncodeTypeInt32(v int32) {
+	logf.AppendInt(e.buf, int64(v))
+}
+
+func (e *logfmtEncoder) EncodeTypeInt16(v int16) {
+	logf.AppendInt(e.buf, int64(v))
+}
+
+func (e *logfmtEncoder) EncodeTypeInt8(v int8) {
+	logf.AppendInt(e.buf, int64(v))
+}
+
+func (e *logfmtEncoder) EncodeTypeUint64(v uint64) {
+	logf.AppendUint(e.buf, v)
+}
+
+func (e *logfmtEncoder) EncodeTypeUint32(v uint32) {
+	logf.AppendUint(e.buf, uint64(v))
+}
+
+func (e *logfmtEncoder) EncodeTypeUint16(v uint16) {
+	logf.AppendUint(e.buf, uint64(v))
+}
+
+func (e *logfmtEncoder) EncodeTypeUint8(v uint8) {
+	logf.AppendUint(e.buf, uint64(v))
+}
+
+func (e *logfmtEncoder) EncodeTypeFloat64(v float64) {
+	logf.AppendFloat64(e.buf, v)
+}
+
+func (e *logfmtEncoder) EncodeTypeFloat32(v float32) {
+	logf.AppendFloat32(e.buf, v)
+}
+
+func (e *logfmtEncoder) EncodeTypeDuration(v time.Duration) {
+	e.appendString(v.String())
+}
+
+func (e *logfmtEncoder) EncodeTypeTime(v time.Time) {
+	e.encodeTime(v, e)
+}
+
+func (e *logfmtEncoder) EncodeTypeString(v string) {
+	e.appendString(v)
+}
+
+func (e *logfmtEncoder) EncodeTypeStrings(v []string) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeStrings(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeBytes(v []byte) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeBytes(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeBools(v []bool) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeBools(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeInts64(v []int64) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeInts64(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeInts32(v []int32) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeInts32(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeInts16(v []int16) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeInts16(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeInts8(v []int8) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeInts8(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeUints64(v []uint64) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeUints64(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeUints32(v []uint32) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeUints32(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeUints16(v []uint16) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeUints16(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeUints8(v []uint8) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeUints8(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeFloats64(v []float64) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeFloats64(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeFloats32(v []float32) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeFloats32(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeDurations(v []time.Duration) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeDurations(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeArray(v logf.ArrayEncoder) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeArray(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeObject(v logf.ObjectEncoder) {
+	e.appendJSON(func(enc logf.TypeEncoder) { enc.EncodeTypeObject(v) })
+}
+
+func (e *logfmtEncoder) EncodeTypeUnsafeBytes(v unsafe.Pointer) {
+	e.appendString(string(*(*[]byte)(v)))
+}
@@ -120,28 +120,77 @@ type FieldLogger interface {
 // LogfAdapter adapts logf.Logger to FieldLogger interface.
 type LogfAdapter struct {
 	Logger *logf.Logger
+
+	// levelChecker is an additional level check that is used for loggers supporting debug escalation.
+	// In this case, Logger accepts all levels, and levelChecker may be replaced to escalate the level to debug.
+	levelChecker logf.LevelChecker
 }
 
+var _ DebugLevelEscalator = (*LogfAdapter)(nil)
+
 // NewDisabledLogger returns a new logger that logs nothing.
 func NewDisabledLogger() FieldLogger {
-	return &LogfAdapter{logf.NewDisabledLogger()}
+	return &LogfAdapter{Logger: logf.NewDisabledLogger()}
+}
+
+// NewLogfAdapterWithDebugEscalation returns a new LogfAdapter that logs messages at the given level and above,
+// and supports escalating the level to debug via EscalateToDebug method (e.g., for a single request).
+// The passed logf.Logger should accept all levels (i.e., it should be created with logf.LevelDebug).
+func NewLogfAdapterWithDebugEscalation(logger *logf.Logger, level Level) *LogfAdapter {
+	return &LogfAdapter{Logger: logger, levelChecker: convertLevelToLogfLevel(level).Checker()}
+}
+
+// LoggerOption is a type for functional options for the NewLogger.
+type LoggerOption func(*loggerOptions)
+
+type loggerOptions struct {
+	hooks           []Hook
+	debugEscalation bool
+}
+
+// WithHooks returns a LoggerOption that adds hooks which are called for every log entry that passed level filtering.
+func WithHooks(hooks ...Hook) LoggerOption {
+	return func(o *loggerOptions) {
+		o.hooks = append(o.hooks, hooks...)
+	}
+}
+
+// WithDebugEscalation returns a LoggerOption that enables escalating the logger level to debug
+// for a particular logger instance (e.g., request-scoped one) via EscalateToDebug function.
+func WithDebugEscalation() LoggerOption {
+	return func(o *loggerOptions) {
+		o.debugEscalation = true
+	}
 }
 
 // NewLogger returns a new logger.
-func NewLogger(cfg *Config) (FieldLogger, CloseFunc) {
-	appender := makeLogfAppender(cfg)
+func NewLogger(cfg *Config, options ...LoggerOption) (FieldLogger, CloseFunc) {
+	var opts loggerOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	appender := newHookAppender(makeLogfAppender(cfg), opts.hooks)
 	channel, closeFunc := logf.NewChannelWriter(logf.ChannelWriterConfig{
 		Appender:          appender,
 		EnableSyncOnError: true,
 	})
-	logfLogger := logf.NewLogger(convertLevelToLogfLevel(cfg.Level), channel)
+	logfLevel := convertLevelToLogfLevel(cfg.Level)
+	if opts.debugEscalation {
+		logfLevel = logf.LevelDebug // Level check is done by LogfAdapter in this case.
+	}
+	logfLogger := logf.NewLogger(logfLevel, channel)
 	logfLogger = logfLogger.With(logf.Int("pid", os.Getpid()))
 	if cfg.AddCaller {
 		// show caller, but skip one last stackframe
 		// to receive log line not in this file
 		logfLogger = logfLogger.WithCaller().WithCallerSkip(1)
 	}
-	var logger FieldLogger = &LogfAdapter{logfLogger}
+	var logger FieldLogger
+	if opts.debugEscalation {
+		logger = NewLogfAdapterWithDebugEscalation(logfLogger, cfg.Level)
+	} else {
+		logger = &LogfAdapter{Logger: logfLogger}
+	}
 
 	if cfg.Masking.Enabled {
 		rules := cfg.Masking.Rules
@@ -155,26 +204,38 @@ func NewLogger(cfg *Config) (FieldLogger, CloseFunc) {
 
 // With returns a new logger with the given additional fields.
 func (l *LogfAdapter) With(fs ...Field) FieldLogger {
-	return &LogfAdapter{l.Logger.With(fs...)}
+	return &LogfAdapter{Logger: l.Logger.With(fs...), levelChecker: l.levelChecker}
 }
 
 // Debug logs message at "debug" level.
 func (l *LogfAdapter) Debug(s string, fields ...Field) {
+	if l.levelChecker != nil && !l.levelChecker(logf.LevelDebug) {
+		return
+	}
 	l.Logger.Debug(s, fields...)
 }
 
 // Info logs message at "info" level.
 func (l *LogfAdapter) Info(s string, fields ...Field) {
+	if l.levelChecker != nil && !l.levelChecker(logf.LevelInfo) {
+		return
+	}
 	l.Logger.Info(s, fields...)
 }
 
 // Warn logs message at "warn" level.
 func (l *LogfAdapter) Warn(s string, fields ...Field) {
+	if l.levelChecker != nil && !l.levelChecker(logf.LevelWarn) {
+		return
+	}
 	l.Logger.Warn(s, fields...)
 }
 
 // Error logs message at "error" level.
 func (l *LogfAdapter) Error(s string, fields ...Field) {
+	if l.levelChecker != nil && !l.levelChecker(logf.LevelError) {
+		return
+	}
 	l.Logger.Error(s, fields...)
 }
 
@@ -208,14 +269,36 @@ func (l *LogfAdapter) logStringAtLevel(level Level, format string, args ...inter
 // AtLevel calls the given fn if logging a message at the specified level
 // is enabled, passing a LogFunc with the bound level.
 func (l *LogfAdapter) AtLevel(level Level, fn func(logFunc LogFunc)) {
-	l.Logger.AtLevel(convertLevelToLogfLevel(level), fn)
+	logfLevel := convertLevelToLogfLevel(level)
+	if l.levelChecker != nil && !l.levelChecker(logfLevel) {
+		return
+	}
+	l.Logger.AtLevel(logfLevel, fn)
 }
 
 // WithLevel returns a new logger with additional level check.
 // All log messages below ("debug" is a minimal level, "error" - maximal)
 // the given AND previously set level will be ignored (i.e. it makes sense to only increase level).
 func (l *LogfAdapter) WithLevel(level Level) FieldLogger {
-	return &LogfAdapter{Logger: l.Logger.WithLevel(convertLevelToLogfLevel(level))}
+	logfLevel := convertLevelToLogfLevel(level)
+	if l.levelChecker == nil {
+		return &LogfAdapter{Logger: l.Logger.WithLevel(logfLevel)}
+	}
+	parentLevelChecker := l.levelChecker
+	return &LogfAdapter{Logger: l.Logger, levelChecker: func(o logf.Level) bool {
+		return parentLevelChecker(o) && logfLevel.Enabled(o)
+	}}
+}
+
+// EscalateToDebug returns a new logger that logs messages at all levels including debug,
+// ignoring the level from the configuration and all levels set by WithLevel.
+// The second returned value is false if debug escalation is not supported by the logger
+// (see WithDebugEscalation and NewLogfAdapterWithDebugEscalation), the logger itself is returned in this case.
+func (l *LogfAdapter) EscalateToDebug() (FieldLogger, bool) {
+	if l.levelChecker == nil {
+		return l, false
+	}
+	return &LogfAdapter{Logger: l.Logger, levelChecker: logf.LevelDebug.Checker()}, true
 }
 
 func convertLevelToLogfLevel(value Level) logf.Level {
@@ -232,6 +315,20 @@ func convertLevelToLogfLevel(value Level) logf.Level {
 	return logf.LevelInfo
 }
 
+func convertLogfLevelToLevel(value logf.Level) Level {
+	switch value {
+	case logf.LevelError:
+		return LevelError
+	case logf.LevelWarn:
+		return LevelWarn
+	case logf.LevelInfo:
+		return LevelInfo
+	case logf.LevelDebug:
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
 func makeLogfAppender(cfg *Config) logf.Appender {
 	switch cfg.Output {
 	case OutputFile:
@@ -270,10 +367,11 @@ func makeLogfAppenderWithWriter(cfg *Config, w io.Writer) logf.Appender {
 		})
 	}
 
-	return logf.NewWriteAppender(w, logf.NewJSONEncoder(logf.JSONEncoderConfig{
-		EncodeTime:   timeEncoder,
-		EncodeError:  errorEncoder,
-		FieldKeyTime: "time",
+	return logf.NewWriteAppender(w, newEncoder(encoderConfig{
+		Format:      cfg.Format,
+		FieldNames:  cfg.FieldNames,
+		EncodeTime:  timeEncoder,
+		EncodeError: errorEncoder,
 	}))
 }
 
@@ -6,4 +6,8 @@ Released under MIT license.
 
 // Package logtest provides implementation of log.FieldLogger that allows writing tests for logging functionality.
 // It was inspired by httptest (https://golang.org/pkg/net/http/httptest) from Go standard library.
+//
+// Recorded entries may be checked with matchers (HasLevel, MessageMatches, FieldEquals, etc.)
+// via AssertEntry, AssertEntriesCount, AssertEntriesSequence and other assertion functions,
+// or compared with golden files via AssertGolden for snapshot testing.
 package logtest
@@ -0,0 +1,148 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package logtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/ssgreg/logf"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/acronis/go-appkit/log"
+)
+
+// UpdateGoldenEnvVar is a name of the environment variable that enables updating golden files
+// instead of comparing recorded entries with them (e.g., "LOGTEST_UPDATE_GOLDEN=1 go test ./...").
+const UpdateGoldenEnvVar = "LOGTEST_UPDATE_GOLDEN"
+
+// IgnoredFieldValue is a value that replaces values of ignored fields in the serialized entries.
+const IgnoredFieldValue = "<ignored>"
+
+// GoldenOpts represents options for AssertGoldenWithOpts and RequireGoldenWithOpts functions.
+type GoldenOpts struct {
+	// IgnoreFields is a list of field keys which values are replaced with IgnoredFieldValue.
+	// It's useful for fields with non-deterministic values (e.g., durations, timestamps, request IDs).
+	IgnoreFields []string
+
+	// Update enables updating the golden file instead of comparing recorded entries with it.
+	// Updating is also enabled if UpdateGoldenEnvVar environment variable is set to a true value.
+	Update bool
+}
+
+// MarshalEntries serializes recorded entries deterministically, one JSON object per line.
+// Time is omitted, so the same logging produces the same output on every run.
+// Each object contains level, logger name (if set), message and fields in the order they were logged.
+func MarshalEntries(entries []RecordedEntry) ([]byte, error) {
+	return marshalEntries(entries, nil)
+}
+
+func marshalEntries(entries []RecordedEntry, ignoreFields []string) ([]byte, error) {
+	enc := logf.NewJSONEncoder(logf.JSONEncoderConfig{
+		DisableFieldTime:   true,
+		DisableFieldCaller: true,
+	})
+	buf := logf.NewBuffer()
+	for i := range entries {
+		fields := make([]logf.Field, 0, len(entries[i].Fields))
+		for _, field := range entries[i].Fields {
+			if slices.Contains(ignoreFields, field.Key) {
+				field = logf.String(field.Key, IgnoredFieldValue)
+			}
+			fields = append(fields, field)
+		}
+		if err := enc.Encode(buf, logf.Entry{
+			LoggerName: entries[i].LoggerName,
+			Level:      convertLevelToLogfLevel(entries[i].Level),
+			Text:       entries[i].Text,
+			Fields:     fields,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// AssertGolden asserts that recorded entries serialized by MarshalEntries are equal to the content of the golden file.
+func AssertGolden(t assert.TestingT, r *Recorder, goldenPath string) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	return AssertGoldenWithOpts(t, r, goldenPath, GoldenOpts{})
+}
+
+// AssertGoldenWithOpts is like AssertGolden but accepts additional options.
+func AssertGoldenWithOpts(t assert.TestingT, r *Recorder, goldenPath string, opts GoldenOpts) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+
+	actual, err := marshalEntries(r.Entries(), opts.IgnoreFields)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("Failed to marshal recorded entries: %v", err))
+	}
+
+	if opts.Update || isUpdateGoldenEnabled() {
+		if err = os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			return assert.Fail(t, fmt.Sprintf("Failed to create directory for golden file: %v", err))
+		}
+		if err = os.WriteFile(goldenPath, actual, 0o644); err != nil { //nolint:gosec // Golden files are not sensitive.
+			return assert.Fail(t, fmt.Sprintf("Failed to write golden file: %v", err))
+		}
+		return true
+	}
+
+	expected, err := os.ReadFile(goldenPath) //nolint:gosec // Path is provided by the test.
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("Failed to read golden file (set %s=1 to create it): %v",
+			UpdateGoldenEnvVar, err))
+	}
+	return assert.Equal(t, string(expected), string(actual),
+		fmt.Sprintf("Recorded entries differ from golden file %s (set %s=1 to update it)", goldenPath, UpdateGoldenEnvVar))
+}
+
+// RequireGolden is like AssertGolden but stops the test execution on failure.
+func RequireGolden(t TestingT, r *Recorder, goldenPath string) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if !AssertGolden(t, r, goldenPath) {
+		t.FailNow()
+	}
+}
+
+// RequireGoldenWithOpts is like AssertGoldenWithOpts but stops the test execution on failure.
+func RequireGoldenWithOpts(t TestingT, r *Recorder, goldenPath string, opts GoldenOpts) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if !AssertGoldenWithOpts(t, r, goldenPath, opts) {
+		t.FailNow()
+	}
+}
+
+func isUpdateGoldenEnabled() bool {
+	update, _ := strconv.ParseBool(os.Getenv(UpdateGoldenEnvVar))
+	return update
+}
+
+func convertLevelToLogfLevel(level log.Level) logf.Level {
+	switch level {
+	case log.LevelError:
+		return logf.LevelError
+	case log.LevelWarn:
+		return logf.LevelWarn
+	case log.LevelInfo:
+		return logf.LevelInfo
+	case log.LevelDebug:
+		return logf.LevelDebug
+	}
+	return logf.LevelInfo
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package logtest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/acronis/go-appkit/log"
+)
+
+func TestMarshalEntries(t *testing.T) {
+	logRecorder := NewRecorder()
+	logger := logRecorder.With(log.String("component", "worker"))
+	logger.Info("job started", log.Int("job_id", 42))
+	logger.Error("job failed", log.Error(errors.New("connection refused")), log.Duration("elapsed", time.Second))
+
+	data, err := MarshalEntries(logRecorder.Entries())
+	require.NoError(t, err)
+	require.Equal(t, `{"level":"info","msg":"job started","job_id":42,"component":"worker"}
+{"level":"error","msg":"job failed","error":"connection refused","elapsed":"1s","component":"worker"}
+`, string(data))
+}
+
+func TestAssertGolden(t *testing.T) {
+	logRecorder := NewRecorder()
+	logRecorder.Info("request finished", log.Int("status", 200), log.Duration("duration", 123*time.Millisecond))
+	logRecorder.Warn("slow request", log.String("path", "/api/v1/users"))
+
+	RequireGoldenWithOpts(t, logRecorder, filepath.Join("testdata", "recorder.golden"), GoldenOpts{
+		IgnoreFields: []string{"duration"},
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		mt := &mockT{}
+		RequireGolden(mt, logRecorder, filepath.Join("testdata", "recorder.golden"))
+		require.True(t, mt.failed)
+		require.Len(t, mt.errors, 1)
+		require.Contains(t, mt.errors[0], "Recorded entries differ from golden file")
+	})
+
+	t.Run("missing golden file", func(t *testing.T) {
+		mt := &mockT{}
+		require.False(t, AssertGolden(mt, logRecorder, filepath.Join(t.TempDir(), "missing.golden")))
+		require.Len(t, mt.errors, 1)
+		require.Contains(t, mt.errors[0], "Failed to read golden file (set "+UpdateGoldenEnvVar+"=1 to create it)")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		goldenPath := filepath.Join(t.TempDir(), "sub", "updated.golden")
+		RequireGoldenWithOpts(t, logRecorder, goldenPath, GoldenOpts{Update: true})
+		data, err := os.ReadFile(goldenPath)
+		require.NoError(t, err)
+		expected, err := MarshalEntries(logRecorder.Entries())
+		require.NoError(t, err)
+		require.Equal(t, string(expected), string(data))
+		RequireGolden(t, logRecorder, goldenPath)
+	})
+}
@@ -0,0 +1,297 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package logtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/ssgreg/logf"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/acronis/go-appkit/log"
+)
+
+// EntryMatcher is an interface for matching recorded logging entries.
+type EntryMatcher interface {
+	// Match returns true if the entry satisfies the matcher.
+	Match(entry RecordedEntry) bool
+	// String returns a human-readable description of the matcher that is used in failure messages.
+	String() string
+}
+
+type entryMatcher struct {
+	match func(entry RecordedEntry) bool
+	desc  string
+}
+
+func (m entryMatcher) Match(entry RecordedEntry) bool { return m.match(entry) }
+
+func (m entryMatcher) String() string { return m.desc }
+
+// NewEntryMatcher creates a new EntryMatcher with the given match function and description.
+func NewEntryMatcher(match func(entry RecordedEntry) bool, desc string) EntryMatcher {
+	return entryMatcher{match, desc}
+}
+
+// HasLevel returns a matcher that matches entries with the given level.
+func HasLevel(level log.Level) EntryMatcher {
+	return entryMatcher{
+		func(entry RecordedEntry) bool { return entry.Level == level },
+		fmt.Sprintf("level=%s", level),
+	}
+}
+
+// HasMessage returns a matcher that matches entries with exactly the given message.
+func HasMessage(msg string) EntryMatcher {
+	return entryMatcher{
+		func(entry RecordedEntry) bool { return entry.Text == msg },
+		fmt.Sprintf("msg=%q", msg),
+	}
+}
+
+// MessageContains returns a matcher that matches entries which message contains the given substring.
+func MessageContains(substr string) EntryMatcher {
+	return entryMatcher{
+		func(entry RecordedEntry) bool { return strings.Contains(entry.Text, substr) },
+		fmt.Sprintf("msg contains %q", substr),
+	}
+}
+
+// MessageMatches returns a matcher that matches entries which message matches the given regular expression.
+// It panics if the pattern cannot be parsed.
+func MessageMatches(pattern string) EntryMatcher {
+	re := regexp.MustCompile(pattern)
+	return entryMatcher{
+		func(entry RecordedEntry) bool { return re.MatchString(entry.Text) },
+		fmt.Sprintf("msg matches /%s/", pattern),
+	}
+}
+
+// HasField returns a matcher that matches entries with the field with the given key.
+func HasField(key string) EntryMatcher {
+	return entryMatcher{
+		func(entry RecordedEntry) bool {
+			_, found := entry.FindField(key)
+			return found
+		},
+		fmt.Sprintf("has field %q", key),
+	}
+}
+
+// HasNoField returns a matcher that matches entries without the field with the given key.
+func HasNoField(key string) EntryMatcher {
+	return entryMatcher{
+		func(entry RecordedEntry) bool {
+			_, found := entry.FindField(key)
+			return !found
+		},
+		fmt.Sprintf("has no field %q", key),
+	}
+}
+
+// FieldEquals returns a matcher that matches entries with the field with the given key and value.
+// Values are compared by their JSON representation produced by the logger,
+// so FieldEquals("n", 42) matches both log.Int("n", 42) and log.Int64("n", 42),
+// and FieldEquals("d", time.Second) matches log.Duration("d", time.Second).
+func FieldEquals(key string, value interface{}) EntryMatcher {
+	expected, expectedErr := encodeFieldValue(logf.Any(key, value))
+	return entryMatcher{
+		func(entry RecordedEntry) bool {
+			if expectedErr != nil {
+				return false
+			}
+			field, found := entry.FindField(key)
+			if !found {
+				return false
+			}
+			actual, err := encodeFieldValue(*field)
+			return err == nil && reflect.DeepEqual(expected, actual)
+		},
+		fmt.Sprintf("field %q=%v", key, value),
+	}
+}
+
+// MatchAll returns a matcher that matches entries satisfying all the given matchers.
+// It's useful for describing a single step in AssertEntriesSequence.
+func MatchAll(matchers ...EntryMatcher) EntryMatcher {
+	descs := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		descs = append(descs, m.String())
+	}
+	return entryMatcher{
+		func(entry RecordedEntry) bool { return matchAll(entry, matchers) },
+		"{" + strings.Join(descs, ", ") + "}",
+	}
+}
+
+func matchAll(entry RecordedEntry, matchers []EntryMatcher) bool {
+	for _, m := range matchers {
+		if !m.Match(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// FindEntries returns all recorded entries that satisfy all the given matchers.
+func (r *Recorder) FindEntries(matchers ...EntryMatcher) []RecordedEntry {
+	return r.FindAllEntriesByFilter(func(entry RecordedEntry) bool {
+		return matchAll(entry, matchers)
+	})
+}
+
+// TestingT is an interface that is implemented by *testing.T and is compatible with testify's require.TestingT.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+type tHelper interface {
+	Helper()
+}
+
+// AssertEntry asserts that at least one recorded entry satisfies all the given matchers.
+func AssertEntry(t assert.TestingT, r *Recorder, matchers ...EntryMatcher) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if len(r.FindEntries(matchers...)) != 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("No recorded entry matches %s", MatchAll(matchers...)), describeEntries(r.Entries()))
+}
+
+// AssertNoEntry asserts that no recorded entry satisfies all the given matchers.
+func AssertNoEntry(t assert.TestingT, r *Recorder, matchers ...EntryMatcher) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	found := r.FindEntries(matchers...)
+	if len(found) == 0 {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("Unexpected recorded entries match %s", MatchAll(matchers...)), describeEntries(found))
+}
+
+// AssertEntriesCount asserts that exactly count recorded entries satisfy all the given matchers.
+func AssertEntriesCount(t assert.TestingT, r *Recorder, count int, matchers ...EntryMatcher) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	found := r.FindEntries(matchers...)
+	if len(found) == count {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("Expected %d recorded entries to match %s, but %d matched",
+		count, MatchAll(matchers...), len(found)), describeEntries(r.Entries()))
+}
+
+// AssertEntriesSequence asserts that recorded entries contain the given sequence.
+// Each matcher must be satisfied by an entry that was recorded after the entry satisfying the previous matcher,
+// other entries may be recorded in between. Use MatchAll for combining several matchers into a single step.
+func AssertEntriesSequence(t assert.TestingT, r *Recorder, sequence ...EntryMatcher) bool {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	entries := r.Entries()
+	step := 0
+	for i := 0; i < len(entries) && step < len(sequence); i++ {
+		if sequence[step].Match(entries[i]) {
+			step++
+		}
+	}
+	if step == len(sequence) {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("Recorded entries don't contain the expected sequence, step #%d %s is not matched",
+		step+1, sequence[step]), describeEntries(entries))
+}
+
+// RequireEntry is like AssertEntry but stops the test execution on failure.
+func RequireEntry(t TestingT, r *Recorder, matchers ...EntryMatcher) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if !AssertEntry(t, r, matchers...) {
+		t.FailNow()
+	}
+}
+
+// RequireNoEntry is like AssertNoEntry but stops the test execution on failure.
+func RequireNoEntry(t TestingT, r *Recorder, matchers ...EntryMatcher) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if !AssertNoEntry(t, r, matchers...) {
+		t.FailNow()
+	}
+}
+
+// RequireEntriesCount is like AssertEntriesCount but stops the test execution on failure.
+func RequireEntriesCount(t TestingT, r *Recorder, count int, matchers ...EntryMatcher) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if !AssertEntriesCount(t, r, count, matchers...) {
+		t.FailNow()
+	}
+}
+
+// RequireEntriesSequence is like AssertEntriesSequence but stops the test execution on failure.
+func RequireEntriesSequence(t TestingT, r *Recorder, sequence ...EntryMatcher) {
+	if h, ok := t.(tHelper); ok {
+		h.Helper()
+	}
+	if !AssertEntriesSequence(t, r, sequence...) {
+		t.FailNow()
+	}
+}
+
+func describeEntries(entries []RecordedEntry) string {
+	if len(entries) == 0 {
+		return "No entries were recorded"
+	}
+	data, err := MarshalEntries(entries)
+	if err != nil {
+		return fmt.Sprintf("Failed to marshal recorded entries: %v", err)
+	}
+	return "Recorded entries:\n" + string(data)
+}
+
+// encodeFieldValue encodes the field to JSON in the same way as the logger does and decodes it back,
+// so values of different Go types that are logged identically become comparable.
+func encodeFieldValue(field log.Field) (interface{}, error) {
+	buf := logf.NewBuffer()
+	field.Key = "v"
+	if err := newFieldsEncoder().Encode(buf, logf.Entry{Fields: []logf.Field{field}}); err != nil {
+		return nil, err
+	}
+	var decoded map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return decoded[field.Key], nil
+}
+
+// newFieldsEncoder creates a JSON encoder that encodes only entry fields.
+// A new encoder should be created for every use since logf encoders are not goroutine-safe.
+func newFieldsEncoder() logf.Encoder {
+	return logf.NewJSONEncoder(logf.JSONEncoderConfig{
+		DisableFieldMsg:    true,
+		DisableFieldTime:   true,
+		DisableFieldLevel:  true,
+		DisableFieldName:   true,
+		DisableFieldCaller: true,
+	})
+}
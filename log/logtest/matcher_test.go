@@ -0,0 +1,126 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package logtest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/acronis/go-appkit/log"
+)
+
+type mockT struct {
+	errors []string
+	failed bool
+}
+
+func (m *mockT) Errorf(format string, args ...interface{}) {
+	m.errors = append(m.errors, fmt.Sprintf(format, args...))
+}
+
+func (m *mockT) FailNow() {
+	m.failed = true
+}
+
+func TestMatchers(t *testing.T) {
+	logRecorder := NewRecorder()
+	logRecorder.With(log.String("component", "db")).Info("connected to database",
+		log.Int("attempt", 3), log.Duration("elapsed", time.Second), log.Error(errors.New("timeout")))
+
+	entry := logRecorder.Entries()[0]
+
+	tests := []struct {
+		name    string
+		matcher EntryMatcher
+		want    bool
+	}{
+		{"level matched", HasLevel(log.LevelInfo), true},
+		{"level not matched", HasLevel(log.LevelError), false},
+		{"message matched", HasMessage("connected to database"), true},
+		{"message not matched", HasMessage("connected"), false},
+		{"message contains", MessageContains("to data"), true},
+		{"message doesn't contain", MessageContains("cache"), false},
+		{"message matches pattern", MessageMatches(`^connected to \w+$`), true},
+		{"message doesn't match pattern", MessageMatches(`^disconnected`), false},
+		{"has field", HasField("attempt"), true},
+		{"has derived field", HasField("component"), true},
+		{"has no field", HasNoField("unknown"), true},
+		{"has no field, but it exists", HasNoField("attempt"), false},
+		{"int field equals", FieldEquals("attempt", 3), true},
+		{"int field equals, different type", FieldEquals("attempt", uint8(3)), true},
+		{"int field doesn't equal", FieldEquals("attempt", 4), false},
+		{"string field equals", FieldEquals("component", "db"), true},
+		{"string field doesn't equal", FieldEquals("component", "3"), false},
+		{"duration field equals", FieldEquals("elapsed", time.Second), true},
+		{"error field equals", FieldEquals("error", errors.New("timeout")), true},
+		{"missing field", FieldEquals("unknown", 3), false},
+		{"all matched", MatchAll(HasLevel(log.LevelInfo), FieldEquals("attempt", 3)), true},
+		{"not all matched", MatchAll(HasLevel(log.LevelInfo), FieldEquals("attempt", 4)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.matcher.Match(entry), tt.matcher.String())
+		})
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	logRecorder := NewRecorder()
+	logRecorder.Info("request started", log.String("method", "GET"))
+	logRecorder.Debug("cache miss", log.String("key", "user:1"))
+	logRecorder.Warn("slow query", log.Int("duration_ms", 1500))
+	logRecorder.Info("request finished", log.Int("status", 200))
+
+	t.Run("passed", func(t *testing.T) {
+		RequireEntry(t, logRecorder, HasLevel(log.LevelWarn), MessageContains("slow"))
+		RequireNoEntry(t, logRecorder, HasLevel(log.LevelError))
+		RequireEntriesCount(t, logRecorder, 2, HasLevel(log.LevelInfo))
+		RequireEntriesCount(t, logRecorder, 0, FieldEquals("status", 500))
+		RequireEntriesSequence(t, logRecorder,
+			HasMessage("request started"),
+			MatchAll(HasLevel(log.LevelWarn), HasField("duration_ms")),
+			MatchAll(HasMessage("request finished"), FieldEquals("status", 200)))
+	})
+
+	t.Run("entry not found", func(t *testing.T) {
+		mt := &mockT{}
+		require.False(t, AssertEntry(mt, logRecorder, HasLevel(log.LevelError), HasMessage("oops")))
+		require.Len(t, mt.errors, 1)
+		require.Contains(t, mt.errors[0], `No recorded entry matches {level=error, msg="oops"}`)
+		require.Contains(t, mt.errors[0], `{"level":"warn","msg":"slow query","duration_ms":1500}`)
+	})
+
+	t.Run("unexpected entry", func(t *testing.T) {
+		mt := &mockT{}
+		RequireNoEntry(mt, logRecorder, HasLevel(log.LevelDebug))
+		require.True(t, mt.failed)
+		require.Len(t, mt.errors, 1)
+		require.Contains(t, mt.errors[0], `Unexpected recorded entries match {level=debug}`)
+		require.Contains(t, mt.errors[0], `{"level":"debug","msg":"cache miss","key":"user:1"}`)
+		require.NotContains(t, mt.errors[0], `request started`)
+	})
+
+	t.Run("wrong count", func(t *testing.T) {
+		mt := &mockT{}
+		RequireEntriesCount(mt, logRecorder, 1, HasLevel(log.LevelInfo))
+		require.True(t, mt.failed)
+		require.Len(t, mt.errors, 1)
+		require.Contains(t, mt.errors[0], `Expected 1 recorded entries to match {level=info}, but 2 matched`)
+	})
+
+	t.Run("wrong sequence", func(t *testing.T) {
+		mt := &mockT{}
+		RequireEntriesSequence(mt, logRecorder, HasMessage("request finished"), HasMessage("request started"))
+		require.True(t, mt.failed)
+		require.Len(t, mt.errors, 1)
+		require.Contains(t, mt.errors[0], `step #2 msg="request started" is not matched`)
+	})
+}
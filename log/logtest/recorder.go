@@ -71,6 +71,14 @@ func NewRecorder() *Recorder {
 	return &Recorder{&log.LogfAdapter{Logger: logger}, ew}
 }
 
+// NewRecorderWithLevel returns an initialized Recorder that records entries at the given level and above.
+// The level of the returned Recorder may be escalated to debug via log.EscalateToDebug function.
+func NewRecorderWithLevel(level log.Level) *Recorder {
+	ew := &recordingEntryWriter{}
+	logger := logf.NewLogger(logf.LevelDebug, ew)
+	return &Recorder{log.NewLogfAdapterWithDebugEscalation(logger, level), ew}
+}
+
 // With returns a new Recorder with the given additional fields.
 func (r *Recorder) With(fs ...log.Field) log.FieldLogger {
 	return &Recorder{r.LogfAdapter.With(fs...).(*log.LogfAdapter), r.entryWriter}
@@ -83,6 +91,16 @@ func (r *Recorder) WithLevel(level log.Level) log.FieldLogger {
 	return &Recorder{r.LogfAdapter.WithLevel(level).(*log.LogfAdapter), r.entryWriter}
 }
 
+// EscalateToDebug returns a new Recorder that records entries at all levels including debug.
+// The second returned value is false if the Recorder is not created by NewRecorderWithLevel.
+func (r *Recorder) EscalateToDebug() (log.FieldLogger, bool) {
+	escalated, ok := r.LogfAdapter.EscalateToDebug()
+	if !ok {
+		return r, false
+	}
+	return &Recorder{escalated.(*log.LogfAdapter), r.entryWriter}, true
+}
+
 // Entries returns all recorded logging entries.
 func (r *Recorder) Entries() []RecordedEntry {
 	r.entryWriter.RLock()
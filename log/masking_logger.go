@@ -89,6 +89,15 @@ func (l MaskingLogger) WithLevel(level Level) FieldLogger {
 	return MaskingLogger{l.log.WithLevel(level), l.masker}
 }
 
+// EscalateToDebug returns a new logger with the level escalated to debug if the underlying logger supports it.
+func (l MaskingLogger) EscalateToDebug() (FieldLogger, bool) {
+	escalated, ok := EscalateToDebug(l.log)
+	if !ok {
+		return l, false
+	}
+	return MaskingLogger{escalated, l.masker}, true
+}
+
 var stringSliceType = reflect.TypeOf([]string{})
 
 // maskFields masks secrets in log fields
@@ -80,3 +80,12 @@ func (l *PrefixedLogger) AtLevel(level Level, fn func(logFunc LogFunc)) {
 func (l *PrefixedLogger) WithLevel(level Level) FieldLogger {
 	return &PrefixedLogger{l.delegate.WithLevel(level), l.prefix}
 }
+
+// EscalateToDebug returns a new logger with the level escalated to debug if the delegate logger supports it.
+func (l *PrefixedLogger) EscalateToDebug() (FieldLogger, bool) {
+	escalated, ok := EscalateToDebug(l.delegate)
+	if !ok {
+		return l, false
+	}
+	return &PrefixedLogger{escalated, l.prefix}, true
+}
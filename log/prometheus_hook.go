@@ -0,0 +1,92 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package log
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/acronis/go-appkit/internal/libinfo"
+)
+
+// Prometheus labels.
+const (
+	PrometheusHookLabelLevel  = "level"
+	PrometheusHookLabelLogger = "logger"
+)
+
+// PrometheusHookOpts represents options for PrometheusHook.
+type PrometheusHookOpts struct {
+	// Namespace is a namespace for metrics. It will be prepended to all metric names.
+	Namespace string
+
+	// ConstLabels is a set of labels that will be applied to all metrics.
+	ConstLabels prometheus.Labels
+
+	// MessagePrefixes is a list of message prefixes (e.g., ones that are used with NewPrefixedLogger)
+	// that are used as a value for the "logger" label when the logger has no name.
+	// The first matched prefix with trimmed whitespaces is used.
+	// If the logger has no name and no prefix is matched, the label value is empty.
+	MessagePrefixes []string
+}
+
+// PrometheusHook is a Hook that counts log entries in Prometheus by level and logger name.
+// It allows alerting on the number of error logs per component without scraping logs.
+type PrometheusHook struct {
+	EntriesTotal *prometheus.CounterVec
+
+	messagePrefixes []string
+}
+
+var _ Hook = (*PrometheusHook)(nil)
+
+// NewPrometheusHook creates a new instance of PrometheusHook with default options.
+func NewPrometheusHook() *PrometheusHook {
+	return NewPrometheusHookWithOpts(PrometheusHookOpts{})
+}
+
+// NewPrometheusHookWithOpts creates a new instance of PrometheusHook with the provided options.
+func NewPrometheusHookWithOpts(opts PrometheusHookOpts) *PrometheusHook {
+	entriesTotal := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "log_entries_total",
+			Help:        "Number of log entries by level and logger.",
+			ConstLabels: libinfo.AddPrometheusLibVersionLabel(opts.ConstLabels),
+		},
+		[]string{PrometheusHookLabelLevel, PrometheusHookLabelLogger},
+	)
+	return &PrometheusHook{EntriesTotal: entriesTotal, messagePrefixes: opts.MessagePrefixes}
+}
+
+// MustRegister does registration of metrics collector in Prometheus and panics if any error occurs.
+func (h *PrometheusHook) MustRegister() {
+	prometheus.MustRegister(h.EntriesTotal)
+}
+
+// Unregister cancels registration of metrics collector in Prometheus.
+func (h *PrometheusHook) Unregister() {
+	prometheus.Unregister(h.EntriesTotal)
+}
+
+// OnEntry increments the counter of log entries.
+func (h *PrometheusHook) OnEntry(entry Entry) {
+	h.EntriesTotal.WithLabelValues(string(entry.Level), h.loggerLabelValue(entry)).Inc()
+}
+
+func (h *PrometheusHook) loggerLabelValue(entry Entry) string {
+	if entry.LoggerName != "" {
+		return entry.LoggerName
+	}
+	for _, prefix := range h.messagePrefixes {
+		if strings.HasPrefix(entry.Text, prefix) {
+			return strings.TrimSpace(prefix)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,268 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package log
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ssgreg/logf"
+	"github.com/ssgreg/logftext"
+)
+
+// DefaultRingBufferSize is a default number of entries that are kept in the RingBuffer.
+const DefaultRingBufferSize = 1000
+
+// RingBufferOpts represents options for RingBuffer.
+type RingBufferOpts struct {
+	// Size is the maximum number of entries that are kept for all levels not present in LevelSizes.
+	// If it's 0, DefaultRingBufferSize is used.
+	Size int
+
+	// LevelSizes allows keeping entries of specific levels in separate buffers of the given sizes.
+	// For example, it can be used to keep errors for a longer period of time than debug messages.
+	// If the size for a level is 0 or negative, entries of this level are not kept at all.
+	LevelSizes map[Level]int
+}
+
+// RingBuffer is a Hook that keeps the most recent log entries in memory.
+// It's useful for debugging when there is no access to the log pipeline.
+// Recent entries may be exposed via HTTP using NewRingBufferHandler.
+type RingBuffer struct {
+	mu          sync.Mutex
+	seq         uint64
+	defaultRing *entryRing
+	levelRings  map[Level]*entryRing
+}
+
+var _ Hook = (*RingBuffer)(nil)
+
+// NewRingBuffer creates a new RingBuffer.
+func NewRingBuffer(opts RingBufferOpts) *RingBuffer {
+	size := opts.Size
+	if size <= 0 {
+		size = DefaultRingBufferSize
+	}
+	levelRings := make(map[Level]*entryRing, len(opts.LevelSizes))
+	for level, levelSize := range opts.LevelSizes {
+		if levelSize < 0 {
+			levelSize = 0
+		}
+		levelRings[level] = newEntryRing(levelSize)
+	}
+	return &RingBuffer{defaultRing: newEntryRing(size), levelRings: levelRings}
+}
+
+// OnEntry stores the entry in the buffer, overwriting the oldest one if the buffer is full.
+func (rb *RingBuffer) OnEntry(entry Entry) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.seq++
+	ring, ok := rb.levelRings[entry.Level]
+	if !ok {
+		ring = rb.defaultRing
+	}
+	ring.push(sequencedEntry{seq: rb.seq, entry: entry})
+}
+
+// RingBufferFilter is a filter for entries returned by RingBuffer.
+type RingBufferFilter struct {
+	// Level is a minimal level of entries. "debug" is a minimal level, "error" - maximal.
+	// If it's empty, entries of all levels are returned.
+	Level Level
+
+	// MsgSubstring is a substring that must be present in the entry message.
+	MsgSubstring string
+
+	// RequestID is a value of the "request_id" or "int_request_id" field that must be present in the entry.
+	RequestID string
+
+	// Limit is the maximum number of the most recent entries to return. If it's 0, all matched entries are returned.
+	Limit int
+}
+
+// Entries returns the recent entries that match the filter in chronological order.
+func (rb *RingBuffer) Entries(filter RingBufferFilter) []Entry {
+	rb.mu.Lock()
+	seqEntries := rb.defaultRing.appendTo(nil)
+	for _, ring := range rb.levelRings {
+		seqEntries = ring.appendTo(seqEntries)
+	}
+	rb.mu.Unlock()
+
+	sort.Slice(seqEntries, func(i, j int) bool {
+		return seqEntries[i].seq < seqEntries[j].seq
+	})
+
+	entries := make([]Entry, 0, len(seqEntries))
+	for i := range seqEntries {
+		if filter.match(&seqEntries[i].entry) {
+			entries = append(entries, seqEntries[i].entry)
+		}
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries
+}
+
+func (f *RingBufferFilter) match(entry *Entry) bool {
+	if f.Level != "" && convertLevelToLogfLevel(entry.Level) > convertLevelToLogfLevel(f.Level) {
+		return false
+	}
+	if f.MsgSubstring != "" && !strings.Contains(entry.Text, f.MsgSubstring) {
+		return false
+	}
+	if f.RequestID != "" {
+		for _, key := range [...]string{"request_id", "int_request_id"} {
+			if field, ok := entry.FindField(key); ok &&
+				field.Type == logf.FieldTypeBytesToString && string(field.Bytes) == f.RequestID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+type sequencedEntry struct {
+	seq   uint64
+	entry Entry
+}
+
+type entryRing struct {
+	entries []sequencedEntry
+	next    int
+	full    bool
+}
+
+func newEntryRing(size int) *entryRing {
+	return &entryRing{entries: make([]sequencedEntry, size)}
+}
+
+func (r *entryRing) push(entry sequencedEntry) {
+	if len(r.entries) == 0 {
+		return
+	}
+	r.entries[r.next] = entry
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+func (r *entryRing) appendTo(dst []sequencedEntry) []sequencedEntry {
+	if r.full {
+		dst = append(dst, r.entries[r.next:]...)
+	}
+	return append(dst, r.entries[:r.next]...)
+}
+
+// Query parameters that are supported by the handler created by NewRingBufferHandler.
+const (
+	RingBufferQueryParamFormat    = "format"
+	RingBufferQueryParamLevel     = "level"
+	RingBufferQueryParamMsg       = "msg"
+	RingBufferQueryParamRequestID = "request_id"
+	RingBufferQueryParamLimit     = "limit"
+)
+
+// NewRingBufferHandler creates a new HTTP handler that returns the recent log entries from the RingBuffer.
+// It may be mounted on the profiling server (see profserver package).
+//
+// Supported query parameters:
+//   - format: "json" (default) or "text".
+//   - level: minimal level of entries ("debug", "info", "warn", "error").
+//   - msg: substring that must be present in the entry message.
+//   - request_id: value of the "request_id" or "int_request_id" field.
+//   - limit: maximum number of the most recent entries to return.
+//
+// In JSON format, the response is an array of entries, in text format - entries separated by newlines.
+func NewRingBufferHandler(rb *RingBuffer) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		filter := RingBufferFilter{
+			MsgSubstring: query.Get(RingBufferQueryParamMsg),
+			RequestID:    query.Get(RingBufferQueryParamRequestID),
+		}
+		if levelStr := query.Get(RingBufferQueryParamLevel); levelStr != "" {
+			filter.Level = Level(strings.ToLower(levelStr))
+			if !isKnownLevel(filter.Level) {
+				http.Error(rw, "unknown level "+strconv.Quote(levelStr), http.StatusBadRequest)
+				return
+			}
+		}
+		if limitStr := query.Get(RingBufferQueryParamLimit); limitStr != "" {
+			limit, err := strconv.Atoi(limitStr)
+			if err != nil || limit < 0 {
+				http.Error(rw, "limit must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			filter.Limit = limit
+		}
+
+		var encoder logf.Encoder
+		var contentType string
+		format := Format(strings.ToLower(query.Get(RingBufferQueryParamFormat)))
+		switch format {
+		case "", FormatJSON:
+			format = FormatJSON
+			encoder = newJSONEncoder(encoderConfig{
+				EncodeTime: logf.RFC3339NanoTimeEncoder, EncodeError: logf.DefaultErrorEncoder})
+			contentType = "application/json"
+		case FormatText:
+			noColor := true
+			encoder = logftext.NewEncoder(logftext.EncoderConfig{
+				NoColor: &noColor, EncodeTime: logf.RFC3339NanoTimeEncoder})
+			contentType = "text/plain; charset=utf-8"
+		default:
+			http.Error(rw, "unsupported format "+strconv.Quote(string(format)), http.StatusBadRequest)
+			return
+		}
+
+		buf := logf.NewBuffer()
+		if format == FormatJSON {
+			buf.AppendByte('[')
+		}
+		for i, entry := range rb.Entries(filter) {
+			if format == FormatJSON && i > 0 {
+				buf.AppendByte(',')
+			}
+			_ = encoder.Encode(buf, logf.Entry{
+				LoggerName: entry.LoggerName,
+				Level:      convertLevelToLogfLevel(entry.Level),
+				Time:       entry.Time,
+				Text:       entry.Text,
+				Fields:     entry.Fields,
+			})
+			if format == FormatJSON && buf.Back() == '\n' {
+				buf.Data = buf.Data[:buf.Len()-1]
+			}
+		}
+		if format == FormatJSON {
+			buf.AppendString("]\n")
+		}
+
+		rw.Header().Set("Content-Type", contentType)
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write(buf.Bytes())
+	})
+}
+
+func isKnownLevel(level Level) bool {
+	switch level {
+	case LevelError, LevelWarn, LevelInfo, LevelDebug:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func entriesTexts(entries []Entry) []string {
+	texts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		texts = append(texts, entry.Text)
+	}
+	return texts
+}
+
+func TestRingBuffer(t *testing.T) {
+	t.Run("overwrite oldest entries", func(t *testing.T) {
+		rb := NewRingBuffer(RingBufferOpts{Size: 3})
+		for _, text := range []string{"msg1", "msg2", "msg3", "msg4", "msg5"} {
+			rb.OnEntry(Entry{Level: LevelInfo, Text: text})
+		}
+		require.Equal(t, []string{"msg3", "msg4", "msg5"}, entriesTexts(rb.Entries(RingBufferFilter{})))
+		require.Equal(t, []string{"msg4", "msg5"}, entriesTexts(rb.Entries(RingBufferFilter{Limit: 2})))
+	})
+
+	t.Run("per level sizes", func(t *testing.T) {
+		rb := NewRingBuffer(RingBufferOpts{Size: 2, LevelSizes: map[Level]int{LevelError: 3, LevelDebug: 0, LevelWarn: -1}})
+		rb.OnEntry(Entry{Level: LevelError, Text: "error1"})
+		rb.OnEntry(Entry{Level: LevelInfo, Text: "info1"})
+		rb.OnEntry(Entry{Level: LevelDebug, Text: "debug1"})
+		rb.OnEntry(Entry{Level: LevelError, Text: "error2"})
+		rb.OnEntry(Entry{Level: LevelWarn, Text: "warn1"})
+		rb.OnEntry(Entry{Level: LevelInfo, Text: "info2"})
+		require.Equal(t, []string{"error1", "info1", "error2", "info2"}, entriesTexts(rb.Entries(RingBufferFilter{})))
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		rb := NewRingBuffer(RingBufferOpts{})
+		rb.OnEntry(Entry{Level: LevelDebug, Text: "debug request", Fields: []Field{String("request_id", "req1")}})
+		rb.OnEntry(Entry{Level: LevelInfo, Text: "info request", Fields: []Field{String("request_id", "req2")}})
+		rb.OnEntry(Entry{Level: LevelWarn, Text: "warn", Fields: []Field{String("int_request_id", "req1")}})
+		rb.OnEntry(Entry{Level: LevelError, Text: "error request", Fields: []Field{String("request_id", "req1")}})
+
+		require.Equal(t, []string{"warn", "error request"},
+			entriesTexts(rb.Entries(RingBufferFilter{Level: LevelWarn})))
+		require.Equal(t, []string{"debug request", "info request", "error request"},
+			entriesTexts(rb.Entries(RingBufferFilter{MsgSubstring: "request"})))
+		require.Equal(t, []string{"debug request", "warn", "error request"},
+			entriesTexts(rb.Entries(RingBufferFilter{RequestID: "req1"})))
+		require.Equal(t, []string{"debug request", "error request"},
+			entriesTexts(rb.Entries(RingBufferFilter{RequestID: "req1", MsgSubstring: "request"})))
+	})
+
+	t.Run("as logger hook", func(t *testing.T) {
+		rb := NewRingBuffer(RingBufferOpts{Size: 10})
+		logger, closeFunc := newTestFileLogger(t, LevelInfo, WithHooks(rb))
+		logger.Debug("debug message")
+		logger.Info("info message")
+		closeFunc()
+		require.Equal(t, []string{"info message"}, entriesTexts(rb.Entries(RingBufferFilter{})))
+	})
+}
+
+func TestRingBufferHandler(t *testing.T) {
+	entryTime := time.Date(2024, 3, 15, 10, 20, 30, 0, time.UTC)
+	rb := NewRingBuffer(RingBufferOpts{})
+	rb.OnEntry(Entry{Level: LevelInfo, Time: entryTime, Text: "request handled",
+		Fields: []Field{String("request_id", "req1"), Int("status", 200)}})
+	rb.OnEntry(Entry{Level: LevelError, Time: entryTime.Add(time.Second), Text: "request failed",
+		Fields: []Field{String("request_id", "req2"), Int("status", 500)}})
+	handler := NewRingBufferHandler(rb)
+
+	doRequest := func(query string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/debug/logs?"+query, nil))
+		return resp
+	}
+
+	t.Run("json", func(t *testing.T) {
+		resp := doRequest("")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+		var entries []map[string]interface{}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &entries))
+		require.Equal(t, []map[string]interface{}{
+			{"level": "info", "time": "2024-03-15T10:20:30Z", "msg": "request handled", "request_id": "req1", "status": 200.0},
+			{"level": "error", "time": "2024-03-15T10:20:31Z", "msg": "request failed", "request_id": "req2", "status": 500.0},
+		}, entries)
+	})
+
+	t.Run("json, no entries", func(t *testing.T) {
+		resp := doRequest("msg=unknown")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "[]\n", resp.Body.String())
+	})
+
+	t.Run("text with filters", func(t *testing.T) {
+		resp := doRequest("format=text&level=warn&request_id=req2&msg=failed&limit=10")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "text/plain; charset=utf-8", resp.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+		require.Len(t, lines, 1)
+		require.Contains(t, lines[0], "request failed")
+		require.Contains(t, lines[0], "status=500")
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		for _, query := range []string{"level=unknown", "limit=-1", "limit=abc", "format=xml"} {
+			resp := doRequest(query)
+			require.Equal(t, http.StatusBadRequest, resp.Code, query)
+		}
+	})
+}
@@ -18,6 +18,23 @@ type cacheEntry[K comparable, V any] struct {
 	key       K
 	value     V
 	expiresAt time.Time
+	cost      int64
+	version   uint64 // changes every time the value for the key is set
+}
+
+type evictedEntry[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictionReason
+}
+
+// maxNegativeEntriesWithoutEntriesLimit limits the number of cached errors of loadValue function
+// when the cache is bounded only by the total cost of entries.
+const maxNegativeEntriesWithoutEntriesLimit = 10000
+
+type negativeEntry struct {
+	err       error
+	expiresAt time.Time
 }
 
 type singleFlightCallResult[V any] struct {
@@ -28,16 +45,29 @@ type singleFlightCallResult[V any] struct {
 // LRUCache represents an LRU cache with eviction mechanism and Prometheus metrics.
 type LRUCache[K comparable, V any] struct {
 	maxEntries int
+	maxCost    int64
 
-	defaultTTL time.Duration
+	defaultTTL   time.Duration
+	refreshAhead time.Duration
+	maxStaleness time.Duration
+	negativeTTL  time.Duration
+	weigher      func(key K, value V) int64
+	onEvict      func(key K, value V, reason EvictionReason)
 
 	mu      sync.RWMutex
 	lruList *list.List
-	cache   map[K]*list.Element // map of cache entries, value is a lruList element
+	cache   map[K]*list.Element  // map of cache entries, value is a lruList element
+	cost    int64                // total cost of all entries
+	evicted []evictedEntry[K, V] // entries for OnEvict callback, it's called after unlocking
+
+	negative    map[K]negativeEntry // cached errors of loadValue function in GetOrLoad
+	refreshing  map[K]struct{}      // keys that are being refreshed in background
+	lastVersion uint64              // last version assigned to the cache entry
 
 	sfGroup *singleFlightGroup[K, singleFlightCallResult[V]]
 
-	metricsCollector MetricsCollector
+	metricsCollector     MetricsCollector
+	costMetricsCollector CostMetricsCollector
 }
 
 // Options represents options for the cache.
@@ -46,6 +76,90 @@ type Options struct {
 	// Please note that expired entries are not removed immediately,
 	// but only when they are accessed or during periodic cleanup (see RunPeriodicCleanup).
 	DefaultTTL time.Duration
+
+	// MaxCost is the maximum total cost of the cache entries (e.g., size in bytes, see Callbacks.Weigher).
+	// If it's greater than 0, the least recently used entries are evicted until the total cost is under the limit.
+	// An entry that costs more than MaxCost is evicted right after adding, other entries are kept.
+	// In this case, maxEntries may be 0 that means no limit on the number of entries.
+	MaxCost int64
+
+	// RefreshAhead is a period before the expiration when GetOrLoad/GetOrLoadWithTTL methods
+	// return the current value and refresh it in background.
+	// If it's 0, entries are not refreshed before the expiration.
+	RefreshAhead time.Duration
+
+	// MaxStaleness is a period after the expiration when GetOrLoad/GetOrLoadWithTTL methods
+	// return the stale value and refresh it in background (stale-while-revalidate).
+	// Other methods (Get, GetOrAdd, etc.) don't return stale values.
+	// Stale entries are removed only when this period is over.
+	// If it's 0, expired values are not returned, and callers wait for loading of the new value.
+	MaxStaleness time.Duration
+
+	// NegativeTTL is a period for which errors returned by the loadValue function in GetOrLoad/GetOrLoadWithTTL
+	// methods are cached, so the following calls for the same key return the cached error without loading.
+	// If it's 0, errors are not cached. The number of cached errors is limited by the maximum number of entries
+	// (or by 10000 if the cache is bounded only by the total cost).
+	// Errors of the background refresh are not cached, the current value is returned until it's stale.
+	NegativeTTL time.Duration
+}
+
+// Callbacks represents typed callbacks for the cache (see NewWithCallbacks and NewShardedWithCallbacks).
+type Callbacks[K comparable, V any] struct {
+	// Weigher returns the cost of the cache entry. It must be fast, non-blocking, and return a non-negative value.
+	// By default, each entry costs 1.
+	Weigher func(key K, value V) int64
+
+	// OnEvict is called when the entry is removed from the cache (see EvictionReason for all possible reasons).
+	// It may be used for releasing resources associated with the entry (e.g., closing connections).
+	// The callback is called synchronously by the goroutine that removed the entry (e.g., by the one that runs
+	// RunPeriodicCleanup for expired entries), after the entry is removed and the cache lock is released,
+	// so it's safe to call cache methods inside it. Callbacks for entries removed at once are called in removal order.
+	// When the value of the existing entry is replaced (e.g., by Add or by the background refresh),
+	// the callback is called for the old value with EvictionReasonReplaced reason, even if the new value is the same.
+	OnEvict func(key K, value V, reason EvictionReason)
+}
+
+// EvictionReason represents a reason why the entry is removed from the cache.
+type EvictionReason int
+
+// Eviction reasons.
+const (
+	// EvictionReasonCapacity means that the entry is evicted because the cache is full
+	// (by the number of entries or by the total cost).
+	EvictionReasonCapacity EvictionReason = iota
+	// EvictionReasonExpired means that the entry is expired and removed when it's accessed or during periodic cleanup.
+	EvictionReasonExpired
+	// EvictionReasonRemoved means that the entry is removed by Remove method.
+	EvictionReasonRemoved
+	// EvictionReasonPurged means that the entry is removed by Purge method.
+	EvictionReasonPurged
+	// EvictionReasonResized means that the entry is evicted because the cache is shrunk by Resize or ResizeCost methods.
+	EvictionReasonResized
+	// EvictionReasonReplaced means that the value of the entry is replaced by a new one
+	// (by Add/AddWithTTL methods or by the background refresh in GetOrLoad/GetOrLoadWithTTL methods).
+	// It's also used for the value loaded by the background refresh if it's discarded
+	// because the entry was changed or removed while the refresh was in progress.
+	EvictionReasonReplaced
+)
+
+// String returns a string representation of the eviction reason.
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictionReasonCapacity:
+		return "capacity"
+	case EvictionReasonExpired:
+		return "expired"
+	case EvictionReasonRemoved:
+		return "removed"
+	case EvictionReasonPurged:
+		return "purged"
+	case EvictionReasonResized:
+		return "resized"
+	case EvictionReasonReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
 }
 
 // New creates a new LRUCache with the provided maximum number of entries and metrics collector.
@@ -56,31 +170,67 @@ func New[K comparable, V any](maxEntries int, metricsCollector MetricsCollector)
 // NewWithOpts creates a new LRUCache with the provided maximum number of entries, metrics collector, and options.
 // Metrics collector is used to collect statistics about cache usage.
 // It can be nil, in this case, metrics will be disabled.
+// If it also implements CostMetricsCollector interface, the total cost of the entries is reported too.
 func NewWithOpts[K comparable, V any](maxEntries int, metricsCollector MetricsCollector, opts Options) (*LRUCache[K, V], error) {
-	if maxEntries <= 0 {
+	return NewWithCallbacks[K, V](maxEntries, metricsCollector, opts, Callbacks[K, V]{})
+}
+
+// NewWithCallbacks is a version of NewWithOpts that allows specifying typed callbacks (weigher and eviction callback).
+func NewWithCallbacks[K comparable, V any](
+	maxEntries int, metricsCollector MetricsCollector, opts Options, callbacks Callbacks[K, V],
+) (*LRUCache[K, V], error) {
+	if maxEntries < 0 || (maxEntries == 0 && opts.MaxCost == 0) {
 		return nil, fmt.Errorf("maxEntries must be greater than 0")
 	}
+	if opts.MaxCost < 0 {
+		return nil, fmt.Errorf("maxCost must be greater or equal to 0 (no cost limit)")
+	}
 	if opts.DefaultTTL < 0 {
 		return nil, fmt.Errorf("defaultTTL must be greater or equal to 0 (no expiration)")
 	}
+	if opts.RefreshAhead < 0 {
+		return nil, fmt.Errorf("refreshAhead must be greater or equal to 0 (no refresh ahead)")
+	}
+	if opts.MaxStaleness < 0 {
+		return nil, fmt.Errorf("maxStaleness must be greater or equal to 0 (no stale values)")
+	}
+	if opts.NegativeTTL < 0 {
+		return nil, fmt.Errorf("negativeTTL must be greater or equal to 0 (no errors caching)")
+	}
+	if callbacks.Weigher == nil {
+		callbacks.Weigher = func(K, V) int64 { return 1 }
+	}
 	if metricsCollector == nil {
 		metricsCollector = disabledMetricsCollector
 	}
+	costMetricsCollector, ok := metricsCollector.(CostMetricsCollector)
+	if !ok {
+		costMetricsCollector = disabledMetrics{}
+	}
 
 	return &LRUCache[K, V]{
-		maxEntries:       maxEntries,
-		lruList:          list.New(),
-		cache:            make(map[K]*list.Element),
-		sfGroup:          &singleFlightGroup[K, singleFlightCallResult[V]]{},
-		metricsCollector: metricsCollector,
-		defaultTTL:       opts.DefaultTTL,
+		maxEntries:           maxEntries,
+		maxCost:              opts.MaxCost,
+		lruList:              list.New(),
+		cache:                make(map[K]*list.Element),
+		negative:             make(map[K]negativeEntry),
+		refreshing:           make(map[K]struct{}),
+		sfGroup:              &singleFlightGroup[K, singleFlightCallResult[V]]{},
+		metricsCollector:     metricsCollector,
+		costMetricsCollector: costMetricsCollector,
+		defaultTTL:           opts.DefaultTTL,
+		refreshAhead:         opts.RefreshAhead,
+		maxStaleness:         opts.MaxStaleness,
+		negativeTTL:          opts.NegativeTTL,
+		weigher:              callbacks.Weigher,
+		onEvict:              callbacks.OnEvict,
 	}, nil
 }
 
 // Get returns a value from the cache by the provided key and type.
 func (c *LRUCache[K, V]) Get(key K) (value V, ok bool) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlockAndNotify()
 	return c.get(key, true)
 }
 
@@ -91,7 +241,7 @@ func (c *LRUCache[K, V]) Add(key K, value V) {
 }
 
 // AddWithTTL adds a value to the cache with the provided key, type, and TTL.
-// If the cache is full, the oldest entry will be removed.
+// If the cache is full (by the number of entries or by the total cost), the oldest entries will be removed.
 // Please note that expired entries are not removed immediately,
 // but only when they are accessed or during periodic cleanup (see RunPeriodicCleanup).
 // If the TTL is less than or equal to 0, the value will not expire.
@@ -102,14 +252,9 @@ func (c *LRUCache[K, V]) AddWithTTL(key K, value V, ttl time.Duration) {
 	}
 
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlockAndNotify()
 
-	if elem, ok := c.cache[key]; ok {
-		c.lruList.MoveToFront(elem)
-		elem.Value = &cacheEntry[K, V]{key: key, value: value, expiresAt: expiresAt}
-		return
-	}
-	c.addNew(key, value, expiresAt)
+	c.add(key, value, expiresAt)
 }
 
 // GetOrAdd returns a value from the cache by the provided key,
@@ -129,7 +274,7 @@ func (c *LRUCache[K, V]) GetOrAdd(key K, valueProvider func() V) (value V, exist
 // If the TTL is less than or equal to 0, the value will not expire.
 func (c *LRUCache[K, V]) GetOrAddWithTTL(key K, valueProvider func() V, ttl time.Duration) (value V, exists bool) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlockAndNotify()
 
 	if value, exists = c.get(key, true); exists {
 		return value, exists
@@ -140,7 +285,7 @@ func (c *LRUCache[K, V]) GetOrAddWithTTL(key K, valueProvider func() V, ttl time
 		expiresAt = time.Now().Add(ttl)
 	}
 	value = valueProvider()
-	c.addNew(key, value, expiresAt)
+	c.add(key, value, expiresAt) // The key may still exist if its value is stale (see Options.MaxStaleness).
 	return value, false
 }
 
@@ -149,7 +294,9 @@ func (c *LRUCache[K, V]) GetOrAddWithTTL(key K, valueProvider func() V, ttl time
 //
 // The new value is provided by the loadValue function, which is called only if the key does not exist.
 // The loadValue function returns the value and error.
-// If the loadValue function returns an error, the value will not be added to the cache.
+// If the loadValue function returns an error, the value will not be added to the cache
+// (but the error may be cached, see Options.NegativeTTL).
+// The value may also be refreshed in background (see Options.RefreshAhead and Options.MaxStaleness).
 //
 // Single flight pattern is used to prevent multiple concurrent calls for the same key.
 // If executing goroutine panics, other goroutines will receive PanicError.
@@ -169,8 +316,15 @@ func (c *LRUCache[K, V]) GetOrLoad(
 //
 // The new value is provided by the loadValue function, which is called only if the key does not exist.
 // The loadValue function returns the value, TTL, and error.
-// If the TTL is less than or equal to 0, the value will not expire.
-// If the loadValue function returns an error, the value will not be added to the cache.
+// If the TTL is less than or equal to 0, the default TTL is used.
+// If the loadValue function returns an error, the value will not be added to the cache
+// (but the error may be cached, see Options.NegativeTTL).
+//
+// If the value is about to expire (see Options.RefreshAhead) or is stale (see Options.MaxStaleness),
+// it's returned immediately, and a single background refresh is started.
+// If the background refresh fails, the current value is kept in the cache.
+// If the entry is changed or removed while the background refresh is in progress, the refreshed value is discarded.
+// Panics in the background refresh are recovered and treated as failures.
 //
 // Single flight pattern is used to prevent multiple concurrent calls for the same key.
 // If executing goroutine panics, other goroutines will receive PanicError.
@@ -181,10 +335,10 @@ func (c *LRUCache[K, V]) GetOrLoadWithTTL(
 ) (value V, exists bool, err error) {
 	// We have to use a separate function to get the value without modifying hits
 	// and misses metrics because of the single flight pattern and the double check.
-	get := func(key K) (value V, exists bool) {
+	get := func(key K) (value V, state loadEntryState, negErr error) {
 		c.mu.Lock()
-		defer c.mu.Unlock()
-		return c.get(key, false)
+		defer c.unlockAndNotify()
+		return c.getForLoad(key)
 	}
 
 	defer func() {
@@ -196,23 +350,30 @@ func (c *LRUCache[K, V]) GetOrLoadWithTTL(
 		}
 	}()
 
-	if val, ok := get(key); ok {
+	val, state, negErr := get(key)
+	if negErr != nil {
+		return value, false, negErr
+	}
+	switch state {
+	case loadEntryStateFresh:
+		return val, true, nil
+	case loadEntryStateNeedsRefresh:
+		c.refreshInBackground(key, loadValue)
 		return val, true, nil
 	}
 
 	result, doErr := c.sfGroup.Do(key, func() (singleFlightCallResult[V], error) {
-		if val, ok := get(key); ok { // double check after possible concurrent call
+		// double check after possible concurrent call
+		if val, state, negErr := get(key); negErr != nil {
+			return singleFlightCallResult[V]{}, negErr
+		} else if state != loadEntryStateMissing {
 			return singleFlightCallResult[V]{value: val, exists: true}, nil
 		}
-		val, ttl, valErr := loadValue(key)
+		val, valErr := c.load(key, loadValue)
 		if valErr != nil {
-			return singleFlightCallResult[V]{value: val, exists: false}, valErr
+			c.addNegative(key, valErr)
 		}
-		if ttl <= 0 {
-			ttl = c.defaultTTL
-		}
-		c.AddWithTTL(key, val, ttl)
-		return singleFlightCallResult[V]{value: val, exists: false}, nil
+		return singleFlightCallResult[V]{value: val, exists: false}, valErr
 	})
 	if doErr != nil {
 		return value, false, doErr
@@ -223,16 +384,17 @@ func (c *LRUCache[K, V]) GetOrLoadWithTTL(
 // Remove removes a value from the cache by the provided key and type.
 func (c *LRUCache[K, V]) Remove(key K) bool {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlockAndNotify()
+
+	delete(c.negative, key)
 
 	elem, ok := c.cache[key]
 	if !ok {
 		return false
 	}
 
-	c.lruList.Remove(elem)
-	delete(c.cache, key)
-	c.metricsCollector.SetAmount(len(c.cache))
+	c.removeElement(elem, EvictionReasonRemoved)
+	c.setSizeMetrics()
 	return true
 }
 
@@ -240,13 +402,22 @@ func (c *LRUCache[K, V]) Remove(key K) bool {
 // Keep in mind that this method does not reset the cache size
 // and does not reset Prometheus metrics except for the total number of entries.
 // All removed entries will not be counted as evictions.
+// OnEvict callback is called for all removed entries with EvictionReasonPurged reason.
 func (c *LRUCache[K, V]) Purge() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlockAndNotify()
 
-	c.metricsCollector.SetAmount(0)
+	if c.onEvict != nil {
+		for elem := c.lruList.Back(); elem != nil; elem = elem.Prev() {
+			entry := elem.Value.(*cacheEntry[K, V])
+			c.evicted = append(c.evicted, evictedEntry[K, V]{key: entry.key, value: entry.value, reason: EvictionReasonPurged})
+		}
+	}
 	c.cache = make(map[K]*list.Element)
+	c.negative = make(map[K]negativeEntry)
 	c.lruList.Init()
+	c.cost = 0
+	c.setSizeMetrics()
 }
 
 // Resize changes the cache size and returns the number of evicted entries.
@@ -256,19 +427,24 @@ func (c *LRUCache[K, V]) Resize(size int) (evicted int) {
 	}
 
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlockAndNotify()
 
 	c.maxEntries = size
-	evicted = len(c.cache) - size
-	if evicted <= 0 {
-		return
-	}
-	for i := 0; i < evicted; i++ {
-		_ = c.removeOldest()
+	return c.evictOverflow(EvictionReasonResized)
+}
+
+// ResizeCost changes the maximum total cost of the cache entries and returns the number of evicted entries.
+// The least recently used entries are evicted until the total cost is under the new limit.
+func (c *LRUCache[K, V]) ResizeCost(maxCost int64) (evicted int) {
+	if maxCost <= 0 {
+		return 0
 	}
-	c.metricsCollector.SetAmount(len(c.cache))
-	c.metricsCollector.AddEvictions(evicted)
-	return evicted
+
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+
+	c.maxCost = maxCost
+	return c.evictOverflow(EvictionReasonResized)
 }
 
 // Len returns the number of items in the cache.
@@ -278,6 +454,13 @@ func (c *LRUCache[K, V]) Len() int {
 	return len(c.cache)
 }
 
+// Cost returns the total cost of items in the cache.
+func (c *LRUCache[K, V]) Cost() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cost
+}
+
 func (c *LRUCache[K, V]) get(key K, incHitsAndMisses bool) (value V, ok bool) {
 	elem, hit := c.cache[key]
 	if !hit {
@@ -287,10 +470,11 @@ func (c *LRUCache[K, V]) get(key K, incHitsAndMisses bool) (value V, ok bool) {
 		return value, false
 	}
 	entry := elem.Value.(*cacheEntry[K, V])
-	if !entry.expiresAt.IsZero() && entry.expiresAt.Before(time.Now()) {
-		c.lruList.Remove(elem)
-		delete(c.cache, key)
-		c.metricsCollector.SetAmount(len(c.cache))
+	if now := time.Now(); c.isExpired(entry, now) {
+		if !c.isStale(entry, now) {
+			c.removeElement(elem, EvictionReasonExpired)
+			c.setSizeMetrics()
+		}
 		if incHitsAndMisses {
 			c.metricsCollector.IncMisses()
 		}
@@ -303,30 +487,102 @@ func (c *LRUCache[K, V]) get(key K, incHitsAndMisses bool) (value V, ok bool) {
 	return entry.value, true
 }
 
-func (c *LRUCache[K, V]) addNew(key K, value V, expiresAt time.Time) {
-	c.cache[key] = c.lruList.PushFront(&cacheEntry[K, V]{key: key, value: value, expiresAt: expiresAt})
-	if len(c.cache) <= c.maxEntries {
-		c.metricsCollector.SetAmount(len(c.cache))
-		return
+func (c *LRUCache[K, V]) add(key K, value V, expiresAt time.Time) {
+	delete(c.negative, key)
+	c.lastVersion++
+	entry := &cacheEntry[K, V]{
+		key: key, value: value, expiresAt: expiresAt, cost: c.weigh(key, value), version: c.lastVersion,
 	}
-	if evictedEntry := c.removeOldest(); evictedEntry != nil {
+	if c.maxCost > 0 && entry.cost > c.maxCost {
+		// The entry never fits the cache, so only it (and the previous value for the key) is evicted.
+		if elem, ok := c.cache[key]; ok {
+			c.removeElement(elem, EvictionReasonReplaced)
+		}
+		c.addEvicted(entry, EvictionReasonCapacity)
+		c.setSizeMetrics()
 		c.metricsCollector.AddEvictions(1)
+		return
+	}
+	if elem, ok := c.cache[key]; ok {
+		c.lruList.MoveToFront(elem)
+		oldEntry := elem.Value.(*cacheEntry[K, V])
+		c.cost += entry.cost - oldEntry.cost
+		c.addEvicted(oldEntry, EvictionReasonReplaced)
+		elem.Value = entry
+	} else {
+		c.cache[key] = c.lruList.PushFront(entry)
+		c.cost += entry.cost
 	}
+	c.evictOverflow(EvictionReasonCapacity)
 }
 
-func (c *LRUCache[K, V]) removeOldest() *cacheEntry[K, V] {
-	elem := c.lruList.Back()
-	if elem == nil {
-		return nil
+func (c *LRUCache[K, V]) isExpired(entry *cacheEntry[K, V], now time.Time) bool {
+	return !entry.expiresAt.IsZero() && entry.expiresAt.Before(now)
+}
+
+// isStale returns true if the entry is expired, but still can be returned by GetOrLoad (see Options.MaxStaleness).
+func (c *LRUCache[K, V]) isStale(entry *cacheEntry[K, V], now time.Time) bool {
+	return c.isExpired(entry, now) && !entry.expiresAt.Add(c.maxStaleness).Before(now)
+}
+
+func (c *LRUCache[K, V]) weigh(key K, value V) int64 {
+	return max(c.weigher(key, value), 0)
+}
+
+func (c *LRUCache[K, V]) isOverflowed() bool {
+	return (c.maxEntries > 0 && len(c.cache) > c.maxEntries) || (c.maxCost > 0 && c.cost > c.maxCost)
+}
+
+// evictOverflow removes the oldest entries until the cache fits the limits and updates metrics.
+func (c *LRUCache[K, V]) evictOverflow(reason EvictionReason) (evicted int) {
+	for c.isOverflowed() {
+		elem := c.lruList.Back()
+		if elem == nil {
+			break
+		}
+		c.removeElement(elem, reason)
+		evicted++
+	}
+	c.setSizeMetrics()
+	if evicted > 0 {
+		c.metricsCollector.AddEvictions(evicted)
 	}
+	return evicted
+}
+
+func (c *LRUCache[K, V]) removeElement(elem *list.Element, reason EvictionReason) {
 	c.lruList.Remove(elem)
 	entry := elem.Value.(*cacheEntry[K, V])
 	delete(c.cache, entry.key)
-	return entry
+	c.cost -= entry.cost
+	c.addEvicted(entry, reason)
+}
+
+// addEvicted remembers the removed entry for calling OnEvict callback after unlocking.
+func (c *LRUCache[K, V]) addEvicted(entry *cacheEntry[K, V], reason EvictionReason) {
+	if c.onEvict != nil {
+		c.evicted = append(c.evicted, evictedEntry[K, V]{key: entry.key, value: entry.value, reason: reason})
+	}
+}
+
+// unlockAndNotify releases the lock and calls OnEvict callback for the entries removed under the lock.
+func (c *LRUCache[K, V]) unlockAndNotify() {
+	evicted := c.evicted
+	c.evicted = nil
+	c.mu.Unlock()
+	for _, entry := range evicted {
+		c.onEvict(entry.key, entry.value, entry.reason)
+	}
+}
+
+func (c *LRUCache[K, V]) setSizeMetrics() {
+	c.metricsCollector.SetAmount(len(c.cache))
+	c.costMetricsCollector.SetCost(c.cost)
 }
 
 // RunPeriodicCleanup runs a cycle of periodic cleanup of expired entries.
 // Entries without expiration time are not affected.
+// OnEvict callback is called for removed entries with EvictionReasonExpired reason in this goroutine.
 // It's supposed to be run in a separate goroutine.
 func (c *LRUCache[K, V]) RunPeriodicCleanup(ctx context.Context, cleanupInterval time.Duration) {
 	ticker := time.NewTicker(cleanupInterval)
@@ -337,17 +593,151 @@ func (c *LRUCache[K, V]) RunPeriodicCleanup(ctx context.Context, cleanupInterval
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			now := time.Now()
+			c.removeExpired(time.Now())
+		}
+	}
+}
+
+func (c *LRUCache[K, V]) removeExpired(now time.Time) {
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+
+	for _, elem := range c.cache {
+		entry := elem.Value.(*cacheEntry[K, V])
+		if c.isExpired(entry, now) && !c.isStale(entry, now) {
+			c.removeElement(elem, EvictionReasonExpired)
+		}
+	}
+	for key, negEntry := range c.negative {
+		if negEntry.expiresAt.Before(now) {
+			delete(c.negative, key)
+		}
+	}
+	c.setSizeMetrics()
+}
+
+type loadEntryState int
+
+const (
+	loadEntryStateMissing loadEntryState = iota
+	loadEntryStateFresh
+	loadEntryStateNeedsRefresh
+)
+
+// getForLoad returns the value for GetOrLoadWithTTL method and the state of the entry.
+// If the loadValue function error is cached for the key, it's returned.
+func (c *LRUCache[K, V]) getForLoad(key K) (value V, state loadEntryState, negErr error) {
+	now := time.Now()
+	if elem, ok := c.cache[key]; ok {
+		entry := elem.Value.(*cacheEntry[K, V])
+		switch {
+		case entry.expiresAt.IsZero() || entry.expiresAt.Add(-c.refreshAhead).After(now):
+			c.lruList.MoveToFront(elem)
+			return entry.value, loadEntryStateFresh, nil
+		case !c.isExpired(entry, now) || c.isStale(entry, now):
+			c.lruList.MoveToFront(elem)
+			return entry.value, loadEntryStateNeedsRefresh, nil
+		}
+		c.removeElement(elem, EvictionReasonExpired)
+		c.setSizeMetrics()
+	}
+
+	if negEntry, ok := c.negative[key]; ok {
+		if !negEntry.expiresAt.Before(now) {
+			return value, loadEntryStateMissing, negEntry.err
+		}
+		delete(c.negative, key)
+	}
+	return value, loadEntryStateMissing, nil
+}
+
+func (c *LRUCache[K, V]) load(key K, loadValue func(K) (value V, ttl time.Duration, err error)) (V, error) {
+	val, ttl, err := loadValue(key)
+	if err != nil {
+		return val, err
+	}
+	if ttl <= 0 {
+		ttl = c.defaultTTL
+	}
+	c.AddWithTTL(key, val, ttl)
+	return val, nil
+}
+
+func (c *LRUCache[K, V]) addNegative(key K, err error) {
+	if c.negativeTTL <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Number of cached errors is limited to prevent unbounded growth.
+	limit := c.maxEntries
+	if limit <= 0 {
+		limit = maxNegativeEntriesWithoutEntriesLimit
+	}
+	if len(c.negative) >= limit {
+		return
+	}
+	c.negative[key] = negativeEntry{err: err, expiresAt: time.Now().Add(c.negativeTTL)}
+}
+
+// refreshInBackground starts loading of the new value in a separate goroutine
+// if there is no another background refresh for the same key.
+func (c *LRUCache[K, V]) refreshInBackground(key K, loadValue func(K) (value V, ttl time.Duration, err error)) {
+	c.mu.Lock()
+	elem, ok := c.cache[key]
+	if !ok {
+		c.mu.Unlock()
+		return // The entry is removed concurrently, so there is nothing to refresh.
+	}
+	if _, ok = c.refreshing[key]; ok {
+		c.mu.Unlock()
+		return
+	}
+	version := elem.Value.(*cacheEntry[K, V]).version
+	c.refreshing[key] = struct{}{}
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			_ = recover() // Panic in loadValue is treated as a failed refresh.
 			c.mu.Lock()
-			for key, elem := range c.cache {
-				entry := elem.Value.(*cacheEntry[K, V])
-				if !entry.expiresAt.IsZero() && entry.expiresAt.Before(now) {
-					c.lruList.Remove(elem)
-					delete(c.cache, key)
-				}
-			}
-			c.metricsCollector.SetAmount(len(c.cache))
+			delete(c.refreshing, key)
 			c.mu.Unlock()
-		}
+		}()
+		// Single flight group is used to not load the value concurrently with GetOrLoadWithTTL for missing key.
+		_, _ = c.sfGroup.Do(key, func() (singleFlightCallResult[V], error) {
+			val, err := c.refresh(key, version, loadValue)
+			return singleFlightCallResult[V]{value: val, exists: false}, err
+		})
+	}()
+}
+
+// refresh loads the new value and replaces the current one only if the entry is not changed (re-added, removed,
+// purged, etc.) since the refresh was started. Otherwise, the loaded value is discarded.
+func (c *LRUCache[K, V]) refresh(
+	key K, version uint64, loadValue func(K) (value V, ttl time.Duration, err error),
+) (V, error) {
+	val, ttl, err := loadValue(key)
+	if err != nil {
+		return val, err
+	}
+	if ttl <= 0 {
+		ttl = c.defaultTTL
+	}
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+
+	if elem, ok := c.cache[key]; !ok || elem.Value.(*cacheEntry[K, V]).version != version {
+		c.addEvicted(&cacheEntry[K, V]{key: key, value: val}, EvictionReasonReplaced)
+		return val, nil
 	}
+	c.add(key, val, expiresAt)
+	return val, nil
 }
@@ -304,12 +304,165 @@ func TestLRUCache_TTL(t *testing.T) {
 	}
 }
 
+func TestLRUCache_MaxCost(t *testing.T) {
+	metrics := NewPrometheusMetrics()
+	cache, err := NewWithCallbacks[string, string](0, metrics, Options{MaxCost: 10}, Callbacks[string, string]{
+		Weigher: func(key string, value string) int64 { return int64(len(value)) },
+	})
+	require.NoError(t, err)
+
+	cache.Add("key1", "aaa")
+	cache.Add("key2", "bbbb")
+	cache.Add("key3", "ccc")
+	require.Equal(t, 3, cache.Len())
+	require.Equal(t, int64(10), cache.Cost())
+
+	_, found := cache.Get("key1") // key1 becomes the most recently used.
+	require.True(t, found)
+
+	// key2 (4) is evicted to fit the new entry (2).
+	cache.Add("key4", "dd")
+	require.Equal(t, int64(8), cache.Cost())
+	_, found = cache.Get("key2")
+	require.False(t, found)
+
+	// Updating the entry changes the total cost, key3 and key1 are evicted.
+	cache.Add("key4", "dddddddd")
+	require.Equal(t, 1, cache.Len())
+	require.Equal(t, int64(8), cache.Cost())
+	assertPrometheusMetrics(t, expectedMetrics{EntriesAmount: 1, HitsTotal: 1, MissesTotal: 1, EvictionsTotal: 3}, metrics)
+	require.Equal(t, 8, int(testutil.ToFloat64(metrics.EntriesCost.With(nil))))
+
+	// The entry that is more expensive than the limit is not kept, other entries are not affected.
+	cache.Add("key5", "eeeeeeeeeee")
+	require.Equal(t, 1, cache.Len())
+	require.Equal(t, int64(8), cache.Cost())
+	_, found = cache.Get("key5")
+	require.False(t, found)
+	_, found = cache.Get("key4")
+	require.True(t, found)
+
+	// Replacing the value with too expensive one removes the entry.
+	cache.Add("key4", "ddddddddddd")
+	require.Equal(t, 0, cache.Len())
+	require.Equal(t, int64(0), cache.Cost())
+
+	cache.Add("key6", "ffff")
+	cache.Add("key7", "gggg")
+	require.Equal(t, 0, cache.ResizeCost(0))
+	require.Equal(t, 1, cache.ResizeCost(5))
+	_, found = cache.Get("key7")
+	require.True(t, found)
+	require.Equal(t, 4, int(testutil.ToFloat64(metrics.EntriesCost.With(nil))))
+
+	cache.Remove("key7")
+	require.Equal(t, int64(0), cache.Cost())
+	require.Equal(t, 0, int(testutil.ToFloat64(metrics.EntriesCost.With(nil))))
+
+	// Both limits are applied.
+	cache, err = NewWithOpts[string, string](2, nil, Options{MaxCost: 100})
+	require.NoError(t, err)
+	for _, key := range []string{"key1", "key2", "key3"} {
+		cache.Add(key, key)
+	}
+	require.Equal(t, 2, cache.Len())
+	require.Equal(t, int64(2), cache.Cost()) // Each entry costs 1 by default.
+
+	// Adding the entry that is more expensive than the limit doesn't evict other entries.
+	intCache, err := NewWithCallbacks[string, int](0, nil, Options{MaxCost: 10}, Callbacks[string, int]{
+		Weigher: func(key string, value int) int64 { return int64(value) },
+	})
+	require.NoError(t, err)
+	intCache.Add("a", 3)
+	intCache.Add("b", 3)
+	intCache.Add("c", 3)
+	intCache.Add("huge", 100)
+	require.Equal(t, 3, intCache.Len())
+	require.Equal(t, int64(9), intCache.Cost())
+	_, found = intCache.Get("huge")
+	require.False(t, found)
+
+	_, err = NewWithOpts[string, string](0, nil, Options{})
+	require.EqualError(t, err, "maxEntries must be greater than 0")
+	_, err = NewWithOpts[string, string](10, nil, Options{MaxCost: -1})
+	require.EqualError(t, err, "maxCost must be greater or equal to 0 (no cost limit)")
+}
+
+func TestLRUCache_OnEvict(t *testing.T) {
+	type evictedEntry struct {
+		key    string
+		value  int
+		reason EvictionReason
+	}
+	var evicted []evictedEntry
+	var cache *LRUCache[string, int]
+	cache, err := NewWithCallbacks[string, int](3, nil, Options{}, Callbacks[string, int]{
+		OnEvict: func(key string, value int, reason EvictionReason) {
+			evicted = append(evicted, evictedEntry{key, value, reason})
+			cache.Len() // The callback is called outside the lock, so there is no deadlock.
+		},
+	})
+	require.NoError(t, err)
+
+	popEvicted := func() []evictedEntry {
+		res := evicted
+		evicted = nil
+		return res
+	}
+
+	cache.Add("key1", 1)
+	cache.Add("key2", 2)
+	cache.Add("key3", 3)
+	require.Empty(t, popEvicted())
+	cache.Add("key1", 10)
+	require.Equal(t, []evictedEntry{{"key1", 1, EvictionReasonReplaced}}, popEvicted())
+
+	cache.Add("key4", 4)
+	require.Equal(t, []evictedEntry{{"key2", 2, EvictionReasonCapacity}}, popEvicted())
+
+	require.True(t, cache.Remove("key3"))
+	require.False(t, cache.Remove("key3"))
+	require.Equal(t, []evictedEntry{{"key3", 3, EvictionReasonRemoved}}, popEvicted())
+
+	cache.AddWithTTL("key5", 5, time.Millisecond)
+	require.Equal(t, 1, cache.Resize(2))
+	require.Equal(t, []evictedEntry{{"key1", 10, EvictionReasonResized}}, popEvicted())
+
+	time.Sleep(time.Millisecond * 5)
+	_, found := cache.Get("key5")
+	require.False(t, found)
+	require.Equal(t, []evictedEntry{{"key5", 5, EvictionReasonExpired}}, popEvicted())
+
+	cache.Add("key6", 6)
+	cache.Purge()
+	require.Equal(t, []evictedEntry{{"key4", 4, EvictionReasonPurged}, {"key6", 6, EvictionReasonPurged}}, popEvicted())
+	require.Equal(t, 0, cache.Len())
+
+	require.Equal(t, "capacity", EvictionReasonCapacity.String())
+	require.Equal(t, "expired", EvictionReasonExpired.String())
+	require.Equal(t, "removed", EvictionReasonRemoved.String())
+	require.Equal(t, "purged", EvictionReasonPurged.String())
+	require.Equal(t, "resized", EvictionReasonResized.String())
+	require.Equal(t, "replaced", EvictionReasonReplaced.String())
+}
+
 func TestLRUCache_PeriodicCleanup(t *testing.T) {
 	const ttl = 100 * time.Millisecond
 
 	// We'll create a short-lived item but never manually Get it.
 	// We'll rely on periodic cleanup to remove it from the cache.
-	cache, err := New[string, string](10, nil)
+	// OnEvict is called in the cleanup goroutine, so reasons are checked in the test goroutine.
+	var evictedKeys []string
+	var evictedReasons []EvictionReason
+	var evictedMu sync.Mutex
+	cache, err := NewWithCallbacks[string, string](10, nil, Options{}, Callbacks[string, string]{
+		OnEvict: func(key string, value string, reason EvictionReason) {
+			evictedMu.Lock()
+			evictedKeys = append(evictedKeys, key)
+			evictedReasons = append(evictedReasons, reason)
+			evictedMu.Unlock()
+		},
+	})
 	require.NoError(t, err)
 
 	// Start periodic cleanup
@@ -338,6 +491,14 @@ func TestLRUCache_PeriodicCleanup(t *testing.T) {
 
 	// The item should be removed by periodic cleanup
 	require.Equal(t, 1, cache.Len())
+	require.Eventually(t, func() bool {
+		evictedMu.Lock()
+		defer evictedMu.Unlock()
+		return len(evictedKeys) == 1 && evictedKeys[0] == key1
+	}, time.Second, ttl/10)
+	evictedMu.Lock()
+	require.Equal(t, []EvictionReason{EvictionReasonExpired}, evictedReasons)
+	evictedMu.Unlock()
 	_, found = cache.Get(key1)
 	require.False(t, found)
 	_, found = cache.Get(key2)
@@ -538,6 +699,252 @@ type getOrLoadResult[V any] struct {
 	err    error
 }
 
+func TestLRUCache_GetOrLoadWithTTL_BackgroundRefresh(t *testing.T) {
+	const ttl = 50 * time.Millisecond
+
+	newLoader := func(values ...string) (func(key string) (string, time.Duration, error), *atomic.Int32, chan struct{}) {
+		var calls atomic.Int32
+		release := make(chan struct{}, len(values))
+		return func(key string) (string, time.Duration, error) {
+			n := int(calls.Add(1))
+			if n > 1 {
+				<-release
+			}
+			if values[n-1] == "" {
+				return "", 0, errors.New("load error")
+			}
+			return values[n-1], ttl, nil
+		}, &calls, release
+	}
+
+	t.Run("stale while revalidate", func(t *testing.T) {
+		metrics := NewPrometheusMetrics()
+		cache, err := NewWithOpts[string, string](10, metrics, Options{MaxStaleness: time.Minute})
+		require.NoError(t, err)
+		load, calls, release := newLoader("v1", "", "v2")
+
+		val, exists, err := cache.GetOrLoadWithTTL("key", load)
+		require.NoError(t, err)
+		require.False(t, exists)
+		require.Equal(t, "v1", val)
+
+		time.Sleep(ttl * 2)
+
+		// Get doesn't return stale values, but keeps them in the cache.
+		_, found := cache.Get("key")
+		require.False(t, found)
+		require.Equal(t, 1, cache.Len())
+
+		// Stale value is returned, single refresh is started in background.
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, e, loadErr := cache.GetOrLoadWithTTL("key", load)
+				assert.NoError(t, loadErr)
+				assert.True(t, e)
+				assert.Equal(t, "v1", v)
+			}()
+		}
+		wg.Wait()
+		require.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, time.Millisecond*10)
+		assertPrometheusMetrics(t, expectedMetrics{EntriesAmount: 1, HitsTotal: 10, MissesTotal: 2}, metrics)
+
+		// Failed refresh keeps the stale value.
+		release <- struct{}{}
+		require.Eventually(t, func() bool {
+			cache.mu.RLock()
+			defer cache.mu.RUnlock()
+			return len(cache.refreshing) == 0
+		}, time.Second, time.Millisecond*10)
+		val, exists, err = cache.GetOrLoadWithTTL("key", load)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, "v1", val)
+
+		release <- struct{}{}
+		require.Eventually(t, func() bool {
+			v, ok := cache.Get("key")
+			return ok && v == "v2"
+		}, time.Second, time.Millisecond*10)
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("max staleness is exceeded", func(t *testing.T) {
+		cache, err := NewWithOpts[string, string](10, nil, Options{MaxStaleness: ttl})
+		require.NoError(t, err)
+		load, calls, release := newLoader("v1", "v2")
+		release <- struct{}{}
+
+		_, _, err = cache.GetOrLoadWithTTL("key", load)
+		require.NoError(t, err)
+
+		time.Sleep(ttl * 3)
+
+		val, exists, err := cache.GetOrLoadWithTTL("key", load)
+		require.NoError(t, err)
+		require.False(t, exists)
+		require.Equal(t, "v2", val)
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("refresh ahead", func(t *testing.T) {
+		cache, err := NewWithOpts[string, string](10, nil, Options{RefreshAhead: ttl / 2})
+		require.NoError(t, err)
+		load, calls, release := newLoader("v1", "v2")
+		release <- struct{}{}
+
+		loadedAt := time.Now()
+		_, _, err = cache.GetOrLoadWithTTL("key", load)
+		require.NoError(t, err)
+
+		// Not in the refresh-ahead window yet.
+		val, exists, err := cache.GetOrLoadWithTTL("key", load)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, "v1", val)
+		require.Equal(t, int32(1), calls.Load())
+
+		time.Sleep(ttl*3/4 - time.Since(loadedAt))
+
+		val, exists, err = cache.GetOrLoadWithTTL("key", load)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.Equal(t, "v1", val)
+		require.Eventually(t, func() bool {
+			v, ok := cache.Get("key")
+			return ok && v == "v2"
+		}, time.Second, time.Millisecond*10)
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("refresh doesn't overwrite concurrent changes", func(t *testing.T) {
+		var evicted []string
+		var evictedMu sync.Mutex
+		cache, err := NewWithCallbacks[string, string](10, nil, Options{MaxStaleness: time.Minute}, Callbacks[string, string]{
+			OnEvict: func(key string, value string, reason EvictionReason) {
+				evictedMu.Lock()
+				defer evictedMu.Unlock()
+				evicted = append(evicted, value+":"+reason.String())
+			},
+		})
+		require.NoError(t, err)
+		waitRefresh := func() {
+			require.Eventually(t, func() bool {
+				cache.mu.RLock()
+				defer cache.mu.RUnlock()
+				return len(cache.refreshing) == 0
+			}, time.Second, time.Millisecond*10)
+		}
+
+		// Removed key is not restored by the refresh.
+		load, _, release := newLoader("v1", "v2")
+		_, _, err = cache.GetOrLoadWithTTL("key1", load)
+		require.NoError(t, err)
+		time.Sleep(ttl * 2)
+		_, exists, err := cache.GetOrLoadWithTTL("key1", load)
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.True(t, cache.Remove("key1"))
+		release <- struct{}{}
+		waitRefresh()
+		require.Equal(t, 0, cache.Len())
+
+		// Value added explicitly is not overwritten by the refresh.
+		load, _, release = newLoader("v1", "v2")
+		_, _, err = cache.GetOrLoadWithTTL("key2", load)
+		require.NoError(t, err)
+		time.Sleep(ttl * 2)
+		_, exists, err = cache.GetOrLoadWithTTL("key2", load)
+		require.NoError(t, err)
+		require.True(t, exists)
+		cache.Add("key2", "added")
+		release <- struct{}{}
+		waitRefresh()
+		val, found := cache.Get("key2")
+		require.True(t, found)
+		require.Equal(t, "added", val)
+
+		evictedMu.Lock()
+		defer evictedMu.Unlock()
+		require.Equal(t, []string{"v1:removed", "v2:replaced", "v1:replaced", "v2:replaced"}, evicted)
+	})
+
+	t.Run("periodic cleanup keeps stale entries", func(t *testing.T) {
+		cache, err := NewWithOpts[string, string](10, nil, Options{MaxStaleness: ttl * 2})
+		require.NoError(t, err)
+		cache.AddWithTTL("key", "v1", ttl)
+
+		cache.removeExpired(time.Now().Add(ttl * 2))
+		require.Equal(t, 1, cache.Len())
+		cache.removeExpired(time.Now().Add(ttl * 4))
+		require.Equal(t, 0, cache.Len())
+	})
+}
+
+func TestLRUCache_GetOrLoad_NegativeCaching(t *testing.T) {
+	const negativeTTL = 50 * time.Millisecond
+	cache, err := NewWithOpts[string, int](10, nil, Options{NegativeTTL: negativeTTL})
+	require.NoError(t, err)
+
+	var calls int
+	loadErr := errors.New("load error")
+	load := func(key string) (int, error) {
+		calls++
+		return 0, loadErr
+	}
+
+	for i := 0; i < 3; i++ {
+		_, exists, err := cache.GetOrLoad("key", load)
+		require.ErrorIs(t, err, loadErr)
+		require.False(t, exists)
+	}
+	require.Equal(t, 1, calls)
+	require.Equal(t, 0, cache.Len())
+
+	// Cached error is expired.
+	time.Sleep(negativeTTL * 2)
+	_, _, err = cache.GetOrLoad("key", load)
+	require.ErrorIs(t, err, loadErr)
+	require.Equal(t, 2, calls)
+
+	// Remove drops the cached error.
+	cache.Remove("key")
+	_, _, err = cache.GetOrLoad("key", load)
+	require.ErrorIs(t, err, loadErr)
+	require.Equal(t, 3, calls)
+
+	// Adding the value drops the cached error too.
+	cache.Add("key", 42)
+	cache.Remove("key")
+	val, exists, err := cache.GetOrLoad("key", func(key string) (int, error) { return 43, nil })
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.Equal(t, 43, val)
+
+	// Number of cached errors is limited even if the cache is bounded only by the total cost.
+	costCache, err := NewWithOpts[int, int](0, nil, Options{MaxCost: 10, NegativeTTL: time.Minute})
+	require.NoError(t, err)
+	for i := 0; i < maxNegativeEntriesWithoutEntriesLimit+10; i++ {
+		_, _, err = costCache.GetOrLoad(i, func(key int) (int, error) { return 0, loadErr })
+		require.ErrorIs(t, err, loadErr)
+	}
+	require.Len(t, costCache.negative, maxNegativeEntriesWithoutEntriesLimit)
+
+	for _, opts := range []struct {
+		opts       Options
+		wantErrMsg string
+	}{
+		{Options{RefreshAhead: -1}, "refreshAhead must be greater or equal to 0 (no refresh ahead)"},
+		{Options{MaxStaleness: -1}, "maxStaleness must be greater or equal to 0 (no stale values)"},
+		{Options{NegativeTTL: -1}, "negativeTTL must be greater or equal to 0 (no errors caching)"},
+	} {
+		_, err = NewWithOpts[string, int](10, nil, opts.opts)
+		require.EqualError(t, err, opts.wantErrMsg)
+	}
+}
+
 type User struct {
 	ID   string
 	Name string
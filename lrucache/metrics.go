@@ -27,6 +27,13 @@ type MetricsCollector interface {
 	AddEvictions(int)
 }
 
+// CostMetricsCollector represents a collector of the total cost of the cache entries.
+// If MetricsCollector passed to the cache also implements this interface, the total cost is reported.
+type CostMetricsCollector interface {
+	// SetCost sets the total cost of entries in the cache.
+	SetCost(int64)
+}
+
 // PrometheusMetricsOpts represents options for PrometheusMetrics.
 type PrometheusMetricsOpts struct {
 	// Namespace is a namespace for metrics. It will be prepended to all metric names.
@@ -46,6 +53,7 @@ type PrometheusMetricsOpts struct {
 // PrometheusMetrics represents a Prometheus metrics for the cache.
 type PrometheusMetrics struct {
 	EntriesAmount  *prometheus.GaugeVec
+	EntriesCost    *prometheus.GaugeVec
 	HitsTotal      *prometheus.CounterVec
 	MissesTotal    *prometheus.CounterVec
 	EvictionsTotal *prometheus.CounterVec
@@ -68,6 +76,16 @@ func NewPrometheusMetricsWithOpts(opts PrometheusMetricsOpts) *PrometheusMetrics
 		opts.CurriedLabelNames,
 	)
 
+	entriesCost := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "cache_entries_cost",
+			Help:        "Total cost of entries in the cache.",
+			ConstLabels: libinfo.AddPrometheusLibVersionLabel(opts.ConstLabels),
+		},
+		opts.CurriedLabelNames,
+	)
+
 	hitsTotal := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace:   opts.Namespace,
@@ -100,6 +118,7 @@ func NewPrometheusMetricsWithOpts(opts PrometheusMetricsOpts) *PrometheusMetrics
 
 	return &PrometheusMetrics{
 		EntriesAmount:  entriesAmount,
+		EntriesCost:    entriesCost,
 		HitsTotal:      hitsTotal,
 		MissesTotal:    missesTotal,
 		EvictionsTotal: evictionsTotal,
@@ -110,6 +129,7 @@ func NewPrometheusMetricsWithOpts(opts PrometheusMetricsOpts) *PrometheusMetrics
 func (pm *PrometheusMetrics) MustCurryWith(labels prometheus.Labels) *PrometheusMetrics {
 	return &PrometheusMetrics{
 		EntriesAmount:  pm.EntriesAmount.MustCurryWith(labels),
+		EntriesCost:    pm.EntriesCost.MustCurryWith(labels),
 		HitsTotal:      pm.HitsTotal.MustCurryWith(labels),
 		MissesTotal:    pm.MissesTotal.MustCurryWith(labels),
 		EvictionsTotal: pm.EvictionsTotal.MustCurryWith(labels),
@@ -120,6 +140,7 @@ func (pm *PrometheusMetrics) MustCurryWith(labels prometheus.Labels) *Prometheus
 func (pm *PrometheusMetrics) MustRegister() {
 	prometheus.MustRegister(
 		pm.EntriesAmount,
+		pm.EntriesCost,
 		pm.HitsTotal,
 		pm.MissesTotal,
 		pm.EvictionsTotal,
@@ -129,6 +150,7 @@ func (pm *PrometheusMetrics) MustRegister() {
 // Unregister cancels registration of metrics collector in Prometheus.
 func (pm *PrometheusMetrics) Unregister() {
 	prometheus.Unregister(pm.EntriesAmount)
+	prometheus.Unregister(pm.EntriesCost)
 	prometheus.Unregister(pm.HitsTotal)
 	prometheus.Unregister(pm.MissesTotal)
 	prometheus.Unregister(pm.EvictionsTotal)
@@ -139,6 +161,11 @@ func (pm *PrometheusMetrics) SetAmount(amount int) {
 	pm.EntriesAmount.With(nil).Set(float64(amount))
 }
 
+// SetCost sets the total cost of entries in the cache.
+func (pm *PrometheusMetrics) SetCost(cost int64) {
+	pm.EntriesCost.With(nil).Set(float64(cost))
+}
+
 // IncHits increments the total number of successfully found keys in the cache.
 func (pm *PrometheusMetrics) IncHits() {
 	pm.HitsTotal.With(nil).Inc()
@@ -157,6 +184,7 @@ func (pm *PrometheusMetrics) AddEvictions(n int) {
 type disabledMetrics struct{}
 
 func (disabledMetrics) SetAmount(int)    {}
+func (disabledMetrics) SetCost(int64)    {}
 func (disabledMetrics) IncHits()         {}
 func (disabledMetrics) IncMisses()       {}
 func (disabledMetrics) AddEvictions(int) {}
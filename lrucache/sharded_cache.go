@@ -0,0 +1,311 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package lrucache
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultShardsNum is the default number of shards in ShardedLRUCache.
+const DefaultShardsNum = 16
+
+// ShardedOptions represents options for the sharded cache.
+type ShardedOptions[K comparable] struct {
+	Options
+
+	// ShardsNum is the number of shards. Each shard is an independent LRUCache with its own lock.
+	// By default, DefaultShardsNum is used (or maxEntries/MaxCost if it's less).
+	ShardsNum int
+
+	// Hash is used for choosing the shard for the key.
+	// By default, the key is hashed with hash/maphash using a random seed.
+	Hash func(key K) uint64
+}
+
+// ShardedLRUCache represents an LRU cache that is split into several independent shards to reduce lock contention.
+// It has the same API as LRUCache.
+// Keep in mind that LRU eviction is per shard, so the least recently used entry of the whole cache
+// is not necessarily evicted first.
+type ShardedLRUCache[K comparable, V any] struct {
+	shards []*LRUCache[K, V]
+	hash   func(key K) uint64
+}
+
+// NewSharded creates a new ShardedLRUCache with the provided maximum number of entries and metrics collector.
+func NewSharded[K comparable, V any](maxEntries int, metricsCollector MetricsCollector) (*ShardedLRUCache[K, V], error) {
+	return NewShardedWithOpts[K, V](maxEntries, metricsCollector, ShardedOptions[K]{})
+}
+
+// NewShardedWithOpts creates a new ShardedLRUCache with the provided maximum number of entries, metrics collector, and options.
+// The maximum number of entries and the maximum total cost are distributed evenly between shards,
+// so an entry that costs more than the shard's limit (MaxCost/ShardsNum) is evicted right after adding.
+// Metrics collector is shared by all shards, and the total number of entries (and their total cost) in all shards is reported.
+// It can be nil, in this case, metrics will be disabled.
+func NewShardedWithOpts[K comparable, V any](
+	maxEntries int, metricsCollector MetricsCollector, opts ShardedOptions[K],
+) (*ShardedLRUCache[K, V], error) {
+	return NewShardedWithCallbacks[K, V](maxEntries, metricsCollector, opts, Callbacks[K, V]{})
+}
+
+// NewShardedWithCallbacks is a version of NewShardedWithOpts that allows specifying typed callbacks
+// (weigher and eviction callback). Callbacks are shared by all shards.
+func NewShardedWithCallbacks[K comparable, V any](
+	maxEntries int, metricsCollector MetricsCollector, opts ShardedOptions[K], callbacks Callbacks[K, V],
+) (*ShardedLRUCache[K, V], error) {
+	if maxEntries < 0 || (maxEntries == 0 && opts.MaxCost == 0) {
+		return nil, fmt.Errorf("maxEntries must be greater than 0")
+	}
+	if opts.MaxCost < 0 {
+		return nil, fmt.Errorf("maxCost must be greater or equal to 0 (no cost limit)")
+	}
+	if opts.ShardsNum < 0 {
+		return nil, fmt.Errorf("shardsNum must be greater or equal to 0 (default number of shards)")
+	}
+	if opts.ShardsNum == 0 {
+		opts.ShardsNum = DefaultShardsNum
+		if maxEntries > 0 {
+			opts.ShardsNum = min(opts.ShardsNum, maxEntries)
+		}
+		if opts.MaxCost > 0 {
+			opts.ShardsNum = int(min(int64(opts.ShardsNum), opts.MaxCost))
+		}
+	}
+	if maxEntries > 0 && opts.ShardsNum > maxEntries {
+		return nil, fmt.Errorf("maxEntries must be greater or equal to the number of shards")
+	}
+	if opts.MaxCost > 0 && int64(opts.ShardsNum) > opts.MaxCost {
+		return nil, fmt.Errorf("maxCost must be greater or equal to the number of shards")
+	}
+	if opts.Hash == nil {
+		seed := maphash.MakeSeed()
+		opts.Hash = func(key K) uint64 { return maphash.Comparable(seed, key) }
+	}
+
+	var amounts *shardedAmounts
+	if metricsCollector != nil {
+		amounts = newShardedAmounts(metricsCollector, opts.ShardsNum)
+	}
+
+	shardSizes := distributeShardSizes(maxEntries, opts.ShardsNum)
+	shardCosts := distributeShardSizes(opts.MaxCost, opts.ShardsNum)
+	shards := make([]*LRUCache[K, V], opts.ShardsNum)
+	for i := range shards {
+		var shardMetricsCollector MetricsCollector
+		if amounts != nil {
+			shardMetricsCollector = &shardMetrics{MetricsCollector: metricsCollector, amounts: amounts, idx: i}
+		}
+		shardOpts := opts.Options
+		shardOpts.MaxCost = shardCosts[i]
+		shard, err := NewWithCallbacks[K, V](shardSizes[i], shardMetricsCollector, shardOpts, callbacks)
+		if err != nil {
+			return nil, err
+		}
+		shards[i] = shard
+	}
+
+	return &ShardedLRUCache[K, V]{shards: shards, hash: opts.Hash}, nil
+}
+
+// Get returns a value from the cache by the provided key and type.
+func (c *ShardedLRUCache[K, V]) Get(key K) (value V, ok bool) {
+	return c.shard(key).Get(key)
+}
+
+// Add adds a value to the cache with the provided key and type.
+// If the shard is full, the oldest entry of the shard will be removed.
+func (c *ShardedLRUCache[K, V]) Add(key K, value V) {
+	c.shard(key).Add(key, value)
+}
+
+// AddWithTTL adds a value to the cache with the provided key, type, and TTL.
+// If the shard is full, the oldest entry of the shard will be removed.
+// If the TTL is less than or equal to 0, the value will not expire.
+func (c *ShardedLRUCache[K, V]) AddWithTTL(key K, value V, ttl time.Duration) {
+	c.shard(key).AddWithTTL(key, value, ttl)
+}
+
+// GetOrAdd returns a value from the cache by the provided key,
+// and adds a new value with the default TTL if the key does not exist.
+// See LRUCache.GetOrAdd for more details.
+func (c *ShardedLRUCache[K, V]) GetOrAdd(key K, valueProvider func() V) (value V, exists bool) {
+	return c.shard(key).GetOrAdd(key, valueProvider)
+}
+
+// GetOrAddWithTTL returns a value from the cache by the provided key,
+// and adds a new value with the specified TTL if the key does not exist.
+// See LRUCache.GetOrAddWithTTL for more details.
+func (c *ShardedLRUCache[K, V]) GetOrAddWithTTL(key K, valueProvider func() V, ttl time.Duration) (value V, exists bool) {
+	return c.shard(key).GetOrAddWithTTL(key, valueProvider, ttl)
+}
+
+// GetOrLoad returns a value from the cache by the provided key,
+// and loads a new value if the key does not exist.
+// Single flight pattern is used to prevent multiple concurrent calls for the same key.
+// See LRUCache.GetOrLoad for more details.
+func (c *ShardedLRUCache[K, V]) GetOrLoad(
+	key K, loadValue func(K) (value V, err error),
+) (value V, exists bool, err error) {
+	return c.shard(key).GetOrLoad(key, loadValue)
+}
+
+// GetOrLoadWithTTL returns a value from the cache by the provided key,
+// and loads a new value if the key does not exist.
+// Single flight pattern is used to prevent multiple concurrent calls for the same key.
+// See LRUCache.GetOrLoadWithTTL for more details.
+func (c *ShardedLRUCache[K, V]) GetOrLoadWithTTL(
+	key K, loadValue func(K) (value V, ttl time.Duration, err error),
+) (value V, exists bool, err error) {
+	return c.shard(key).GetOrLoadWithTTL(key, loadValue)
+}
+
+// Remove removes a value from the cache by the provided key and type.
+func (c *ShardedLRUCache[K, V]) Remove(key K) bool {
+	return c.shard(key).Remove(key)
+}
+
+// Purge clears the cache.
+// See LRUCache.Purge for more details.
+func (c *ShardedLRUCache[K, V]) Purge() {
+	for _, shard := range c.shards {
+		shard.Purge()
+	}
+}
+
+// Resize changes the cache size and returns the number of evicted entries.
+// The new size is distributed evenly between shards.
+// If the size is less than the number of shards, the cache is not resized.
+func (c *ShardedLRUCache[K, V]) Resize(size int) (evicted int) {
+	if size < len(c.shards) {
+		return 0
+	}
+	for i, shardSize := range distributeShardSizes(size, len(c.shards)) {
+		evicted += c.shards[i].Resize(shardSize)
+	}
+	return evicted
+}
+
+// ResizeCost changes the maximum total cost of the cache entries and returns the number of evicted entries.
+// The new limit is distributed evenly between shards.
+// If the limit is less than the number of shards, the cache is not resized.
+func (c *ShardedLRUCache[K, V]) ResizeCost(maxCost int64) (evicted int) {
+	if maxCost < int64(len(c.shards)) {
+		return 0
+	}
+	for i, shardCost := range distributeShardSizes(maxCost, len(c.shards)) {
+		evicted += c.shards[i].ResizeCost(shardCost)
+	}
+	return evicted
+}
+
+// Len returns the number of items in the cache.
+func (c *ShardedLRUCache[K, V]) Len() int {
+	var n int
+	for _, shard := range c.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// Cost returns the total cost of items in the cache.
+func (c *ShardedLRUCache[K, V]) Cost() int64 {
+	var cost int64
+	for _, shard := range c.shards {
+		cost += shard.Cost()
+	}
+	return cost
+}
+
+// RunPeriodicCleanup runs a cycle of periodic cleanup of expired entries in all shards.
+// Entries without expiration time are not affected.
+// OnEvict callback is called for removed entries with EvictionReasonExpired reason in this goroutine.
+// It's supposed to be run in a separate goroutine.
+func (c *ShardedLRUCache[K, V]) RunPeriodicCleanup(ctx context.Context, cleanupInterval time.Duration) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			for _, shard := range c.shards {
+				shard.removeExpired(now)
+			}
+		}
+	}
+}
+
+func (c *ShardedLRUCache[K, V]) shard(key K) *LRUCache[K, V] {
+	return c.shards[c.hash(key)%uint64(len(c.shards))]
+}
+
+func distributeShardSizes[T int | int64](size T, shardsNum int) []T {
+	n := T(shardsNum)
+	sizes := make([]T, shardsNum)
+	for i := range sizes {
+		sizes[i] = size / n
+		if T(i) < size%n {
+			sizes[i]++
+		}
+	}
+	return sizes
+}
+
+// shardedAmounts sums up the numbers of entries (and their costs) in all shards,
+// since the shared metrics collector should receive the total values.
+// Atomics are used instead of a mutex to avoid the contention between shards.
+// Concurrent updates may report the totals out of order, but the next update of any shard reports the actual ones.
+type shardedAmounts struct {
+	collector     MetricsCollector
+	costCollector CostMetricsCollector
+	amounts       []atomic.Int64
+	costs         []atomic.Int64
+	totalAmount   atomic.Int64
+	totalCost     atomic.Int64
+}
+
+func newShardedAmounts(collector MetricsCollector, shardsNum int) *shardedAmounts {
+	costCollector, ok := collector.(CostMetricsCollector)
+	if !ok {
+		costCollector = disabledMetrics{}
+	}
+	return &shardedAmounts{
+		collector:     collector,
+		costCollector: costCollector,
+		amounts:       make([]atomic.Int64, shardsNum),
+		costs:         make([]atomic.Int64, shardsNum),
+	}
+}
+
+func (a *shardedAmounts) setAmount(idx, amount int) {
+	delta := int64(amount) - a.amounts[idx].Swap(int64(amount))
+	a.collector.SetAmount(int(a.totalAmount.Add(delta)))
+}
+
+func (a *shardedAmounts) setCost(idx int, cost int64) {
+	delta := cost - a.costs[idx].Swap(cost)
+	a.costCollector.SetCost(a.totalCost.Add(delta))
+}
+
+type shardMetrics struct {
+	MetricsCollector
+	amounts *shardedAmounts
+	idx     int
+}
+
+func (m *shardMetrics) SetAmount(amount int) {
+	m.amounts.setAmount(m.idx, amount)
+}
+
+func (m *shardMetrics) SetCost(cost int64) {
+	m.amounts.setCost(m.idx, cost)
+}
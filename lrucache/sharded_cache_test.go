@@ -0,0 +1,245 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package lrucache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedLRUCache(t *testing.T) {
+	t.Run("add, get and remove", func(t *testing.T) {
+		metrics := NewPrometheusMetrics()
+		cache, err := NewSharded[string, int](100, metrics)
+		require.NoError(t, err)
+		require.Len(t, cache.shards, DefaultShardsNum)
+
+		for i := 0; i < 10; i++ {
+			cache.Add(strconv.Itoa(i), i)
+		}
+		require.Equal(t, 10, cache.Len())
+		for i := 0; i < 10; i++ {
+			val, found := cache.Get(strconv.Itoa(i))
+			require.True(t, found)
+			require.Equal(t, i, val)
+		}
+		_, found := cache.Get("not_existing_key")
+		require.False(t, found)
+
+		require.True(t, cache.Remove("0"))
+		require.False(t, cache.Remove("0"))
+		require.Equal(t, 9, cache.Len())
+
+		val, exists := cache.GetOrAdd("100", func() int { return 100 })
+		require.False(t, exists)
+		require.Equal(t, 100, val)
+		assertPrometheusMetrics(t, expectedMetrics{EntriesAmount: 10, HitsTotal: 10, MissesTotal: 2}, metrics)
+
+		cache.Purge()
+		require.Equal(t, 0, cache.Len())
+		assertPrometheusMetrics(t, expectedMetrics{HitsTotal: 10, MissesTotal: 2}, metrics)
+	})
+
+	t.Run("eviction and resize", func(t *testing.T) {
+		metrics := NewPrometheusMetrics()
+		cache, err := NewShardedWithOpts[int, int](4, metrics, ShardedOptions[int]{
+			ShardsNum: 2,
+			Hash:      func(key int) uint64 { return uint64(key) }, // Even keys go to the first shard, odd keys to the second one.
+		})
+		require.NoError(t, err)
+
+		for i := 0; i < 6; i++ {
+			cache.Add(i, i)
+		}
+		require.Equal(t, 4, cache.Len())
+		for _, key := range []int{0, 1} {
+			_, found := cache.Get(key)
+			require.False(t, found)
+		}
+		assertPrometheusMetrics(t, expectedMetrics{EntriesAmount: 4, MissesTotal: 2, EvictionsTotal: 2}, metrics)
+
+		require.Equal(t, 0, cache.Resize(1)) // Less than the number of shards.
+		require.Equal(t, 2, cache.Resize(2))
+		require.Equal(t, 2, cache.Len())
+		for _, key := range []int{4, 5} {
+			_, found := cache.Get(key)
+			require.True(t, found)
+		}
+		assertPrometheusMetrics(t, expectedMetrics{EntriesAmount: 2, HitsTotal: 2, MissesTotal: 2, EvictionsTotal: 4}, metrics)
+	})
+
+	t.Run("max cost", func(t *testing.T) {
+		metrics := NewPrometheusMetrics()
+		cache, err := NewShardedWithCallbacks[int, string](0, metrics, ShardedOptions[int]{
+			Options:   Options{MaxCost: 10},
+			ShardsNum: 2,
+			Hash:      func(key int) uint64 { return uint64(key) },
+		}, Callbacks[int, string]{
+			Weigher: func(key int, value string) int64 { return int64(len(value)) },
+		})
+		require.NoError(t, err)
+
+		cache.Add(0, "aaa")
+		cache.Add(2, "bb")
+		cache.Add(1, "cccc")
+		require.Equal(t, int64(9), cache.Cost())
+		require.Equal(t, 9, int(testutil.ToFloat64(metrics.EntriesCost.With(nil))))
+
+		cache.Add(4, "ddd") // The first shard's limit is 5, so "aaa" is evicted.
+		require.Equal(t, int64(9), cache.Cost())
+		_, found := cache.Get(0)
+		require.False(t, found)
+
+		require.Equal(t, 3, cache.ResizeCost(4))
+		require.Equal(t, int64(0), cache.Cost())
+		assertPrometheusMetrics(t, expectedMetrics{MissesTotal: 1, EvictionsTotal: 4}, metrics)
+	})
+
+	t.Run("get or load", func(t *testing.T) {
+		cache, err := NewShardedWithOpts[string, int](100, nil, ShardedOptions[string]{ShardsNum: 4})
+		require.NoError(t, err)
+
+		var loadCalls atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				val, _, loadErr := cache.GetOrLoadWithTTL("key", func(key string) (int, time.Duration, error) {
+					loadCalls.Add(1)
+					time.Sleep(50 * time.Millisecond)
+					return 42, time.Minute, nil
+				})
+				require.NoError(t, loadErr)
+				require.Equal(t, 42, val)
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, int32(1), loadCalls.Load())
+	})
+
+	t.Run("periodic cleanup", func(t *testing.T) {
+		const ttl = 100 * time.Millisecond
+		cache, err := NewShardedWithOpts[string, int](100, nil, ShardedOptions[string]{
+			Options: Options{DefaultTTL: ttl}, ShardsNum: 4,
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go cache.RunPeriodicCleanup(ctx, ttl/2)
+
+		for i := 0; i < 10; i++ {
+			cache.Add(strconv.Itoa(i), i)
+		}
+		cache.AddWithTTL("no_ttl", 0, 0)
+		require.Equal(t, 11, cache.Len())
+
+		require.Eventually(t, func() bool { return cache.Len() == 1 }, ttl*5, ttl/5)
+		_, found := cache.Get("no_ttl")
+		require.True(t, found)
+	})
+}
+
+func TestNewShardedWithOpts(t *testing.T) {
+	_, err := NewSharded[string, int](0, nil)
+	require.EqualError(t, err, "maxEntries must be greater than 0")
+
+	_, err = NewShardedWithOpts[string, int](10, nil, ShardedOptions[string]{ShardsNum: -1})
+	require.EqualError(t, err, "shardsNum must be greater or equal to 0 (default number of shards)")
+
+	_, err = NewShardedWithOpts[string, int](10, nil, ShardedOptions[string]{ShardsNum: 11})
+	require.EqualError(t, err, "maxEntries must be greater or equal to the number of shards")
+
+	_, err = NewShardedWithOpts[string, int](0, nil, ShardedOptions[string]{Options: Options{MaxCost: 2}, ShardsNum: 3})
+	require.EqualError(t, err, "maxCost must be greater or equal to the number of shards")
+
+	_, err = NewShardedWithOpts[string, int](10, nil, ShardedOptions[string]{Options: Options{DefaultTTL: -1}})
+	require.EqualError(t, err, "defaultTTL must be greater or equal to 0 (no expiration)")
+
+	cache, err := NewSharded[string, int](5, nil)
+	require.NoError(t, err)
+	require.Len(t, cache.shards, 5)
+}
+
+type benchCache interface {
+	Get(key string) (int, bool)
+	Add(key string, value int)
+}
+
+// BenchmarkCache_Parallel compares LRUCache and ShardedLRUCache under concurrent load.
+// Caches are run with disabled metrics to measure the lock contention of the caches themselves,
+// and with Prometheus metrics to make sure that the shared metrics don't bring the contention back.
+// Run it with several CPUs (e.g., -cpu 1,4,16) to see the difference.
+func BenchmarkCache_Parallel(b *testing.B) {
+	const maxEntries = 10_000
+	keys := make([]string, maxEntries*2)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	newCaches := []struct {
+		name     string
+		newCache func() benchCache
+	}{
+		{"LRUCache", func() benchCache {
+			cache, _ := New[string, int](maxEntries, nil)
+			return cache
+		}},
+		{"ShardedLRUCache", func() benchCache {
+			cache, _ := NewSharded[string, int](maxEntries, nil)
+			return cache
+		}},
+		{"LRUCache with metrics", func() benchCache {
+			cache, _ := New[string, int](maxEntries, NewPrometheusMetrics())
+			return cache
+		}},
+		{"ShardedLRUCache with metrics", func() benchCache {
+			cache, _ := NewSharded[string, int](maxEntries, NewPrometheusMetrics())
+			return cache
+		}},
+	}
+
+	for _, workload := range []struct {
+		name         string
+		writePercent int
+	}{
+		{"read only", 0},
+		{"10% writes", 10},
+		{"50% writes", 50},
+	} {
+		for _, nc := range newCaches {
+			b.Run(workload.name+"/"+nc.name, func(b *testing.B) {
+				cache := nc.newCache()
+				for i := 0; i < maxEntries; i++ {
+					cache.Add(keys[i], i)
+				}
+				var seq atomic.Uint64
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					i := int(seq.Add(1) * 7919)
+					for pb.Next() {
+						i++
+						key := keys[i%len(keys)]
+						if i%100 < workload.writePercent {
+							cache.Add(key, i)
+						} else {
+							cache.Get(key)
+						}
+					}
+				})
+			})
+		}
+	}
+}
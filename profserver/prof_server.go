@@ -22,8 +22,14 @@ import (
 // ProfServer represents HTTP server for profiling. pprof is used under the hood.
 // It implements service.Unit interface.
 type ProfServer struct {
-	URL            string
-	HTTPServer     *http.Server
+	URL        string
+	HTTPServer *http.Server
+
+	// HTTPRouter may be used for mounting additional debug handlers before starting the server.
+	// E.g., recent log entries may be exposed with
+	// profServer.HTTPRouter.Mount("/debug/logs", log.NewRingBufferHandler(ringBuffer)).
+	HTTPRouter chi.Router
+
 	httpServerDone chan struct{}
 	Logger         log.FieldLogger
 }
@@ -48,6 +54,7 @@ func New(cfg *Config, logger log.FieldLogger) *ProfServer {
 	return &ProfServer{
 		URL:            "http://" + httpServer.Addr,
 		HTTPServer:     httpServer,
+		HTTPRouter:     router,
 		httpServerDone: make(chan struct{}),
 		Logger:         logger,
 	}
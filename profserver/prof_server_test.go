@@ -14,6 +14,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/acronis/go-appkit/log"
 	"github.com/acronis/go-appkit/log/logtest"
 	"github.com/acronis/go-appkit/testutil"
 )
@@ -38,3 +39,28 @@ func TestProfServer_Start(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, len(respBody) > 0)
 }
+
+func TestProfServer_MountRingBufferHandler(t *testing.T) {
+	addr := testutil.GetLocalAddrWithFreeTCPPort()
+
+	ringBuffer := log.NewRingBuffer(log.RingBufferOpts{})
+	ringBuffer.OnEntry(log.Entry{Level: log.LevelError, Time: time.Now(), Text: "something went wrong"})
+
+	profServer := New(&Config{Address: addr}, logtest.NewRecorder())
+	profServer.HTTPRouter.Mount("/debug/logs", log.NewRingBufferHandler(ringBuffer))
+	fatalErr := make(chan error, 1)
+	go profServer.Start(fatalErr)
+	require.NoError(t, testutil.WaitListeningServer(addr, time.Second*3))
+	defer func() {
+		require.NoError(t, profServer.Stop(false))
+		testutil.RequireNoErrorInChannel(t, fatalErr)
+	}()
+
+	resp, err := http.Get(profServer.URL + "/debug/logs?format=text")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, resp.Body.Close()) }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(respBody), "something went wrong")
+}
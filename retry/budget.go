@@ -0,0 +1,123 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package retry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// Default parameter values for Budget.
+const (
+	DefaultBudgetName      = "default"
+	DefaultBudgetMaxTokens = 10
+)
+
+// BudgetOpts contains optional parameters for constructing Budget.
+type BudgetOpts struct {
+	// Name is used in metrics. DefaultBudgetName is used if empty.
+	Name string
+
+	// MaxTokens is a capacity of the token bucket, i.e. the maximum number of retries
+	// that can be done in a row without successful calls. DefaultBudgetMaxTokens is used if zero.
+	MaxTokens float64
+
+	// MetricsCollector collects metrics of the budget (e.g., BudgetPrometheusMetrics).
+	MetricsCollector BudgetMetricsCollector
+}
+
+// Budget limits the number of retries to prevent retry storms when the downstream service is unavailable.
+//
+// It's a token bucket that initially is full. Every retry takes one token from the bucket,
+// and every successful call puts the ratio of a token back (up to the bucket capacity).
+// So in the long term, the number of retries cannot exceed the ratio of the number of successful calls.
+// The budget is safe for concurrent use and may be shared between multiple clients.
+type Budget struct {
+	name             string
+	ratio            float64
+	maxTokens        float64
+	metricsCollector BudgetMetricsCollector
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+// NewBudget creates a new Budget with the given ratio of retries to successful calls (e.g., 0.1 means 10%).
+func NewBudget(ratio float64) *Budget {
+	return NewBudgetWithOpts(ratio, BudgetOpts{})
+}
+
+// NewBudgetWithOpts creates a new Budget with the given ratio of retries to successful calls
+// and an ability to specify different optional parameters.
+func NewBudgetWithOpts(ratio float64, opts BudgetOpts) *Budget {
+	if opts.Name == "" {
+		opts.Name = DefaultBudgetName
+	}
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = DefaultBudgetMaxTokens
+	}
+	if opts.MetricsCollector == nil {
+		opts.MetricsCollector = disabledBudgetMetrics{}
+	}
+	b := &Budget{
+		name:             opts.Name,
+		ratio:            ratio,
+		maxTokens:        opts.MaxTokens,
+		metricsCollector: opts.MetricsCollector,
+		tokens:           opts.MaxTokens,
+	}
+	b.metricsCollector.SetTokens(b.name, b.tokens)
+	return b
+}
+
+// RecordSuccess refills the budget after a successful call.
+func (b *Budget) RecordSuccess() {
+	b.mu.Lock()
+	b.tokens = min(b.tokens+b.ratio, b.maxTokens)
+	tokens := b.tokens
+	b.mu.Unlock()
+	b.metricsCollector.SetTokens(b.name, tokens)
+}
+
+// TryRetry takes a token from the budget and returns true if the retry is allowed.
+// False is returned if the budget is exhausted.
+func (b *Budget) TryRetry() bool {
+	b.mu.Lock()
+	if b.tokens < 1 {
+		b.mu.Unlock()
+		b.metricsCollector.IncDeniedRetries(b.name)
+		return false
+	}
+	b.tokens--
+	tokens := b.tokens
+	b.mu.Unlock()
+	b.metricsCollector.SetTokens(b.name, tokens)
+	return true
+}
+
+// Tokens returns the number of tokens that are currently available in the budget.
+func (b *Budget) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
+
+// budgetBackOff stops retrying if the retry budget is exhausted.
+type budgetBackOff struct {
+	backoff.BackOff
+	budget *Budget
+}
+
+func (b *budgetBackOff) NextBackOff() time.Duration {
+	next := b.BackOff.NextBackOff()
+	if next == backoff.Stop || !b.budget.TryRetry() {
+		return backoff.Stop
+	}
+	return next
+}
@@ -0,0 +1,86 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package retry
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/acronis/go-appkit/internal/libinfo"
+)
+
+// BudgetMetricsCollector represents a collector of metrics for Budget.
+type BudgetMetricsCollector interface {
+	// SetTokens sets the number of tokens that are currently available in the budget.
+	SetTokens(budgetName string, tokens float64)
+	// IncDeniedRetries increments the total number of retries denied since the budget is exhausted.
+	IncDeniedRetries(budgetName string)
+}
+
+// BudgetPrometheusMetricsOpts represents options for BudgetPrometheusMetrics.
+type BudgetPrometheusMetricsOpts struct {
+	// Namespace is a namespace for metrics. It will be prepended to all metric names.
+	Namespace string
+
+	// ConstLabels is a set of labels that will be applied to all metrics.
+	ConstLabels prometheus.Labels
+}
+
+// BudgetPrometheusMetrics represents a Prometheus metrics for Budget.
+type BudgetPrometheusMetrics struct {
+	Tokens        *prometheus.GaugeVec
+	DeniedRetries *prometheus.CounterVec
+}
+
+// NewBudgetPrometheusMetrics creates a new instance of BudgetPrometheusMetrics with default options.
+func NewBudgetPrometheusMetrics() *BudgetPrometheusMetrics {
+	return NewBudgetPrometheusMetricsWithOpts(BudgetPrometheusMetricsOpts{})
+}
+
+// NewBudgetPrometheusMetricsWithOpts creates a new instance of BudgetPrometheusMetrics with the provided options.
+func NewBudgetPrometheusMetricsWithOpts(opts BudgetPrometheusMetricsOpts) *BudgetPrometheusMetrics {
+	constLabels := libinfo.AddPrometheusLibVersionLabel(opts.ConstLabels)
+	return &BudgetPrometheusMetrics{
+		Tokens: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "retry_budget_tokens",
+			Help:        "Number of tokens that are currently available in the retry budget.",
+			ConstLabels: constLabels,
+		}, []string{"budget"}),
+		DeniedRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "retry_budget_denied_retries_total",
+			Help:        "Number of retries denied since the retry budget is exhausted.",
+			ConstLabels: constLabels,
+		}, []string{"budget"}),
+	}
+}
+
+// SetTokens sets the number of tokens that are currently available in the budget.
+func (pm *BudgetPrometheusMetrics) SetTokens(budgetName string, tokens float64) {
+	pm.Tokens.WithLabelValues(budgetName).Set(tokens)
+}
+
+// IncDeniedRetries increments the total number of retries denied since the budget is exhausted.
+func (pm *BudgetPrometheusMetrics) IncDeniedRetries(budgetName string) {
+	pm.DeniedRetries.WithLabelValues(budgetName).Inc()
+}
+
+// MustRegister does registration of metrics collector in Prometheus and panics if any error occurs.
+func (pm *BudgetPrometheusMetrics) MustRegister() {
+	prometheus.MustRegister(pm.Tokens, pm.DeniedRetries)
+}
+
+// Unregister cancels registration of metrics collector in Prometheus.
+func (pm *BudgetPrometheusMetrics) Unregister() {
+	prometheus.Unregister(pm.Tokens)
+	prometheus.Unregister(pm.DeniedRetries)
+}
+
+type disabledBudgetMetrics struct{}
+
+func (disabledBudgetMetrics) SetTokens(string, float64) {}
+func (disabledBudgetMetrics) IncDeniedRetries(string)   {}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudget(t *testing.T) {
+	t.Run("retries are limited by tokens", func(t *testing.T) {
+		promMetrics := NewBudgetPrometheusMetrics()
+		budget := NewBudgetWithOpts(0.5, BudgetOpts{Name: "test", MaxTokens: 2, MetricsCollector: promMetrics})
+
+		require.True(t, budget.TryRetry())
+		require.True(t, budget.TryRetry())
+		require.False(t, budget.TryRetry())
+		require.Equal(t, 1.0, testutil.ToFloat64(promMetrics.DeniedRetries.WithLabelValues("test")))
+		require.Equal(t, 0.0, testutil.ToFloat64(promMetrics.Tokens.WithLabelValues("test")))
+
+		budget.RecordSuccess()
+		require.False(t, budget.TryRetry())
+		budget.RecordSuccess()
+		require.True(t, budget.TryRetry())
+		require.Equal(t, 2.0, testutil.ToFloat64(promMetrics.DeniedRetries.WithLabelValues("test")))
+	})
+
+	t.Run("tokens are capped", func(t *testing.T) {
+		budget := NewBudgetWithOpts(1, BudgetOpts{MaxTokens: 3})
+		for i := 0; i < 10; i++ {
+			budget.RecordSuccess()
+		}
+		require.Equal(t, 3.0, budget.Tokens())
+	})
+
+	t.Run("DoWithRetryResult respects budget", func(t *testing.T) {
+		errTemporary := errors.New("temporary error")
+		budget := NewBudgetWithOpts(0.1, BudgetOpts{MaxTokens: 3})
+		opts := Opts{Budget: budget}
+		policy := NewConstantBackoffPolicy(time.Millisecond, 10)
+
+		attempts := 0
+		_, err := DoWithRetryResult(context.Background(), policy, opts, func(ctx context.Context, attempt int) (int, error) {
+			attempts = attempt
+			return 0, errTemporary
+		})
+		require.ErrorIs(t, err, errTemporary)
+		require.Equal(t, 4, attempts) // The first attempt and 3 retries.
+		require.Equal(t, 0.0, budget.Tokens())
+
+		err = DoWithRetryWithOpts(context.Background(), policy, opts, func(ctx context.Context) error { return nil })
+		require.NoError(t, err)
+		require.InDelta(t, 0.1, budget.Tokens(), 1e-9)
+	})
+}
@@ -0,0 +1,162 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package retry
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// JitterStrategy defines how random jitter is applied to backoff delays.
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/ for details.
+type JitterStrategy string
+
+// Jitter strategies.
+const (
+	// JitterStrategyFull means that the delay is a random value between 0 and the exponentially growing interval.
+	JitterStrategyFull JitterStrategy = "full"
+
+	// JitterStrategyEqual means that the delay is a half of the exponentially growing interval
+	// plus a random value between 0 and the other half.
+	JitterStrategyEqual JitterStrategy = "equal"
+
+	// JitterStrategyDecorrelated means that the delay is a random value between the initial interval
+	// and the tripled previous delay. Multiplier is not used by this strategy.
+	JitterStrategyDecorrelated JitterStrategy = "decorrelated"
+)
+
+// IsValid returns true if the jitter strategy is known.
+func (s JitterStrategy) IsValid() bool {
+	switch s {
+	case JitterStrategyFull, JitterStrategyEqual, JitterStrategyDecorrelated:
+		return true
+	}
+	return false
+}
+
+// Default values for JitterBackoffPolicy.
+const (
+	DefaultJitterMultiplier  = 2.0
+	DefaultJitterMaxInterval = time.Minute
+)
+
+// decorrelatedJitterFactor is a factor by which the previous delay is multiplied in the decorrelated jitter strategy.
+const decorrelatedJitterFactor = 3
+
+// JitterBackoffPolicyOpts contains optional parameters for JitterBackoffPolicy.
+type JitterBackoffPolicyOpts struct {
+	// Multiplier is a factor by which the interval grows after every attempt. DefaultJitterMultiplier is used by default.
+	Multiplier float64
+
+	// MaxInterval caps the interval (and so the delay). DefaultJitterMaxInterval is used by default.
+	MaxInterval time.Duration
+
+	// MaxElapsedTime limits the total time of retries. Zero value means no limit.
+	MaxElapsedTime time.Duration
+}
+
+// JitterBackoffPolicy means repeat up to max times with exponentially growing delays randomized
+// according to the jitter strategy.
+type JitterBackoffPolicy struct {
+	strategy        JitterStrategy
+	initialInterval time.Duration
+	maxAttempts     int
+	multiplier      float64
+	maxInterval     time.Duration
+	maxElapsedTime  time.Duration
+}
+
+// NewJitterBackoffPolicy returns a jitter backoff policy with given strategy, initial interval and max retry attempt count.
+func NewJitterBackoffPolicy(strategy JitterStrategy, initialInterval time.Duration, maxRetryAttempts int) JitterBackoffPolicy {
+	return NewJitterBackoffPolicyWithOpts(strategy, initialInterval, maxRetryAttempts, JitterBackoffPolicyOpts{})
+}
+
+// NewJitterBackoffPolicyWithOpts is a more configurable version of NewJitterBackoffPolicy.
+// If the strategy is unknown, JitterStrategyFull is used.
+func NewJitterBackoffPolicyWithOpts(
+	strategy JitterStrategy, initialInterval time.Duration, maxRetryAttempts int, opts JitterBackoffPolicyOpts,
+) JitterBackoffPolicy {
+	if !strategy.IsValid() {
+		strategy = JitterStrategyFull
+	}
+	if opts.Multiplier <= 0 {
+		opts.Multiplier = DefaultJitterMultiplier
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = DefaultJitterMaxInterval
+	}
+	return JitterBackoffPolicy{
+		strategy:        strategy,
+		initialInterval: initialInterval,
+		maxAttempts:     maxRetryAttempts,
+		multiplier:      opts.Multiplier,
+		maxInterval:     opts.MaxInterval,
+		maxElapsedTime:  opts.MaxElapsedTime,
+	}
+}
+
+// NewBackOff implements retry.Policy.
+func (p JitterBackoffPolicy) NewBackOff() backoff.BackOff {
+	var bf backoff.BackOff = &jitterBackOff{policy: p}
+	if p.maxElapsedTime > 0 {
+		bf = &maxElapsedTimeBackOff{BackOff: bf, maxElapsedTime: p.maxElapsedTime}
+	}
+	if p.maxAttempts > 0 {
+		bf = backoff.WithMaxRetries(bf, uint64(p.maxAttempts))
+	}
+	bf.Reset()
+	return bf
+}
+
+type jitterBackOff struct {
+	policy    JitterBackoffPolicy
+	attempt   int
+	prevDelay time.Duration
+}
+
+func (b *jitterBackOff) Reset() {
+	b.attempt = 0
+	b.prevDelay = b.policy.initialInterval
+}
+
+func (b *jitterBackOff) NextBackOff() time.Duration {
+	defer func() { b.attempt++ }()
+	switch b.policy.strategy {
+	case JitterStrategyEqual:
+		interval := b.interval()
+		return interval/2 + randDuration(0, interval-interval/2)
+	case JitterStrategyDecorrelated:
+		upper := b.prevDelay * decorrelatedJitterFactor
+		if upper > b.policy.maxInterval || upper < b.prevDelay { // Handle overflow as well.
+			upper = b.policy.maxInterval
+		}
+		b.prevDelay = randDuration(min(b.policy.initialInterval, upper), upper)
+		return b.prevDelay
+	default: // JitterStrategyFull is used for unknown strategies (e.g., in the zero-value policy).
+		return randDuration(0, b.interval())
+	}
+}
+
+// interval returns the exponentially growing interval for the current attempt capped by the max interval.
+func (b *jitterBackOff) interval() time.Duration {
+	interval := float64(b.policy.initialInterval) * math.Pow(b.policy.multiplier, float64(b.attempt))
+	if interval >= float64(b.policy.maxInterval) {
+		return b.policy.maxInterval
+	}
+	return time.Duration(interval)
+}
+
+// randDuration returns a random duration in [lower, upper].
+func randDuration(lower, upper time.Duration) time.Duration {
+	if upper <= lower {
+		return lower
+	}
+	return lower + rand.N(upper-lower+1) //nolint:gosec // Cryptographically secure random is not needed for jitter.
+}
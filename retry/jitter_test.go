@@ -0,0 +1,101 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJitterBackoffPolicy(t *testing.T) {
+	const initialInterval = time.Millisecond * 100
+	const maxInterval = time.Second
+	opts := JitterBackoffPolicyOpts{MaxInterval: maxInterval}
+
+	t.Run("full jitter", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			bf := NewJitterBackoffPolicyWithOpts(JitterStrategyFull, initialInterval, 6, opts).NewBackOff()
+			for _, upper := range []time.Duration{
+				initialInterval, initialInterval * 2, initialInterval * 4, initialInterval * 8, maxInterval, maxInterval,
+			} {
+				delay := bf.NextBackOff()
+				require.GreaterOrEqual(t, delay, time.Duration(0))
+				require.LessOrEqual(t, delay, upper)
+			}
+			require.Equal(t, backoff.Stop, bf.NextBackOff())
+		}
+	})
+
+	t.Run("equal jitter", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			bf := NewJitterBackoffPolicyWithOpts(JitterStrategyEqual, initialInterval, 0,
+				JitterBackoffPolicyOpts{Multiplier: 3, MaxInterval: maxInterval}).NewBackOff()
+			for _, upper := range []time.Duration{initialInterval, initialInterval * 3, initialInterval * 9, maxInterval} {
+				delay := bf.NextBackOff()
+				require.GreaterOrEqual(t, delay, upper/2)
+				require.LessOrEqual(t, delay, upper)
+			}
+		}
+	})
+
+	t.Run("decorrelated jitter", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			bf := NewJitterBackoffPolicyWithOpts(JitterStrategyDecorrelated, initialInterval, 0, opts).NewBackOff()
+			prevDelay := initialInterval
+			for j := 0; j < 10; j++ {
+				delay := bf.NextBackOff()
+				require.GreaterOrEqual(t, delay, initialInterval)
+				require.LessOrEqual(t, delay, min(prevDelay*3, maxInterval))
+				prevDelay = delay
+			}
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		bf := NewJitterBackoffPolicy(JitterStrategyEqual, initialInterval, 2).NewBackOff()
+		bf.NextBackOff()
+		bf.NextBackOff()
+		require.Equal(t, backoff.Stop, bf.NextBackOff())
+		bf.Reset()
+		require.LessOrEqual(t, bf.NextBackOff(), initialInterval)
+	})
+
+	t.Run("unknown strategy falls back to full jitter", func(t *testing.T) {
+		bf := NewJitterBackoffPolicyWithOpts("unknown", initialInterval, 0, opts).NewBackOff()
+		require.LessOrEqual(t, bf.NextBackOff(), initialInterval)
+
+		require.NotPanics(t, func() { JitterBackoffPolicy{}.NewBackOff().NextBackOff() })
+	})
+
+	t.Run("max elapsed time", func(t *testing.T) {
+		bf := NewJitterBackoffPolicyWithOpts(JitterStrategyEqual, time.Hour, 0,
+			JitterBackoffPolicyOpts{MaxInterval: time.Hour * 2, MaxElapsedTime: time.Minute}).NewBackOff()
+		require.Equal(t, backoff.Stop, bf.NextBackOff())
+	})
+}
+
+func TestExponentialBackoffPolicyWithOpts(t *testing.T) {
+	bf := NewExponentialBackoffPolicyWithOpts(time.Millisecond*100, 0, ExponentialBackoffPolicyOpts{
+		Multiplier:  3,
+		MaxInterval: time.Millisecond * 500,
+	}).NewBackOff()
+	eb, ok := bf.(*backoff.ExponentialBackOff)
+	require.True(t, ok)
+	require.Equal(t, 3.0, eb.Multiplier)
+	require.Equal(t, time.Millisecond*500, eb.MaxInterval)
+	require.Equal(t, backoff.DefaultMaxElapsedTime, eb.MaxElapsedTime)
+}
+
+func TestJitterStrategy_IsValid(t *testing.T) {
+	for _, s := range []JitterStrategy{JitterStrategyFull, JitterStrategyEqual, JitterStrategyDecorrelated} {
+		require.True(t, s.IsValid())
+	}
+	require.False(t, JitterStrategy("unknown").IsValid())
+}
@@ -28,6 +28,7 @@ type Policy interface {
 // IsRetryable defines which errors lead to retry attempt (can be nil for any error).
 // Notify can be used to receive notification on every retry with error and backoff delay
 // (can be nil if no notifications required).
+// Use DoWithRetryWithOpts or DoWithRetryResult for per-attempt timeouts, max elapsed time and structured notifications.
 func DoWithRetry(ctx context.Context, p Policy, isRetryable IsRetryable, notify backoff.Notify, fn RetryableFunc) error {
 	b := p.NewBackOff()
 	bctx := backoff.WithContext(b, ctx)
@@ -42,6 +43,134 @@ func DoWithRetry(ctx context.Context, p Policy, isRetryable IsRetryable, notify
 	return backoff.RetryNotify(op, bctx, notify)
 }
 
+// AttemptInfo contains information about the failed attempt that is passed to NotifyFunc.
+type AttemptInfo struct {
+	// Attempt is a number of the failed attempt (starting from 1).
+	Attempt int
+	// Err is an error returned by the failed attempt.
+	Err error
+	// Delay is a backoff delay before the next attempt.
+	Delay time.Duration
+	// Elapsed is a time elapsed since the first attempt was started.
+	Elapsed time.Duration
+}
+
+// NotifyFunc is a function that receives notification on every failed attempt that will be retried.
+type NotifyFunc func(info AttemptInfo)
+
+// RetryableFuncWithResult is a function that does some work, returns its result and can be potentially retried.
+// The number of the current attempt (starting from 1) is passed to the function.
+type RetryableFuncWithResult[T any] func(ctx context.Context, attempt int) (T, error)
+
+// Opts contains optional parameters for DoWithRetryWithOpts and DoWithRetryResult.
+type Opts struct {
+	// IsRetryable defines which errors lead to retry attempt (nil means any error).
+	IsRetryable IsRetryable
+
+	// Notify receives notification on every failed attempt that will be retried (nil means no notifications).
+	Notify NotifyFunc
+
+	// AttemptTimeout limits the time of every single attempt. Zero value means no timeout.
+	// Errors caused by exceeding this timeout are retried (if IsRetryable allows this).
+	// Contexts of failed attempts are canceled right after they return, but the context of the successful attempt
+	// is not canceled on return, so its result (e.g., the body of http.Response) may be used until the timeout expires.
+	// I.e., the timeout covers using the result as http.Client.Timeout covers reading the response body.
+	AttemptTimeout time.Duration
+
+	// MaxElapsedTime limits the total time of all attempts and delays between them.
+	// The next attempt is not made if it's going to start after this time. Zero value means no limit.
+	MaxElapsedTime time.Duration
+
+	// Budget limits the number of retries (nil means no limit).
+	// It's refilled by successful calls and may be shared between multiple callers.
+	Budget *Budget
+}
+
+type attemptCtxKey struct{}
+
+// AttemptFromContext returns the number of the current attempt (starting from 1) from the context
+// that is passed to the function by DoWithRetryWithOpts and DoWithRetryResult.
+// Zero is returned if the context is not created by these functions.
+func AttemptFromContext(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptCtxKey{}).(int)
+	return attempt
+}
+
+// DoWithRetryWithOpts is a more configurable version of DoWithRetry.
+func DoWithRetryWithOpts(ctx context.Context, p Policy, opts Opts, fn RetryableFunc) error {
+	_, err := DoWithRetryResult(ctx, p, opts, func(ctx context.Context, _ int) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// DoWithRetryResult executes fn with retry according to policy p and with respect to context ctx,
+// and returns the result of the last attempt.
+func DoWithRetryResult[T any](ctx context.Context, p Policy, opts Opts, fn RetryableFuncWithResult[T]) (T, error) {
+	b := p.NewBackOff()
+	if opts.MaxElapsedTime > 0 {
+		b = &maxElapsedTimeBackOff{BackOff: b, maxElapsedTime: opts.MaxElapsedTime}
+	}
+	if opts.Budget != nil {
+		b = &budgetBackOff{BackOff: b, budget: opts.Budget}
+	}
+	bctx := backoff.WithContext(b, ctx)
+
+	startTime := time.Now()
+	attempt := 0
+	var op backoff.OperationWithData[T] = func() (res T, err error) {
+		attempt++
+		attemptCtx := context.WithValue(bctx.Context(), attemptCtxKey{}, attempt)
+		if opts.AttemptTimeout > 0 {
+			var cancel context.CancelFunc
+			attemptCtx, cancel = context.WithTimeout(attemptCtx, opts.AttemptTimeout)
+			// The context of the successful attempt is released when the timeout expires,
+			// since the result may still depend on it.
+			defer func() {
+				if err != nil {
+					cancel()
+				}
+			}()
+		}
+		res, err = fn(attemptCtx, attempt)
+		if err == nil && opts.Budget != nil {
+			opts.Budget.RecordSuccess()
+		}
+		if err != nil && opts.IsRetryable != nil && !opts.IsRetryable(err) {
+			return res, backoff.Permanent(err)
+		}
+		return res, err
+	}
+
+	var notify backoff.Notify
+	if opts.Notify != nil {
+		notify = func(err error, delay time.Duration) {
+			opts.Notify(AttemptInfo{Attempt: attempt, Err: err, Delay: delay, Elapsed: time.Since(startTime)})
+		}
+	}
+	return backoff.RetryNotifyWithData(op, bctx, notify)
+}
+
+// maxElapsedTimeBackOff stops retrying if the next attempt is going to start after the max elapsed time.
+type maxElapsedTimeBackOff struct {
+	backoff.BackOff
+	maxElapsedTime time.Duration
+	startTime      time.Time
+}
+
+func (b *maxElapsedTimeBackOff) Reset() {
+	b.startTime = time.Now()
+	b.BackOff.Reset()
+}
+
+func (b *maxElapsedTimeBackOff) NextBackOff() time.Duration {
+	next := b.BackOff.NextBackOff()
+	if next == backoff.Stop || time.Since(b.startTime)+next > b.maxElapsedTime {
+		return backoff.Stop
+	}
+	return next
+}
+
 // The PolicyFunc type is an adapter to allow the use of ordinary functions as retry.Policy.
 type PolicyFunc func() backoff.BackOff
 
@@ -50,21 +179,51 @@ func (f PolicyFunc) NewBackOff() backoff.BackOff {
 	return f()
 }
 
-// ExponentialBackoffPolicy means repeat up to max times with exponentially growing delays (1.5 multiplier).
+// ExponentialBackoffPolicyOpts contains optional parameters for ExponentialBackoffPolicy.
+type ExponentialBackoffPolicyOpts struct {
+	// Multiplier is a factor by which the interval grows after every attempt (1.5 by default).
+	Multiplier float64
+
+	// MaxInterval caps the interval between attempts (1 minute by default).
+	MaxInterval time.Duration
+
+	// MaxElapsedTime limits the total time of retries (15 minutes by default).
+	MaxElapsedTime time.Duration
+}
+
+// ExponentialBackoffPolicy means repeat up to max times with exponentially growing delays (1.5 multiplier by default).
+// Delays are randomized with a 0.5 randomization factor. Use JitterBackoffPolicy for other jitter strategies.
 type ExponentialBackoffPolicy struct {
 	initialInterval time.Duration
 	maxAttempts     int
+	opts            ExponentialBackoffPolicyOpts
 }
 
 // NewExponentialBackoffPolicy returns an exponential backoff policy with given initial interval and max retry attempt count.
 func NewExponentialBackoffPolicy(initialInterval time.Duration, maxRetryAttempts int) ExponentialBackoffPolicy {
-	return ExponentialBackoffPolicy{initialInterval, maxRetryAttempts}
+	return NewExponentialBackoffPolicyWithOpts(initialInterval, maxRetryAttempts, ExponentialBackoffPolicyOpts{})
+}
+
+// NewExponentialBackoffPolicyWithOpts is a more configurable version of NewExponentialBackoffPolicy.
+func NewExponentialBackoffPolicyWithOpts(
+	initialInterval time.Duration, maxRetryAttempts int, opts ExponentialBackoffPolicyOpts,
+) ExponentialBackoffPolicy {
+	return ExponentialBackoffPolicy{initialInterval: initialInterval, maxAttempts: maxRetryAttempts, opts: opts}
 }
 
 // NewBackOff implements retry.Policy.
 func (p ExponentialBackoffPolicy) NewBackOff() backoff.BackOff {
 	eb := backoff.NewExponentialBackOff()
 	eb.InitialInterval = p.initialInterval
+	if p.opts.Multiplier > 0 {
+		eb.Multiplier = p.opts.Multiplier
+	}
+	if p.opts.MaxInterval > 0 {
+		eb.MaxInterval = p.opts.MaxInterval
+	}
+	if p.opts.MaxElapsedTime > 0 {
+		eb.MaxElapsedTime = p.opts.MaxElapsedTime
+	}
 	var bf backoff.BackOff = eb
 	if p.maxAttempts > 0 {
 		bf = backoff.WithMaxRetries(eb, uint64(p.maxAttempts))
@@ -0,0 +1,146 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoWithRetryResult(t *testing.T) {
+	errTemporary := errors.New("temporary error")
+	errPermanent := errors.New("permanent error")
+
+	t.Run("result of successful attempt is returned", func(t *testing.T) {
+		var notifications []AttemptInfo
+		res, err := DoWithRetryResult(context.Background(), NewConstantBackoffPolicy(time.Millisecond, 5), Opts{
+			Notify: func(info AttemptInfo) { notifications = append(notifications, info) },
+		}, func(ctx context.Context, attempt int) (string, error) {
+			require.Equal(t, attempt, AttemptFromContext(ctx))
+			if attempt < 3 {
+				return "", errTemporary
+			}
+			return "ok", nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, "ok", res)
+		require.Len(t, notifications, 2)
+		for i, info := range notifications {
+			require.Equal(t, i+1, info.Attempt)
+			require.ErrorIs(t, info.Err, errTemporary)
+			require.Equal(t, time.Millisecond, info.Delay)
+			require.Positive(t, info.Elapsed)
+		}
+	})
+
+	t.Run("max attempts exceeded", func(t *testing.T) {
+		var attempts int
+		_, err := DoWithRetryResult(context.Background(), NewConstantBackoffPolicy(time.Millisecond, 2), Opts{},
+			func(ctx context.Context, attempt int) (int, error) {
+				attempts = attempt
+				return 0, errTemporary
+			})
+		require.ErrorIs(t, err, errTemporary)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("not retryable error", func(t *testing.T) {
+		var attempts int
+		res, err := DoWithRetryResult(context.Background(), NewConstantBackoffPolicy(time.Millisecond, 5), Opts{
+			IsRetryable: func(err error) bool { return !errors.Is(err, errPermanent) },
+		}, func(ctx context.Context, attempt int) (int, error) {
+			attempts = attempt
+			return 42, errPermanent
+		})
+		require.ErrorIs(t, err, errPermanent)
+		require.Equal(t, 42, res)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("attempt timeout", func(t *testing.T) {
+		res, err := DoWithRetryResult(context.Background(), NewConstantBackoffPolicy(time.Millisecond, 5), Opts{
+			AttemptTimeout: time.Millisecond * 20,
+		}, func(ctx context.Context, attempt int) (int, error) {
+			if attempt == 1 {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			return attempt, ctx.Err()
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, res)
+	})
+
+	t.Run("context of successful attempt is not canceled on return", func(t *testing.T) {
+		var attemptCtxs []context.Context
+		resCtx, err := DoWithRetryResult(context.Background(), NewConstantBackoffPolicy(time.Millisecond, 5), Opts{
+			AttemptTimeout: time.Millisecond * 50,
+		}, func(ctx context.Context, attempt int) (context.Context, error) {
+			attemptCtxs = append(attemptCtxs, ctx)
+			if attempt == 1 {
+				return nil, errTemporary
+			}
+			return ctx, nil // E.g., http.Response with the body that is read using the attempt context.
+		})
+		require.NoError(t, err)
+		require.Len(t, attemptCtxs, 2)
+		require.ErrorIs(t, attemptCtxs[0].Err(), context.Canceled, "context of failed attempt must be canceled")
+		require.NoError(t, resCtx.Err())
+		select {
+		case <-resCtx.Done():
+			require.ErrorIs(t, resCtx.Err(), context.DeadlineExceeded)
+		case <-time.After(time.Second):
+			require.Fail(t, "context of successful attempt is not released after timeout")
+		}
+	})
+
+	t.Run("max elapsed time", func(t *testing.T) {
+		var attempts int
+		startTime := time.Now()
+		_, err := DoWithRetryResult(context.Background(), NewConstantBackoffPolicy(time.Millisecond*30, 0), Opts{
+			MaxElapsedTime: time.Millisecond * 100,
+		}, func(ctx context.Context, attempt int) (int, error) {
+			attempts = attempt
+			return 0, errTemporary
+		})
+		require.ErrorIs(t, err, errTemporary)
+		require.GreaterOrEqual(t, attempts, 2)
+		require.LessOrEqual(t, attempts, 4) // Attempts are made at 0, 30, 60 and 90 ms in the ideal case.
+		require.Less(t, time.Since(startTime), time.Millisecond*120)
+	})
+
+	t.Run("context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		_, err := DoWithRetryResult(ctx, NewConstantBackoffPolicy(time.Millisecond, 0), Opts{},
+			func(ctx context.Context, attempt int) (int, error) {
+				if attempt == 2 {
+					cancel()
+				}
+				return 0, errTemporary
+			})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestDoWithRetryWithOpts(t *testing.T) {
+	var attempts []int
+	err := DoWithRetryWithOpts(context.Background(), NewExponentialBackoffPolicy(time.Millisecond, 3), Opts{},
+		func(ctx context.Context) error {
+			attempts = append(attempts, AttemptFromContext(ctx))
+			if len(attempts) < 2 {
+				return errors.New("temporary error")
+			}
+			return nil
+		})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, attempts)
+	require.Zero(t, AttemptFromContext(context.Background()))
+}
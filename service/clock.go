@@ -0,0 +1,53 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import "time"
+
+// Clock is an abstraction over time that allows testing time-dependent workers deterministically.
+// See servicetest.FakeClock for the implementation that may be used in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer creates a new Timer that sends the current time on its channel after at least duration d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer represents a single event like time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+	// Stop prevents the Timer from firing. It returns false if the timer has already expired or been stopped.
+	Stop() bool
+}
+
+// NewRealClock returns a Clock that uses the functions from the standard time package.
+func NewRealClock() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
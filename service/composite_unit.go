@@ -10,16 +10,32 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+
+	"github.com/acronis/go-appkit/log"
 )
 
 // CompositeUnit represents a composition of service units and implements Composite design pattern.
 type CompositeUnit struct {
 	Units []Unit
+
+	// StopLogger is used for logging how long each unit takes to stop. Nothing is logged if it's nil.
+	StopLogger log.FieldLogger
+
+	// Events is used for publishing lifecycle events of the units. Nothing is published if it's nil.
+	Events *LifecycleEvents
+
+	readyOnce sync.Once
+	ready     chan struct{}
 }
 
+var _ ReadinessNotifier = (*CompositeUnit)(nil)
+var _ StopLoggerSetter = (*CompositeUnit)(nil)
+var _ LifecycleEventsSetter = (*CompositeUnit)(nil)
+var _ Reloadable = (*CompositeUnit)(nil)
+
 // NewCompositeUnit creates a new composite unit.
 func NewCompositeUnit(units ...Unit) *CompositeUnit {
-	return &CompositeUnit{units}
+	return &CompositeUnit{Units: units}
 }
 
 // Start launches all units in the composition concurrently, each in its own goroutine, by calling their Start methods.
@@ -38,7 +54,7 @@ func (cu *CompositeUnit) Start(fatalError chan<- error) {
 	runningOrFailedUnits := int32(len(cu.Units)) //nolint:gosec // unit count is reasonable
 	for i := 0; i < len(cu.Units); i++ {
 		go func(i int) {
-			cu.Units[i].Start(fatalErrs[i])
+			startUnit(cu.Units[i], fatalErrs[i], cu.Events)
 			if len(fatalErrs[i]) != 0 {
 				ok <- false
 				return
@@ -81,7 +97,7 @@ func (cu *CompositeUnit) Stop(gracefully bool) error {
 	for _, s := range cu.Units {
 		go func(s Unit) {
 			defer wg.Done()
-			results <- s.Stop(gracefully)
+			results <- stopUnit(s, gracefully, cu.StopLogger, cu.Events)
 		}(s)
 	}
 	wg.Wait()
@@ -98,6 +114,48 @@ func (cu *CompositeUnit) Stop(gracefully bool) error {
 	return nil
 }
 
+// Reload reloads all units in the composition that implement Reloadable one by one.
+// Errors that occurred while reloading the units are collected and single CompositeUnitError is returned.
+func (cu *CompositeUnit) Reload() error {
+	if errs := reloadUnits(cu.Units); len(errs) > 0 {
+		return &CompositeUnitError{errs}
+	}
+	return nil
+}
+
+// SetStopLogger sets the logger for logging how long each unit takes to stop if no logger is set yet.
+// The logger is also passed to the nested units that implement StopLoggerSetter.
+func (cu *CompositeUnit) SetStopLogger(logger log.FieldLogger) {
+	if cu.StopLogger == nil {
+		cu.StopLogger = logger
+	}
+	setStopLogger(cu.Units, logger)
+}
+
+// SetLifecycleEvents sets the dispatcher for publishing lifecycle events of the units if no dispatcher is set yet.
+// The dispatcher is also passed to the nested units that implement LifecycleEventsSetter.
+func (cu *CompositeUnit) SetLifecycleEvents(events *LifecycleEvents) {
+	if cu.Events == nil {
+		cu.Events = events
+	}
+	setLifecycleEvents(cu.Units, events)
+}
+
+// Ready returns a channel that is closed when all units in the composition are ready.
+// Units that don't implement ReadinessNotifier are considered ready immediately.
+func (cu *CompositeUnit) Ready() <-chan struct{} {
+	cu.readyOnce.Do(func() {
+		cu.ready = make(chan struct{})
+		go func() {
+			for _, unit := range cu.Units {
+				<-unitReady(unit)
+			}
+			close(cu.ready)
+		}()
+	})
+	return cu.ready
+}
+
 // MustRegisterMetrics registers metrics in Prometheus client and panics if any error occurs.
 func (cu *CompositeUnit) MustRegisterMetrics() {
 	for _, s := range cu.Units {
@@ -129,3 +187,8 @@ func (cue *CompositeUnitError) Error() string {
 	}
 	return strings.Join(msgs, "; ")
 }
+
+// Unwrap returns errors of the units, so errors.Is and errors.As functions can be used with CompositeUnitError.
+func (cue *CompositeUnitError) Unwrap() []error {
+	return cue.UnitErrors
+}
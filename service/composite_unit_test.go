@@ -161,3 +161,30 @@ func TestCompositeUnit_StartAndStop(t *testing.T) {
 		}
 	})
 }
+
+func TestCompositeUnit_Ready(t *testing.T) {
+	var runningCounter int32
+	readyUnit := newStagedMockUnit("ready", &eventsRecorder{})
+	becomeReady := make(chan struct{})
+	readyUnit.readyFunc = func(u *stagedMockUnit) {
+		<-becomeReady
+		close(u.ready)
+	}
+	compositeUnit := NewCompositeUnit(newMockUnit("plain", &runningCounter, false), readyUnit)
+
+	go compositeUnit.Start(make(chan error, 1))
+	defer func() { require.NoError(t, compositeUnit.Stop(false)) }()
+
+	select {
+	case <-compositeUnit.Ready():
+		require.Fail(t, "composite unit must not be ready")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(becomeReady)
+	select {
+	case <-compositeUnit.Ready():
+	case <-time.After(time.Second):
+		require.Fail(t, "composite unit must be ready")
+	}
+}
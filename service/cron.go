@@ -0,0 +1,334 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule describes the times when a job should be run.
+type Schedule interface {
+	// Next returns the next activation time that is later than t.
+	// Zero time is returned if there is no such time.
+	Next(t time.Time) time.Time
+}
+
+// cronSearchYearsLimit limits the search of the next activation time for schedules that are never satisfied
+// (e.g., "0 0 30 2 *").
+const cronSearchYearsLimit = 5
+
+type cronField struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	cronFieldSecond = cronField{name: "second", min: 0, max: 59}
+	cronFieldMinute = cronField{name: "minute", min: 0, max: 59}
+	cronFieldHour   = cronField{name: "hour", min: 0, max: 23}
+	cronFieldDom    = cronField{name: "day of month", min: 1, max: 31}
+	cronFieldMonth  = cronField{name: "month", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronFieldDow = cronField{name: "day of week", min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// CronScheduleOpts contains optional parameters for parsing CronSchedule.
+type CronScheduleOpts struct {
+	// Location is a time zone in which the schedule is interpreted. time.Local is used by default.
+	// It's overridden by the "CRON_TZ=" (or "TZ=") prefix in the expression.
+	Location *time.Location
+}
+
+// CronSchedule is a Schedule that is specified by a cron expression.
+type CronSchedule struct {
+	expr     string
+	second   uint64
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	domStar  bool
+	dowStar  bool
+	location *time.Location
+}
+
+// ParseCronSchedule parses a cron expression.
+//
+// Both standard 5-field ("minute hour day-of-month month day-of-week")
+// and 6-field ("second minute hour day-of-month month day-of-week") formats are supported.
+// Each field may contain "*" (or "?"), single values, ranges ("1-5"), lists ("1,3,5") and steps ("*/15", "10-50/20").
+// Months and days of week may be specified by their three-letter English names ("JAN", "MON").
+// Predefined descriptors (@yearly, @annually, @monthly, @weekly, @daily, @midnight, @hourly) are supported too.
+// The time zone may be specified via "CRON_TZ=" or "TZ=" prefix (e.g., "CRON_TZ=Europe/Berlin 0 2 * * *").
+func ParseCronSchedule(expr string) (*CronSchedule, error) {
+	return ParseCronScheduleWithOpts(expr, CronScheduleOpts{})
+}
+
+// ParseCronScheduleWithOpts is a more configurable version of ParseCronSchedule.
+func ParseCronScheduleWithOpts(expr string, opts CronScheduleOpts) (*CronSchedule, error) {
+	sched := &CronSchedule{expr: expr, location: opts.Location}
+	if sched.location == nil {
+		sched.location = time.Local
+	}
+
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		tzSpec, rest, _ := strings.Cut(spec, " ")
+		_, tzName, _ := strings.Cut(tzSpec, "=")
+		loc, err := time.LoadLocation(tzName)
+		if err != nil {
+			return nil, fmt.Errorf("load time zone %q: %w", tzName, err)
+		}
+		sched.location = loc
+		spec = strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		descriptorSpec, ok := cronDescriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("unknown cron descriptor %q", spec)
+		}
+		spec = descriptorSpec
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("cron expression %q must contain 5 or 6 fields, got %d", expr, len(fields))
+	}
+
+	var err error
+	if sched.second, _, err = parseCronField(fields[0], cronFieldSecond); err != nil {
+		return nil, err
+	}
+	if sched.minute, _, err = parseCronField(fields[1], cronFieldMinute); err != nil {
+		return nil, err
+	}
+	if sched.hour, _, err = parseCronField(fields[2], cronFieldHour); err != nil {
+		return nil, err
+	}
+	if sched.dom, sched.domStar, err = parseCronField(fields[3], cronFieldDom); err != nil {
+		return nil, err
+	}
+	if sched.month, _, err = parseCronField(fields[4], cronFieldMonth); err != nil {
+		return nil, err
+	}
+	if sched.dow, sched.dowStar, err = parseCronField(fields[5], cronFieldDow); err != nil {
+		return nil, err
+	}
+	if sched.dow&(1<<7) != 0 { // 7 is an alias for Sunday.
+		sched.dow = sched.dow&^(1<<7) | 1
+	}
+	return sched, nil
+}
+
+// MustParseCronSchedule is like ParseCronSchedule but panics if the expression cannot be parsed.
+func MustParseCronSchedule(expr string) *CronSchedule {
+	sched, err := ParseCronSchedule(expr)
+	if err != nil {
+		panic(err)
+	}
+	return sched
+}
+
+// parseCronField parses a single field of the cron expression and returns a bitset of the allowed values.
+// The second returned value is true if the field is "*" or "?" (without step).
+func parseCronField(value string, field cronField) (bits uint64, star bool, err error) {
+	for _, part := range strings.Split(value, ",") {
+		partBits, partStar, partErr := parseCronFieldPart(part, field)
+		if partErr != nil {
+			return 0, false, fmt.Errorf("parse cron %s field %q: %w", field.name, value, partErr)
+		}
+		bits |= partBits
+		star = star || partStar
+	}
+	return bits, star, nil
+}
+
+func parseCronFieldPart(part string, field cronField) (bits uint64, star bool, err error) {
+	rangeStr, stepStr, hasStep := strings.Cut(part, "/")
+
+	var start, end uint
+	switch rangeStr {
+	case "*", "?":
+		start, end = field.min, field.max
+		if field.max == cronFieldDow.max {
+			end = 6 // 7 is an alias for Sunday, no need to include it for "*".
+		}
+		star = !hasStep
+	default:
+		startStr, endStr, isRange := strings.Cut(rangeStr, "-")
+		if start, err = parseCronValue(startStr, field); err != nil {
+			return 0, false, err
+		}
+		switch {
+		case isRange:
+			if end, err = parseCronValue(endStr, field); err != nil {
+				return 0, false, err
+			}
+		case hasStep:
+			end = field.max
+		default:
+			end = start
+		}
+	}
+	if start > end {
+		return 0, false, fmt.Errorf("beginning of range (%d) is beyond the end (%d)", start, end)
+	}
+
+	step := uint64(1)
+	if hasStep {
+		if step, err = strconv.ParseUint(stepStr, 10, 8); err != nil || step == 0 {
+			return 0, false, fmt.Errorf("invalid step %q", stepStr)
+		}
+	}
+
+	for i := uint64(start); i <= uint64(end); i += step {
+		bits |= 1 << i
+	}
+	return bits, star, nil
+}
+
+func parseCronValue(s string, field cronField) (uint, error) {
+	if v, ok := field.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if uint(v) < field.min || uint(v) > field.max {
+		return 0, fmt.Errorf("value %d is out of range [%d, %d]", v, field.min, field.max)
+	}
+	return uint(v), nil
+}
+
+// String returns the cron expression of the schedule.
+func (s *CronSchedule) String() string {
+	return s.expr
+}
+
+// Location returns the time zone in which the schedule is interpreted.
+func (s *CronSchedule) Location() *time.Location {
+	return s.location
+}
+
+// Next returns the next activation time that is later than t.
+// Zero time is returned if there is no such time within the next several years.
+// The returned time is in the same location as t.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	origLocation := t.Location()
+	t = t.In(s.location)
+	loc := s.location
+
+	// Start from the next whole second.
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + cronSearchYearsLimit
+
+	// The added flag is used for resetting all smaller fields when any field is incremented.
+	added := false
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// Midnight may be skipped or repeated due to DST transition, so the hour is fixed.
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(-time.Duration(t.Hour()) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		if !added {
+			added = true
+			t = t.Add(-time.Duration(t.Second()) * time.Second)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	for s.second&(1<<uint(t.Second())) == 0 {
+		added = true
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+
+	return t.In(origLocation)
+}
+
+// dayMatches checks day of month and day of week fields.
+// As in standard cron, if both fields are restricted (not "*"), the day matches if either field matches.
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronSchedule_Next(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		expr string
+		from string
+		want []string
+	}{
+		{
+			expr: "0 2 * * *",
+			from: "2024-03-10T01:59:59Z",
+			want: []string{"2024-03-10T02:00:00Z", "2024-03-11T02:00:00Z", "2024-03-12T02:00:00Z"},
+		},
+		{
+			expr: "*/15 * * * *",
+			from: "2024-03-10T10:07:13Z",
+			want: []string{"2024-03-10T10:15:00Z", "2024-03-10T10:30:00Z", "2024-03-10T10:45:00Z", "2024-03-10T11:00:00Z"},
+		},
+		{
+			expr: "*/20 * * * * *",
+			from: "2024-03-10T10:00:50.5Z",
+			want: []string{"2024-03-10T10:01:00Z", "2024-03-10T10:01:20Z", "2024-03-10T10:01:40Z"},
+		},
+		{
+			expr: "30 9 * * MON-FRI",
+			from: "2024-03-08T10:00:00Z", // Friday.
+			want: []string{"2024-03-11T09:30:00Z", "2024-03-12T09:30:00Z"},
+		},
+		{
+			expr: "0 0 1,15 * SUN", // Either 1st or 15th day of month or Sunday.
+			from: "2024-03-01T00:00:00Z",
+			want: []string{"2024-03-03T00:00:00Z", "2024-03-10T00:00:00Z", "2024-03-15T00:00:00Z", "2024-03-17T00:00:00Z"},
+		},
+		{
+			expr: "0 12 29 feb *",
+			from: "2024-03-01T00:00:00Z",
+			want: []string{"2028-02-29T12:00:00Z"},
+		},
+		{
+			expr: "0 0 * * 7",
+			from: "2024-03-01T00:00:00Z",
+			want: []string{"2024-03-03T00:00:00Z"},
+		},
+		{
+			expr: "@monthly",
+			from: "2024-12-15T00:00:00Z",
+			want: []string{"2025-01-01T00:00:00Z", "2025-02-01T00:00:00Z"},
+		},
+		{
+			expr: "0 0 30 2 *",
+			from: "2024-01-01T00:00:00Z",
+			want: []string{""},
+		},
+		{
+			expr: "CRON_TZ=Europe/Berlin 0 2 * * *",
+			from: "2024-01-10T00:00:00Z",
+			want: []string{"2024-01-10T01:00:00Z", "2024-01-11T01:00:00Z"},
+		},
+		{
+			expr: "TZ=Europe/Berlin 30 2 * * *", // 02:30 doesn't exist on 2024-03-31 in Berlin due to DST, so it's skipped.
+			from: "2024-03-30T12:00:00Z",
+			want: []string{"2024-04-01T00:30:00Z", "2024-04-02T00:30:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			sched, err := ParseCronScheduleWithOpts(tt.expr, CronScheduleOpts{Location: time.UTC})
+			require.NoError(t, err)
+			require.Equal(t, tt.expr, sched.String())
+
+			cur, err := time.Parse(time.RFC3339Nano, tt.from)
+			require.NoError(t, err)
+			for _, wantStr := range tt.want {
+				cur = sched.Next(cur)
+				if wantStr == "" {
+					require.True(t, cur.IsZero(), "zero time is expected, got %s", cur)
+					return
+				}
+				want, parseErr := time.Parse(time.RFC3339, wantStr)
+				require.NoError(t, parseErr)
+				require.True(t, want.Equal(cur), "expected %s, got %s", want, cur)
+				require.Equal(t, time.UTC, cur.Location())
+			}
+		})
+	}
+
+	t.Run("location from opts", func(t *testing.T) {
+		sched, err := ParseCronScheduleWithOpts("0 2 * * *", CronScheduleOpts{Location: berlin})
+		require.NoError(t, err)
+		require.Equal(t, berlin, sched.Location())
+		next := sched.Next(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+		require.True(t, time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC).Equal(next), "got %s", next)
+	})
+}
+
+func TestParseCronSchedule_Errors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"* * * *", `cron expression "* * * *" must contain 5 or 6 fields, got 4`},
+		{"* * * * * * *", `cron expression "* * * * * * *" must contain 5 or 6 fields, got 7`},
+		{"60 * * * *", `parse cron minute field "60": value 60 is out of range [0, 59]`},
+		{"* 24 * * *", `parse cron hour field "24": value 24 is out of range [0, 23]`},
+		{"* * 0 * *", `parse cron day of month field "0": value 0 is out of range [1, 31]`},
+		{"* * * foo *", `parse cron month field "foo": invalid value "foo"`},
+		{"* * * * 5-1", `parse cron day of week field "5-1": beginning of range (5) is beyond the end (1)`},
+		{"*/0 * * * *", `parse cron minute field "*/0": invalid step "0"`},
+		{"@fortnightly", `unknown cron descriptor "@fortnightly"`},
+		{"CRON_TZ=Unknown/Zone * * * * *", `load time zone "Unknown/Zone": unknown time zone Unknown/Zone`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseCronSchedule(tt.expr)
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+
+	require.Panics(t, func() { MustParseCronSchedule("invalid") })
+}
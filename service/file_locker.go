@@ -0,0 +1,100 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileLocker is a Locker that uses advisory file locks (flock) for coordination of processes on a single host.
+// Each lease is represented by the "<name>.lock" file in the directory.
+// The lock is held until the lease is released or the process exits, so TTL is not used.
+// FileLocker is supported only on Unix-like systems.
+type FileLocker struct {
+	dir string
+}
+
+var _ Locker = (*FileLocker)(nil)
+
+// NewFileLocker creates a new FileLocker that keeps lock files in the given directory.
+func NewFileLocker(dir string) *FileLocker {
+	return &FileLocker{dir: dir}
+}
+
+// TryAcquire tries to lock the file for the lease with the given name.
+func (l *FileLocker) TryAcquire(_ context.Context, name string, _ time.Duration) (Lease, error) {
+	path := filepath.Join(l.dir, name+".lock")
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600) //nolint:gosec // path is built from trusted values
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	if err = tryLockFile(f); err != nil {
+		_ = f.Close()
+		if errors.Is(err, errFileLocked) {
+			return nil, ErrLeaseNotAcquired
+		}
+		return nil, fmt.Errorf("lock file %s: %w", path, err)
+	}
+	return &fileLease{path: path, file: f}, nil
+}
+
+type fileLease struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Renew checks that the lock file is still held and was not removed or replaced.
+func (ls *fileLease) Renew(_ context.Context, _ time.Duration) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.file == nil {
+		return ErrLeaseLost
+	}
+	heldInfo, err := ls.file.Stat()
+	if err != nil {
+		return fmt.Errorf("stat held lock file: %w", err)
+	}
+	curInfo, err := os.Stat(ls.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrLeaseLost
+		}
+		return fmt.Errorf("stat lock file: %w", err)
+	}
+	if !os.SameFile(heldInfo, curInfo) {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Release unlocks and closes the lock file. The file itself is not removed
+// since removing may break the mutual exclusion for the processes that have already opened it.
+func (ls *fileLease) Release(_ context.Context) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.file == nil {
+		return nil
+	}
+	unlockErr := unlockFile(ls.file)
+	closeErr := ls.file.Close()
+	ls.file = nil
+	if unlockErr != nil {
+		return fmt.Errorf("unlock file %s: %w", ls.path, unlockErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("close lock file %s: %w", ls.path, closeErr)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build !unix
+
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"errors"
+	"os"
+)
+
+// errFileLocked is returned by tryLockFile when the file is locked by another process or file descriptor.
+var errFileLocked = errors.New("file is locked")
+
+// tryLockFile is not supported on this platform.
+func tryLockFile(_ *os.File) error {
+	return errors.ErrUnsupported
+}
+
+// unlockFile is not supported on this platform.
+func unlockFile(_ *os.File) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,34 @@
+//go:build unix
+
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// errFileLocked is returned by tryLockFile when the file is locked by another process or file descriptor.
+var errFileLocked = errors.New("file is locked")
+
+// tryLockFile places an exclusive advisory lock (flock) on the file without blocking.
+func tryLockFile(f *os.File) error {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil { //nolint:gosec // fd fits int
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return errFileLocked
+		}
+		return err
+	}
+	return nil
+}
+
+// unlockFile removes the advisory lock from the file.
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN) //nolint:gosec // fd fits int
+}
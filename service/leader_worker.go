@@ -0,0 +1,182 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/acronis/go-appkit/log"
+)
+
+// DefaultLeaderLeaseTTL is a default TTL of the lease acquired by LeaderWorker.
+const DefaultLeaderLeaseTTL = 15 * time.Second
+
+// LeaderWorkerOpts contains optional parameters for constructing LeaderWorker.
+type LeaderWorkerOpts struct {
+	// LeaseTTL is a TTL of the lease. DefaultLeaderLeaseTTL is used by default.
+	LeaseTTL time.Duration
+
+	// RenewInterval is an interval of the lease renewal. One third of LeaseTTL is used by default.
+	RenewInterval time.Duration
+
+	// AcquireInterval is an interval of attempts to acquire the lease while it's held by someone else.
+	// RenewInterval is used by default.
+	AcquireInterval time.Duration
+
+	// Clock is used for getting the current time and creating timers. The real clock is used by default.
+	Clock Clock
+}
+
+// LeaderWorker represents a worker that runs the underlying worker only while it holds the lease (i.e., it's a leader).
+// It allows running singleton workers (e.g., cleanup jobs) when several replicas of the service are deployed.
+//
+// The lease is renewed periodically. If the renewal fails with ErrLeaseLost or the lease may expire before
+// the next renewal attempt (i.e., renewals keep failing for LeaseTTL - RenewInterval), the leadership is considered lost,
+// the context of the underlying worker is canceled, and LeaderWorker tries to acquire the lease again
+// after the worker returns.
+type LeaderWorker struct {
+	worker          Worker
+	locker          Locker
+	leaseName       string
+	logger          log.FieldLogger
+	leaseTTL        time.Duration
+	renewInterval   time.Duration
+	acquireInterval time.Duration
+	clock           Clock
+
+	isLeader atomic.Bool
+}
+
+// NewLeaderWorker creates a new instance of LeaderWorker with default options.
+func NewLeaderWorker(worker Worker, locker Locker, leaseName string, logger log.FieldLogger) *LeaderWorker {
+	return NewLeaderWorkerWithOpts(worker, locker, leaseName, logger, LeaderWorkerOpts{})
+}
+
+// NewLeaderWorkerWithOpts creates a new instance of LeaderWorker with an ability to specify different optional parameters.
+func NewLeaderWorkerWithOpts(
+	worker Worker, locker Locker, leaseName string, logger log.FieldLogger, opts LeaderWorkerOpts,
+) *LeaderWorker {
+	if opts.LeaseTTL <= 0 {
+		opts.LeaseTTL = DefaultLeaderLeaseTTL
+	}
+	if opts.RenewInterval <= 0 {
+		opts.RenewInterval = opts.LeaseTTL / 3
+	}
+	if opts.AcquireInterval <= 0 {
+		opts.AcquireInterval = opts.RenewInterval
+	}
+	if opts.Clock == nil {
+		opts.Clock = NewRealClock()
+	}
+	return &LeaderWorker{
+		worker:          worker,
+		locker:          locker,
+		leaseName:       leaseName,
+		logger:          logger.With(log.String("lease", leaseName)),
+		leaseTTL:        opts.LeaseTTL,
+		renewInterval:   opts.RenewInterval,
+		acquireInterval: opts.AcquireInterval,
+		clock:           opts.Clock,
+	}
+}
+
+// IsLeader returns true if the worker holds the lease at the moment.
+func (lw *LeaderWorker) IsLeader() bool {
+	return lw.isLeader.Load()
+}
+
+// Run tries to acquire the lease periodically and runs the underlying worker while the lease is held.
+// It returns when the context is canceled or the underlying worker returns by itself
+// (ErrPeriodicWorkerStop is treated as a successful completion).
+func (lw *LeaderWorker) Run(ctx context.Context) error {
+	for {
+		lease, err := lw.locker.TryAcquire(ctx, lw.leaseName, lw.leaseTTL)
+		switch {
+		case err == nil:
+			lw.logger.Info("leadership is acquired")
+			finished, runErr := lw.runAsLeader(ctx, lease)
+			if finished {
+				if errors.Is(runErr, ErrPeriodicWorkerStop) {
+					return nil
+				}
+				return runErr
+			}
+		case errors.Is(err, ErrLeaseNotAcquired):
+		default:
+			lw.logger.Error("failed to acquire lease", log.Error(err))
+		}
+
+		if !lw.sleep(ctx, lw.acquireInterval) {
+			return nil
+		}
+	}
+}
+
+// runAsLeader runs the worker while the lease is held.
+// It returns finished=true if the worker returned by itself or the context was canceled.
+func (lw *LeaderWorker) runAsLeader(ctx context.Context, lease Lease) (finished bool, err error) {
+	lw.isLeader.Store(true)
+	defer func() {
+		lw.isLeader.Store(false)
+		if releaseErr := lease.Release(context.WithoutCancel(ctx)); releaseErr != nil {
+			lw.logger.Error("failed to release lease", log.Error(releaseErr))
+		}
+	}()
+
+	workerCtx, workerCancel := context.WithCancel(ctx)
+	defer workerCancel()
+	workerDone := make(chan error, 1)
+	go func() {
+		workerDone <- lw.worker.Run(workerCtx)
+	}()
+
+	lastRenewedAt := lw.clock.Now()
+	for {
+		timer := lw.clock.NewTimer(lw.renewInterval)
+		select {
+		case err = <-workerDone:
+			timer.Stop()
+			return true, err
+		case <-ctx.Done():
+			timer.Stop()
+			return true, <-workerDone
+		case <-timer.C():
+		}
+
+		renewErr := lease.Renew(ctx, lw.leaseTTL)
+		if renewErr == nil {
+			lastRenewedAt = lw.clock.Now()
+			continue
+		}
+		// The worker must not run after the lease expires, so the leadership is given up
+		// if the lease may expire before the next renewal attempt.
+		if !errors.Is(renewErr, ErrLeaseLost) && lw.clock.Now().Sub(lastRenewedAt)+lw.renewInterval < lw.leaseTTL {
+			lw.logger.Warn("failed to renew lease, will retry", log.Error(renewErr))
+			continue
+		}
+		lw.logger.Warn("leadership is lost, worker will be stopped", log.Error(renewErr))
+		workerCancel()
+		if err = <-workerDone; err != nil && !errors.Is(err, context.Canceled) {
+			lw.logger.Error("worker finished with error after leadership loss", log.Error(err))
+		}
+		return false, nil
+	}
+}
+
+func (lw *LeaderWorker) sleep(ctx context.Context, d time.Duration) bool {
+	timer := lw.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
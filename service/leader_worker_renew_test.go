@@ -0,0 +1,86 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/acronis/go-appkit/log"
+	"github.com/acronis/go-appkit/log/logtest"
+	"github.com/acronis/go-appkit/service"
+	"github.com/acronis/go-appkit/service/servicetest"
+)
+
+type flakyLocker struct {
+	acquired atomic.Bool
+	renewErr error
+}
+
+func (l *flakyLocker) TryAcquire(ctx context.Context, name string, ttl time.Duration) (service.Lease, error) {
+	if !l.acquired.CompareAndSwap(false, true) {
+		return nil, service.ErrLeaseNotAcquired
+	}
+	return l, nil
+}
+
+func (l *flakyLocker) Renew(ctx context.Context, ttl time.Duration) error {
+	return l.renewErr
+}
+
+func (l *flakyLocker) Release(ctx context.Context) error {
+	return nil
+}
+
+func TestLeaderWorker_TransientRenewErrors(t *testing.T) {
+	startTime := time.Date(2024, 3, 10, 10, 7, 0, 0, time.UTC)
+	clock := servicetest.NewFakeClock(startTime)
+	locker := &flakyLocker{renewErr: errors.New("storage is unavailable")}
+	logRecorder := logtest.NewRecorder()
+	workerCanceled := make(chan time.Time, 1)
+	worker := service.WorkerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		workerCanceled <- clock.Now()
+		return nil
+	})
+	const leaseTTL = time.Second * 30
+	lw := service.NewLeaderWorkerWithOpts(worker, locker, "cleanup", logRecorder,
+		service.LeaderWorkerOpts{LeaseTTL: leaseTTL, RenewInterval: time.Second * 10, Clock: clock})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- lw.Run(ctx) }()
+
+	// The first renewal fails, but the lease is still valid for the next attempt.
+	clock.BlockUntilActiveTimers(1)
+	clock.Advance(time.Second * 10)
+	clock.BlockUntilActiveTimers(1)
+	require.True(t, lw.IsLeader())
+	logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelWarn),
+		logtest.HasMessage("failed to renew lease, will retry"))
+
+	// The second renewal fails, and the lease may expire before the next attempt.
+	clock.Advance(time.Second * 10)
+	select {
+	case canceledAt := <-workerCanceled:
+		require.True(t, canceledAt.Before(startTime.Add(leaseTTL)), "worker must be stopped before the lease expires")
+	case <-time.After(time.Second):
+		require.Fail(t, "worker is not canceled")
+	}
+	logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelWarn),
+		logtest.HasMessage("leadership is lost, worker will be stopped"))
+	require.Eventually(t, func() bool { return !lw.IsLeader() }, time.Second, time.Millisecond*5)
+
+	cancel()
+	require.NoError(t, <-runErr)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/acronis/go-appkit/log"
+	"github.com/acronis/go-appkit/log/logtest"
+)
+
+func TestLeaderWorker_Run(t *testing.T) {
+	opts := LeaderWorkerOpts{LeaseTTL: time.Millisecond * 60, RenewInterval: time.Millisecond * 10}
+
+	t.Run("only one replica runs the worker", func(t *testing.T) {
+		locker := NewInMemoryLocker()
+		var running, maxRunning atomic.Int32
+		worker := WorkerFunc(func(ctx context.Context) error {
+			cur := running.Add(1)
+			defer running.Add(-1)
+			if cur > maxRunning.Load() {
+				maxRunning.Store(cur)
+			}
+			<-ctx.Done()
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		replicas := []*LeaderWorker{
+			NewLeaderWorkerWithOpts(worker, locker, "cleanup", log.NewDisabledLogger(), opts),
+			NewLeaderWorkerWithOpts(worker, locker, "cleanup", log.NewDisabledLogger(), opts),
+		}
+		runErrs := make(chan error, len(replicas))
+		for _, lw := range replicas {
+			go func(lw *LeaderWorker) { runErrs <- lw.Run(ctx) }(lw)
+		}
+
+		require.Eventually(t, func() bool { return running.Load() == 1 }, time.Second, time.Millisecond*5)
+		require.Never(t, func() bool { return running.Load() > 1 }, time.Millisecond*100, time.Millisecond*5)
+		require.NotEqual(t, replicas[0].IsLeader(), replicas[1].IsLeader())
+
+		cancel()
+		for range replicas {
+			require.NoError(t, <-runErrs)
+		}
+		require.Equal(t, int32(1), maxRunning.Load())
+		require.False(t, replicas[0].IsLeader())
+		require.False(t, replicas[1].IsLeader())
+
+		_, err := locker.TryAcquire(context.Background(), "cleanup", time.Second)
+		require.NoError(t, err, "lease must be released")
+	})
+
+	t.Run("worker is canceled on leadership loss", func(t *testing.T) {
+		locker := NewInMemoryLocker()
+		logRecorder := logtest.NewRecorder()
+		var runs atomic.Int32
+		workerCanceled := make(chan struct{}, 10)
+		worker := WorkerFunc(func(ctx context.Context) error {
+			runs.Add(1)
+			<-ctx.Done()
+			workerCanceled <- struct{}{}
+			return nil
+		})
+		lw := NewLeaderWorkerWithOpts(worker, locker, "cleanup", logRecorder, opts)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		runErr := make(chan error, 1)
+		go func() { runErr <- lw.Run(ctx) }()
+
+		require.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, time.Millisecond*5)
+		locker.Revoke("cleanup")
+
+		select {
+		case <-workerCanceled:
+		case <-time.After(time.Second):
+			require.Fail(t, "worker is not canceled")
+		}
+		logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelWarn),
+			logtest.HasMessage("leadership is lost, worker will be stopped"), logtest.FieldEquals("lease", "cleanup"))
+
+		// The lease is free, so it's acquired again.
+		require.Eventually(t, func() bool { return runs.Load() == 2 && lw.IsLeader() }, time.Second, time.Millisecond*5)
+		cancel()
+		require.NoError(t, <-runErr)
+	})
+
+	t.Run("worker returns by itself", func(t *testing.T) {
+		locker := NewInMemoryLocker()
+		internalErr := errors.New("internal error")
+		lw := NewLeaderWorkerWithOpts(WorkerFunc(func(ctx context.Context) error {
+			return internalErr
+		}), locker, "cleanup", log.NewDisabledLogger(), opts)
+		require.ErrorIs(t, lw.Run(context.Background()), internalErr)
+
+		lw = NewLeaderWorkerWithOpts(WorkerFunc(func(ctx context.Context) error {
+			return ErrPeriodicWorkerStop
+		}), locker, "cleanup", log.NewDisabledLogger(), opts)
+		require.NoError(t, lw.Run(context.Background()))
+
+		_, err := locker.TryAcquire(context.Background(), "cleanup", time.Second)
+		require.NoError(t, err, "lease must be released")
+	})
+}
@@ -0,0 +1,75 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+// LifecycleState represents a state of the service lifecycle.
+type LifecycleState int32
+
+// Lifecycle states.
+const (
+	// LifecycleStateNew means that the service is created but is not started yet.
+	LifecycleStateNew LifecycleState = iota
+	// LifecycleStateStarting means that the service unit is starting but is not ready yet.
+	LifecycleStateStarting
+	// LifecycleStateReady means that the service unit is started and ready to serve.
+	LifecycleStateReady
+	// LifecycleStateDraining means that the service is going to be stopped gracefully,
+	// it should not receive new work anymore, but in-flight work is still being completed.
+	LifecycleStateDraining
+	// LifecycleStateStopped means that the service unit is stopped (gracefully or because of a fatal error).
+	LifecycleStateStopped
+)
+
+// String returns a string representation of the lifecycle state.
+func (s LifecycleState) String() string {
+	switch s {
+	case LifecycleStateNew:
+		return "new"
+	case LifecycleStateStarting:
+		return "starting"
+	case LifecycleStateReady:
+		return "ready"
+	case LifecycleStateDraining:
+		return "draining"
+	case LifecycleStateStopped:
+		return "stopped"
+	}
+	return "unknown"
+}
+
+// IsAlive returns true if the service is not stopped.
+// It's supposed to be used for liveness probes, so the draining service is still alive.
+func (s LifecycleState) IsAlive() bool {
+	return s != LifecycleStateStopped
+}
+
+// IsReady returns true if the service is ready to serve.
+// It's supposed to be used for readiness probes.
+func (s LifecycleState) IsReady() bool {
+	return s == LifecycleStateReady
+}
+
+// LifecycleStateProvider is an interface for objects that provide the current lifecycle state (e.g., Service).
+type LifecycleStateProvider interface {
+	State() LifecycleState
+}
+
+// closedChan is a closed channel that is used for units that don't implement ReadinessNotifier.
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// unitReady returns a channel that is closed when the unit becomes ready.
+// Units that don't implement ReadinessNotifier are considered ready immediately.
+func unitReady(unit Unit) <-chan struct{} {
+	if rn, ok := unit.(ReadinessNotifier); ok {
+		return rn.Ready()
+	}
+	return closedChan
+}
@@ -0,0 +1,194 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// LifecycleEventType represents a type of the lifecycle event.
+type LifecycleEventType int
+
+// Lifecycle event types.
+const (
+	// LifecycleEventServiceStateChanged is published when the lifecycle state of Service is changed.
+	LifecycleEventServiceStateChanged LifecycleEventType = iota
+	// LifecycleEventUnitReady is published when the unit becomes ready. Duration is the time since the unit was started.
+	LifecycleEventUnitReady
+	// LifecycleEventUnitStopped is published when the Stop method of the unit returns.
+	// Duration is the time spent in the Stop method.
+	LifecycleEventUnitStopped
+	// LifecycleEventUnitFatalError is published when the unit reports a fatal error.
+	LifecycleEventUnitFatalError
+	// LifecycleEventWorkerRunFinished is published when a single run of PeriodicWorker is finished.
+	// Duration is the time of the run.
+	LifecycleEventWorkerRunFinished
+)
+
+// String returns a string representation of the lifecycle event type.
+func (t LifecycleEventType) String() string {
+	switch t {
+	case LifecycleEventServiceStateChanged:
+		return "service_state_changed"
+	case LifecycleEventUnitReady:
+		return "unit_ready"
+	case LifecycleEventUnitStopped:
+		return "unit_stopped"
+	case LifecycleEventUnitFatalError:
+		return "unit_fatal_error"
+	case LifecycleEventWorkerRunFinished:
+		return "worker_run_finished"
+	}
+	return "unknown"
+}
+
+// LifecycleEvent represents an event in the lifecycle of the service, its units or workers.
+// Only the fields that make sense for the event type are filled.
+type LifecycleEvent struct {
+	Type LifecycleEventType
+	Time time.Time
+
+	// State is the new lifecycle state of Service (LifecycleEventServiceStateChanged).
+	State LifecycleState
+
+	// Unit is a name of the unit or the worker (see NewNamedUnit and PeriodicWorkerOpts.Name).
+	Unit string
+
+	Duration   time.Duration
+	Gracefully bool
+	Err        error
+	Panicked   bool
+}
+
+// LifecycleListener is an interface for subscribers of lifecycle events (e.g., LifecyclePrometheusMetrics).
+// Events are delivered synchronously, so OnLifecycleEvent should not block.
+type LifecycleListener interface {
+	OnLifecycleEvent(event LifecycleEvent)
+}
+
+// LifecycleListenerFunc is an adapter to allow the use of ordinary functions as LifecycleListener.
+type LifecycleListenerFunc func(event LifecycleEvent)
+
+// OnLifecycleEvent is a part of LifecycleListener interface.
+func (f LifecycleListenerFunc) OnLifecycleEvent(event LifecycleEvent) {
+	f(event)
+}
+
+// LifecycleEvents dispatches lifecycle events to the subscribed listeners.
+// All methods are safe for concurrent use, Publish may be called on nil *LifecycleEvents.
+type LifecycleEvents struct {
+	mu             sync.RWMutex
+	listeners      map[uint64]LifecycleListener
+	lastListenerID uint64
+}
+
+// NewLifecycleEvents creates a new LifecycleEvents.
+func NewLifecycleEvents() *LifecycleEvents {
+	return &LifecycleEvents{listeners: make(map[uint64]LifecycleListener)}
+}
+
+// Subscribe adds the listener. The returned function removes it.
+// Nothing is subscribed if the receiver is nil.
+func (e *LifecycleEvents) Subscribe(listener LifecycleListener) (unsubscribe func()) {
+	if e == nil {
+		return func() {}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastListenerID++
+	id := e.lastListenerID
+	e.listeners[id] = listener
+	return func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		delete(e.listeners, id)
+	}
+}
+
+// Publish delivers the event to all listeners. The Time field is set to the current time if it's zero.
+func (e *LifecycleEvents) Publish(event LifecycleEvent) {
+	if e == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, listener := range e.listeners {
+		listener.OnLifecycleEvent(event)
+	}
+}
+
+// LifecycleEventsSetter is an interface for composite units that publish lifecycle events of their units.
+// Service passes its own LifecycleEvents to the unit if it implements this interface.
+type LifecycleEventsSetter interface {
+	// SetLifecycleEvents sets the events dispatcher if no dispatcher is set yet.
+	SetLifecycleEvents(events *LifecycleEvents)
+}
+
+func setLifecycleEvents(units []Unit, events *LifecycleEvents) {
+	for _, unit := range units {
+		if les, ok := unit.(LifecycleEventsSetter); ok {
+			les.SetLifecycleEvents(events)
+		}
+	}
+}
+
+// startUnit starts the unit and publishes LifecycleEventUnitReady and LifecycleEventUnitFatalError events.
+// It blocks until the Start method of the unit returns.
+// The unit writes to its own buffered channel, so the fatal error is published before it's forwarded
+// to the provided channel, and nobody else can read it in between.
+func startUnit(unit Unit, fatalErr chan<- error, events *LifecycleEvents) {
+	if events == nil {
+		unit.Start(fatalErr)
+		return
+	}
+
+	name := unitName(unit)
+	startTime := time.Now()
+	startReturned := make(chan struct{})
+	readyWatched := make(chan struct{})
+	go func() {
+		defer close(readyWatched)
+		ready := unitReady(unit)
+		select {
+		case <-ready:
+		case <-startReturned:
+			select {
+			case <-ready:
+			default:
+				return
+			}
+		}
+		events.Publish(LifecycleEvent{Type: LifecycleEventUnitReady, Unit: name, Duration: time.Since(startTime)})
+	}()
+
+	unitFatalErr := make(chan error, 1)
+	errForwarded := make(chan struct{})
+	go func() {
+		defer close(errForwarded)
+		var err error
+		select {
+		case err = <-unitFatalErr:
+		case <-startReturned:
+			select {
+			case err = <-unitFatalErr:
+			default:
+				return
+			}
+		}
+		events.Publish(LifecycleEvent{Type: LifecycleEventUnitFatalError, Unit: name, Err: err})
+		fatalErr <- err
+	}()
+
+	unit.Start(unitFatalErr)
+	close(startReturned)
+	<-errForwarded
+	<-readyWatched
+}
@@ -0,0 +1,211 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/acronis/go-appkit/log"
+	"github.com/acronis/go-appkit/log/logtest"
+)
+
+type eventsCollector struct {
+	mu     sync.Mutex
+	events []LifecycleEvent
+}
+
+func (c *eventsCollector) OnLifecycleEvent(event LifecycleEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, event)
+}
+
+func (c *eventsCollector) find(eventType LifecycleEventType, unit string) []LifecycleEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var res []LifecycleEvent
+	for _, e := range c.events {
+		if e.Type == eventType && e.Unit == unit {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func (c *eventsCollector) states() []LifecycleState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var res []LifecycleState
+	for _, e := range c.events {
+		if e.Type == LifecycleEventServiceStateChanged {
+			res = append(res, e.State)
+		}
+	}
+	return res
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	events := NewLifecycleEvents()
+	var received []LifecycleEventType
+	unsubscribe := events.Subscribe(LifecycleListenerFunc(func(event LifecycleEvent) {
+		require.False(t, event.Time.IsZero())
+		received = append(received, event.Type)
+	}))
+	events.Publish(LifecycleEvent{Type: LifecycleEventUnitReady})
+	unsubscribe()
+	events.Publish(LifecycleEvent{Type: LifecycleEventUnitStopped})
+	require.Equal(t, []LifecycleEventType{LifecycleEventUnitReady}, received)
+
+	var nilEvents *LifecycleEvents
+	require.NotPanics(t, func() { nilEvents.Publish(LifecycleEvent{}) })
+	require.NotPanics(t, func() { nilEvents.Subscribe(&eventsCollector{})() })
+
+	service := &Service{Unit: newStagedMockUnit("srv", &eventsRecorder{})}
+	require.NotPanics(t, func() { service.Subscribe(&eventsCollector{})() })
+}
+
+func TestService_LifecycleEventsAndMetrics(t *testing.T) {
+	t.Run("composite unit", func(t *testing.T) {
+		collector := &eventsCollector{}
+		metrics := NewLifecyclePrometheusMetrics()
+		unit := NewCompositeUnit(
+			NewNamedUnit("db", newStagedMockUnit("db", &eventsRecorder{})),
+			NewNamedUnit("api", newStagedMockUnit("api", &eventsRecorder{})),
+		)
+		service := NewWithOpts(logtest.NewRecorder(), unit, Opts{
+			ShutdownSignals:    []os.Signal{os.Interrupt},
+			LifecycleListeners: []LifecycleListener{metrics},
+		})
+		service.Subscribe(collector)
+
+		startErr := make(chan error, 1)
+		go func() { startErr <- service.Start() }()
+		require.Eventually(t, func() bool { return service.State() == LifecycleStateReady },
+			time.Second, time.Millisecond*10)
+		require.Len(t, collector.find(LifecycleEventUnitReady, "db"), 1)
+		require.Len(t, collector.find(LifecycleEventUnitReady, "api"), 1)
+		require.Equal(t, 1.0, testutil.ToFloat64(metrics.UnitUp.WithLabelValues("api")))
+		require.Equal(t, 1.0, testutil.ToFloat64(metrics.ServiceState.WithLabelValues("ready")))
+
+		service.Signals <- os.Interrupt
+		require.NoError(t, <-startErr)
+
+		require.Equal(t, []LifecycleState{
+			LifecycleStateStarting, LifecycleStateReady, LifecycleStateDraining, LifecycleStateStopped,
+		}, collector.states())
+		stopEvents := collector.find(LifecycleEventUnitStopped, "db")
+		require.Len(t, stopEvents, 1)
+		require.True(t, stopEvents[0].Gracefully)
+		require.Empty(t, collector.find(LifecycleEventUnitReady, "*service.CompositeUnit"))
+
+		require.Equal(t, 0.0, testutil.ToFloat64(metrics.UnitUp.WithLabelValues("db")))
+		require.Equal(t, 1.0, testutil.ToFloat64(metrics.ServiceState.WithLabelValues("stopped")))
+		require.Equal(t, 0.0, testutil.ToFloat64(metrics.ServiceState.WithLabelValues("ready")))
+		require.Equal(t, 2, testutil.CollectAndCount(metrics.UnitStartDuration))
+		require.Equal(t, 2, testutil.CollectAndCount(metrics.UnitStopDuration))
+	})
+
+	t.Run("fatal error", func(t *testing.T) {
+		collector := &eventsCollector{}
+		metrics := NewLifecyclePrometheusMetrics()
+		failing := newStagedMockUnit("failing", &eventsRecorder{})
+		failing.startErr = errors.New("start error")
+		unit := NewStagedCompositeUnit(
+			NewStage("storage", NewNamedUnit("db", newStagedMockUnit("db", &eventsRecorder{}))),
+			NewStage("api", NewNamedUnit("failing", failing)),
+		)
+		service := NewWithOpts(logtest.NewRecorder(), unit, Opts{
+			LifecycleListeners: []LifecycleListener{metrics, collector},
+		})
+
+		require.ErrorContains(t, service.Start(), "start error")
+		fatalEvents := collector.find(LifecycleEventUnitFatalError, "failing")
+		require.Len(t, fatalEvents, 1)
+		require.EqualError(t, fatalEvents[0].Err, "start error")
+		require.Len(t, collector.find(LifecycleEventUnitStopped, "db"), 1)
+		require.Equal(t, 1.0, testutil.ToFloat64(metrics.UnitFatalErrors.WithLabelValues("failing")))
+	})
+
+	t.Run("fatal error, single unit", func(t *testing.T) {
+		collector := &eventsCollector{}
+		metrics := NewLifecyclePrometheusMetrics()
+		failing := newStagedMockUnit("failing", &eventsRecorder{})
+		failing.startErr = errors.New("start error")
+		service := NewWithOpts(logtest.NewRecorder(), NewNamedUnit("failing", failing), Opts{
+			LifecycleListeners: []LifecycleListener{metrics, collector},
+		})
+
+		require.ErrorContains(t, service.Start(), "start error")
+		fatalEvents := collector.find(LifecycleEventUnitFatalError, "failing")
+		require.Len(t, fatalEvents, 1)
+		require.EqualError(t, fatalEvents[0].Err, "start error")
+		require.Empty(t, collector.find(LifecycleEventUnitReady, "failing"))
+		require.Equal(t, 1.0, testutil.ToFloat64(metrics.UnitFatalErrors.WithLabelValues("failing")))
+	})
+
+	t.Run("single unit", func(t *testing.T) {
+		collector := &eventsCollector{}
+		service := NewWithOpts(logtest.NewRecorder(), NewNamedUnit("srv", newStagedMockUnit("srv", &eventsRecorder{})),
+			Opts{ShutdownSignals: []os.Signal{os.Interrupt}, LifecycleListeners: []LifecycleListener{collector}})
+
+		startErr := make(chan error, 1)
+		go func() { startErr <- service.Start() }()
+		require.Eventually(t, func() bool { return service.State() == LifecycleStateReady },
+			time.Second, time.Millisecond*10)
+		service.Signals <- os.Interrupt
+		require.NoError(t, <-startErr)
+
+		require.Len(t, collector.find(LifecycleEventUnitReady, "srv"), 1)
+		require.Len(t, collector.find(LifecycleEventUnitStopped, "srv"), 1)
+	})
+}
+
+func TestPeriodicWorker_LifecycleEvents(t *testing.T) {
+	events := NewLifecycleEvents()
+	metrics := NewLifecyclePrometheusMetrics()
+	collector := &eventsCollector{}
+	events.Subscribe(metrics)
+	events.Subscribe(collector)
+
+	var runs int
+	worker := WorkerFunc(func(ctx context.Context) error {
+		runs++
+		switch runs {
+		case 1:
+			return nil
+		case 2:
+			return errors.New("internal error")
+		}
+		return ErrPeriodicWorkerStop
+	})
+	pw := NewPeriodicWorkerWithOpts(worker, time.Millisecond, log.NewDisabledLogger(),
+		PeriodicWorkerOpts{Name: "cleanup", LifecycleEvents: events})
+	require.NoError(t, pw.Run(context.Background()))
+
+	var outcomes []string
+	for _, e := range collector.find(LifecycleEventWorkerRunFinished, "cleanup") {
+		outcomes = append(outcomes, workerRunOutcome(e))
+	}
+	require.Equal(t, []string{WorkerRunOutcomeSuccess, WorkerRunOutcomeError, WorkerRunOutcomeSuccess}, outcomes)
+	require.Equal(t, 2, testutil.CollectAndCount(metrics.WorkerRunDuration)) // success and error series.
+
+	panicking := NewPeriodicWorkerWithOpts(WorkerFunc(func(ctx context.Context) error { panic("boom") }),
+		time.Millisecond, log.NewDisabledLogger(), PeriodicWorkerOpts{Name: "panicking", LifecycleEvents: events})
+	require.Panics(t, func() { _ = panicking.Run(context.Background()) })
+	panicEvents := collector.find(LifecycleEventWorkerRunFinished, "panicking")
+	require.Len(t, panicEvents, 1)
+	require.Equal(t, WorkerRunOutcomePanic, workerRunOutcome(panicEvents[0]))
+	require.Equal(t, 3, testutil.CollectAndCount(metrics.WorkerRunDuration))
+}
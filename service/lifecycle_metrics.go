@@ -0,0 +1,166 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/acronis/go-appkit/internal/libinfo"
+)
+
+// DefaultLifecycleDurationBuckets is default buckets into which observations of start/stop and run durations are counted.
+var DefaultLifecycleDurationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 150, 300, 600}
+
+// Outcomes of PeriodicWorker runs that are used in LifecyclePrometheusMetrics.
+const (
+	WorkerRunOutcomeSuccess = "success"
+	WorkerRunOutcomeError   = "error"
+	WorkerRunOutcomePanic   = "panic"
+)
+
+// LifecyclePrometheusMetricsOpts represents options for LifecyclePrometheusMetrics.
+type LifecyclePrometheusMetricsOpts struct {
+	// Namespace is a namespace for metrics. It will be prepended to all metric names.
+	Namespace string
+
+	// DurationBuckets is a list of buckets into which observations of durations are counted.
+	// DefaultLifecycleDurationBuckets is used if nil.
+	DurationBuckets []float64
+
+	// ConstLabels is a set of labels that will be applied to all metrics.
+	ConstLabels prometheus.Labels
+}
+
+// LifecyclePrometheusMetrics represents a Prometheus metrics of the service lifecycle.
+// It's a LifecycleListener, so it may be passed to Opts.LifecycleListeners or subscribed via Service.Subscribe.
+type LifecyclePrometheusMetrics struct {
+	ServiceState      *prometheus.GaugeVec
+	UnitUp            *prometheus.GaugeVec
+	UnitStartDuration *prometheus.HistogramVec
+	UnitStopDuration  *prometheus.HistogramVec
+	UnitFatalErrors   *prometheus.CounterVec
+	WorkerRunDuration *prometheus.HistogramVec
+}
+
+var _ LifecycleListener = (*LifecyclePrometheusMetrics)(nil)
+
+// NewLifecyclePrometheusMetrics creates a new instance of LifecyclePrometheusMetrics with default options.
+func NewLifecyclePrometheusMetrics() *LifecyclePrometheusMetrics {
+	return NewLifecyclePrometheusMetricsWithOpts(LifecyclePrometheusMetricsOpts{})
+}
+
+// NewLifecyclePrometheusMetricsWithOpts creates a new instance of LifecyclePrometheusMetrics with the provided options.
+func NewLifecyclePrometheusMetricsWithOpts(opts LifecyclePrometheusMetricsOpts) *LifecyclePrometheusMetrics {
+	durBuckets := opts.DurationBuckets
+	if durBuckets == nil {
+		durBuckets = DefaultLifecycleDurationBuckets
+	}
+	constLabels := libinfo.AddPrometheusLibVersionLabel(opts.ConstLabels)
+	return &LifecyclePrometheusMetrics{
+		ServiceState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "service_lifecycle_state",
+			Help:        "Current lifecycle state of the service (1 for the current state, 0 for others).",
+			ConstLabels: constLabels,
+		}, []string{"state"}),
+		UnitUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "service_unit_up",
+			Help:        "Whether the service unit is ready (1) or stopped/failed (0).",
+			ConstLabels: constLabels,
+		}, []string{"unit"}),
+		UnitStartDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Name:        "service_unit_start_duration_seconds",
+			Help:        "A histogram of the time it takes the service unit to become ready.",
+			Buckets:     durBuckets,
+			ConstLabels: constLabels,
+		}, []string{"unit"}),
+		UnitStopDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Name:        "service_unit_stop_duration_seconds",
+			Help:        "A histogram of the time it takes the service unit to stop.",
+			Buckets:     durBuckets,
+			ConstLabels: constLabels,
+		}, []string{"unit", "gracefully"}),
+		UnitFatalErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "service_unit_fatal_errors_total",
+			Help:        "Number of fatal errors reported by the service unit.",
+			ConstLabels: constLabels,
+		}, []string{"unit"}),
+		WorkerRunDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Name:        "periodic_worker_run_duration_seconds",
+			Help:        "A histogram of the periodic worker run durations.",
+			Buckets:     durBuckets,
+			ConstLabels: constLabels,
+		}, []string{"worker", "outcome"}),
+	}
+}
+
+// OnLifecycleEvent updates metrics according to the lifecycle event.
+func (pm *LifecyclePrometheusMetrics) OnLifecycleEvent(event LifecycleEvent) {
+	switch event.Type {
+	case LifecycleEventServiceStateChanged:
+		for _, state := range []LifecycleState{
+			LifecycleStateStarting, LifecycleStateReady, LifecycleStateDraining, LifecycleStateStopped,
+		} {
+			var val float64
+			if state == event.State {
+				val = 1
+			}
+			pm.ServiceState.WithLabelValues(state.String()).Set(val)
+		}
+	case LifecycleEventUnitReady:
+		pm.UnitUp.WithLabelValues(event.Unit).Set(1)
+		pm.UnitStartDuration.WithLabelValues(event.Unit).Observe(event.Duration.Seconds())
+	case LifecycleEventUnitStopped:
+		pm.UnitUp.WithLabelValues(event.Unit).Set(0)
+		pm.UnitStopDuration.WithLabelValues(event.Unit, strconv.FormatBool(event.Gracefully)).Observe(event.Duration.Seconds())
+	case LifecycleEventUnitFatalError:
+		pm.UnitUp.WithLabelValues(event.Unit).Set(0)
+		pm.UnitFatalErrors.WithLabelValues(event.Unit).Inc()
+	case LifecycleEventWorkerRunFinished:
+		pm.WorkerRunDuration.WithLabelValues(event.Unit, workerRunOutcome(event)).Observe(event.Duration.Seconds())
+	}
+}
+
+func workerRunOutcome(event LifecycleEvent) string {
+	switch {
+	case event.Panicked:
+		return WorkerRunOutcomePanic
+	case event.Err != nil && !errors.Is(event.Err, ErrPeriodicWorkerStop):
+		return WorkerRunOutcomeError
+	}
+	return WorkerRunOutcomeSuccess
+}
+
+// MustRegister does registration of metrics collector in Prometheus and panics if any error occurs.
+func (pm *LifecyclePrometheusMetrics) MustRegister() {
+	prometheus.MustRegister(
+		pm.ServiceState,
+		pm.UnitUp,
+		pm.UnitStartDuration,
+		pm.UnitStopDuration,
+		pm.UnitFatalErrors,
+		pm.WorkerRunDuration,
+	)
+}
+
+// Unregister cancels registration of metrics collector in Prometheus.
+func (pm *LifecyclePrometheusMetrics) Unregister() {
+	prometheus.Unregister(pm.ServiceState)
+	prometheus.Unregister(pm.UnitUp)
+	prometheus.Unregister(pm.UnitStartDuration)
+	prometheus.Unregister(pm.UnitStopDuration)
+	prometheus.Unregister(pm.UnitFatalErrors)
+	prometheus.Unregister(pm.WorkerRunDuration)
+}
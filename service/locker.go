@@ -0,0 +1,121 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLeaseNotAcquired is an error that is returned by Locker.TryAcquire when the lease is held by someone else.
+var ErrLeaseNotAcquired = errors.New("lease is not acquired")
+
+// ErrLeaseLost is an error that is returned by Lease.Renew when the lease is expired or taken by someone else.
+var ErrLeaseLost = errors.New("lease is lost")
+
+// Locker is a pluggable backend for acquiring named leases (distributed locks).
+// It's used by LeaderWorker for running the worker only in a single replica at a time.
+type Locker interface {
+	// TryAcquire tries to acquire the lease with the given name for the given TTL without blocking.
+	// ErrLeaseNotAcquired is returned if the lease is held by someone else.
+	TryAcquire(ctx context.Context, name string, ttl time.Duration) (Lease, error)
+}
+
+// Lease represents an acquired lease.
+type Lease interface {
+	// Renew extends the lease for the given TTL. ErrLeaseLost is returned if the lease is not held anymore.
+	Renew(ctx context.Context, ttl time.Duration) error
+	// Release releases the lease, so it may be acquired by someone else.
+	Release(ctx context.Context) error
+}
+
+// InMemoryLockerOpts contains optional parameters for constructing InMemoryLocker.
+type InMemoryLockerOpts struct {
+	// Clock is used for checking the lease expiration. The real clock is used by default.
+	Clock Clock
+}
+
+// InMemoryLocker is a Locker that keeps leases in memory.
+// It's supposed to be used in tests and for coordination of workers within a single process.
+type InMemoryLocker struct {
+	clock Clock
+
+	mu          sync.Mutex
+	leases      map[string]inMemoryLeaseEntry
+	lastLeaseID uint64
+}
+
+type inMemoryLeaseEntry struct {
+	id        uint64
+	expiresAt time.Time
+}
+
+var _ Locker = (*InMemoryLocker)(nil)
+
+// NewInMemoryLocker creates a new InMemoryLocker.
+func NewInMemoryLocker() *InMemoryLocker {
+	return NewInMemoryLockerWithOpts(InMemoryLockerOpts{})
+}
+
+// NewInMemoryLockerWithOpts creates a new InMemoryLocker with an ability to specify different optional parameters.
+func NewInMemoryLockerWithOpts(opts InMemoryLockerOpts) *InMemoryLocker {
+	if opts.Clock == nil {
+		opts.Clock = NewRealClock()
+	}
+	return &InMemoryLocker{clock: opts.Clock, leases: make(map[string]inMemoryLeaseEntry)}
+}
+
+// TryAcquire tries to acquire the lease with the given name for the given TTL.
+func (l *InMemoryLocker) TryAcquire(_ context.Context, name string, ttl time.Duration) (Lease, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	if entry, ok := l.leases[name]; ok && now.Before(entry.expiresAt) {
+		return nil, ErrLeaseNotAcquired
+	}
+	l.lastLeaseID++
+	l.leases[name] = inMemoryLeaseEntry{id: l.lastLeaseID, expiresAt: now.Add(ttl)}
+	return &inMemoryLease{locker: l, name: name, id: l.lastLeaseID}, nil
+}
+
+// Revoke releases the lease with the given name regardless of who holds it.
+// It may be used in tests for simulating the lease loss.
+func (l *InMemoryLocker) Revoke(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.leases, name)
+}
+
+type inMemoryLease struct {
+	locker *InMemoryLocker
+	name   string
+	id     uint64
+}
+
+func (ls *inMemoryLease) Renew(_ context.Context, ttl time.Duration) error {
+	ls.locker.mu.Lock()
+	defer ls.locker.mu.Unlock()
+	now := ls.locker.clock.Now()
+	entry, ok := ls.locker.leases[ls.name]
+	if !ok || entry.id != ls.id || !now.Before(entry.expiresAt) {
+		return ErrLeaseLost
+	}
+	entry.expiresAt = now.Add(ttl)
+	ls.locker.leases[ls.name] = entry
+	return nil
+}
+
+func (ls *inMemoryLease) Release(_ context.Context) error {
+	ls.locker.mu.Lock()
+	defer ls.locker.mu.Unlock()
+	if entry, ok := ls.locker.leases[ls.name]; ok && entry.id == ls.id {
+		delete(ls.locker.leases, ls.name)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryLocker(t *testing.T) {
+	ctx := context.Background()
+	locker := NewInMemoryLocker()
+
+	lease, err := locker.TryAcquire(ctx, "cleanup", time.Millisecond*50)
+	require.NoError(t, err)
+	_, err = locker.TryAcquire(ctx, "cleanup", time.Millisecond*50)
+	require.ErrorIs(t, err, ErrLeaseNotAcquired)
+
+	otherLease, err := locker.TryAcquire(ctx, "other", time.Second)
+	require.NoError(t, err)
+	require.NoError(t, otherLease.Release(ctx))
+
+	require.NoError(t, lease.Renew(ctx, time.Millisecond*50))
+	time.Sleep(time.Millisecond * 60)
+	require.ErrorIs(t, lease.Renew(ctx, time.Millisecond*50), ErrLeaseLost)
+
+	newLease, err := locker.TryAcquire(ctx, "cleanup", time.Second)
+	require.NoError(t, err)
+	require.NoError(t, lease.Release(ctx)) // Releasing of the expired lease must not affect the new one.
+	_, err = locker.TryAcquire(ctx, "cleanup", time.Second)
+	require.ErrorIs(t, err, ErrLeaseNotAcquired)
+
+	locker.Revoke("cleanup")
+	require.ErrorIs(t, newLease.Renew(ctx, time.Second), ErrLeaseLost)
+}
+
+func TestFileLocker(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	locker := NewFileLocker(dir)
+
+	lease, err := locker.TryAcquire(ctx, "cleanup", time.Second)
+	require.NoError(t, err)
+	_, err = NewFileLocker(dir).TryAcquire(ctx, "cleanup", time.Second)
+	require.ErrorIs(t, err, ErrLeaseNotAcquired)
+	require.NoError(t, lease.Renew(ctx, time.Second))
+
+	require.NoError(t, lease.Release(ctx))
+	require.ErrorIs(t, lease.Renew(ctx, time.Second), ErrLeaseLost)
+	require.NoError(t, lease.Release(ctx))
+
+	lease, err = locker.TryAcquire(ctx, "cleanup", time.Second)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(dir, "cleanup.lock")))
+	require.ErrorIs(t, lease.Renew(ctx, time.Second), ErrLeaseLost)
+	require.NoError(t, lease.Release(ctx))
+
+	_, err = NewFileLocker(filepath.Join(dir, "unknown")).TryAcquire(ctx, "cleanup", time.Second)
+	require.ErrorContains(t, err, "open lock file")
+}
@@ -0,0 +1,71 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import "github.com/acronis/go-appkit/log"
+
+// NamedUnit wraps the unit and gives it a name that is used in logs, lifecycle events and metrics
+// instead of the type name. All optional interfaces (ReadinessNotifier, Reloadable, MetricsRegisterer, etc.)
+// are forwarded to the wrapped unit.
+type NamedUnit struct {
+	Unit
+	name string
+}
+
+var _ ReadinessNotifier = (*NamedUnit)(nil)
+var _ Reloadable = (*NamedUnit)(nil)
+var _ MetricsRegisterer = (*NamedUnit)(nil)
+var _ StopLoggerSetter = (*NamedUnit)(nil)
+var _ LifecycleEventsSetter = (*NamedUnit)(nil)
+
+// NewNamedUnit creates a new NamedUnit.
+func NewNamedUnit(name string, unit Unit) *NamedUnit {
+	return &NamedUnit{Unit: unit, name: name}
+}
+
+// String returns the name of the unit.
+func (u *NamedUnit) String() string {
+	return u.name
+}
+
+// Ready returns the readiness channel of the wrapped unit.
+// If the wrapped unit doesn't implement ReadinessNotifier, the returned channel is already closed.
+func (u *NamedUnit) Ready() <-chan struct{} {
+	return unitReady(u.Unit)
+}
+
+// Reload reloads the wrapped unit if it implements Reloadable.
+func (u *NamedUnit) Reload() error {
+	if r, ok := u.Unit.(Reloadable); ok {
+		return r.Reload()
+	}
+	return nil
+}
+
+// MustRegisterMetrics registers metrics of the wrapped unit if it implements MetricsRegisterer.
+func (u *NamedUnit) MustRegisterMetrics() {
+	if mr, ok := u.Unit.(MetricsRegisterer); ok {
+		mr.MustRegisterMetrics()
+	}
+}
+
+// UnregisterMetrics unregisters metrics of the wrapped unit if it implements MetricsRegisterer.
+func (u *NamedUnit) UnregisterMetrics() {
+	if mr, ok := u.Unit.(MetricsRegisterer); ok {
+		mr.UnregisterMetrics()
+	}
+}
+
+// SetStopLogger passes the logger to the wrapped unit if it implements StopLoggerSetter.
+func (u *NamedUnit) SetStopLogger(logger log.FieldLogger) {
+	setStopLogger([]Unit{u.Unit}, logger)
+}
+
+// SetLifecycleEvents passes the dispatcher to the wrapped unit if it implements LifecycleEventsSetter.
+func (u *NamedUnit) SetLifecycleEvents(events *LifecycleEvents) {
+	setLifecycleEvents([]Unit{u.Unit}, events)
+}
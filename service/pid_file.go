@@ -0,0 +1,134 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// ErrAnotherInstanceRunning is an error that occurs when the PID file is locked by another running instance.
+var ErrAnotherInstanceRunning = errors.New("another instance is already running")
+
+// pidFileLockAttempts limits attempts to lock the PID file if it's replaced concurrently.
+const pidFileLockAttempts = 3
+
+// PIDFile represents a PID file that is exclusively locked (flock) by the current process.
+// It guarantees that only a single instance of the service is running on the host.
+// PIDFile is supported only on Unix-like systems.
+type PIDFile struct {
+	path string
+	file *os.File
+
+	// StalePID is a PID from the file left by the previous instance that was not stopped gracefully.
+	// Zero value means that there was no stale PID file.
+	StalePID int
+}
+
+// AcquirePIDFile creates (or opens the existing) PID file, locks it exclusively and writes the current PID there.
+// If the file is locked by another process, the returned error wraps ErrAnotherInstanceRunning.
+// If the file exists but isn't locked, it's considered stale (the previous instance crashed) and is reused.
+func AcquirePIDFile(path string) (*PIDFile, error) {
+	for i := 0; i < pidFileLockAttempts; i++ {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644) //nolint:gosec // PID file should be readable
+		if err != nil {
+			return nil, fmt.Errorf("open pid file: %w", err)
+		}
+		if err = tryLockFile(f); err != nil {
+			pid, _ := readPID(f)
+			_ = f.Close()
+			if errors.Is(err, errFileLocked) {
+				return nil, fmt.Errorf("%w (pid %d, pid file %s)", ErrAnotherInstanceRunning, pid, path)
+			}
+			return nil, fmt.Errorf("lock pid file %s: %w", path, err)
+		}
+
+		// The file may be removed by the previous instance between opening and locking, check it's still in place.
+		if !isSameFile(f, path) {
+			_ = f.Close()
+			continue
+		}
+
+		pidFile := &PIDFile{path: path, file: f}
+		if pidFile.StalePID, err = readPID(f); err != nil {
+			pidFile.StalePID = 0 // Malformed content is overwritten.
+		}
+		if err = pidFile.writePID(); err != nil {
+			_ = pidFile.Release()
+			return nil, err
+		}
+		return pidFile, nil
+	}
+	return nil, fmt.Errorf("lock pid file %s: file is replaced concurrently", path)
+}
+
+// Path returns the path of the PID file.
+func (p *PIDFile) Path() string {
+	return p.path
+}
+
+// Release removes the PID file and unlocks it.
+func (p *PIDFile) Release() error {
+	if p.file == nil {
+		return nil
+	}
+	var errs []error
+	if isSameFile(p.file, p.path) {
+		if err := os.Remove(p.path); err != nil {
+			errs = append(errs, fmt.Errorf("remove pid file: %w", err))
+		}
+	}
+	if err := unlockFile(p.file); err != nil {
+		errs = append(errs, fmt.Errorf("unlock pid file: %w", err))
+	}
+	if err := p.file.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("close pid file: %w", err))
+	}
+	p.file = nil
+	return errors.Join(errs...)
+}
+
+func (p *PIDFile) writePID() error {
+	if err := p.file.Truncate(0); err != nil {
+		return fmt.Errorf("truncate pid file: %w", err)
+	}
+	if _, err := p.file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		return fmt.Errorf("write pid file: %w", err)
+	}
+	if err := p.file.Sync(); err != nil {
+		return fmt.Errorf("sync pid file: %w", err)
+	}
+	return nil
+}
+
+func readPID(f *os.File) (int, error) {
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, 64))
+	if err != nil {
+		return 0, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return 0, nil
+	}
+	return strconv.Atoi(string(data))
+}
+
+func isSameFile(f *os.File, path string) bool {
+	openedInfo, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	curInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(openedInfo, curInfo)
+}
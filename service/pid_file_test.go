@@ -0,0 +1,91 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/acronis/go-appkit/log"
+	"github.com/acronis/go-appkit/log/logtest"
+)
+
+func TestAcquirePIDFile(t *testing.T) {
+	t.Run("single instance", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "service.pid")
+
+		pidFile, err := AcquirePIDFile(path)
+		require.NoError(t, err)
+		require.Zero(t, pidFile.StalePID)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, strconv.Itoa(os.Getpid())+"\n", string(data))
+
+		_, err = AcquirePIDFile(path)
+		require.ErrorIs(t, err, ErrAnotherInstanceRunning)
+		require.EqualError(t, err, "another instance is already running (pid "+strconv.Itoa(os.Getpid())+", pid file "+path+")")
+
+		require.NoError(t, pidFile.Release())
+		require.NoFileExists(t, path)
+		require.NoError(t, pidFile.Release())
+
+		pidFile, err = AcquirePIDFile(path)
+		require.NoError(t, err)
+		require.NoError(t, pidFile.Release())
+	})
+
+	t.Run("stale pid file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "service.pid")
+		require.NoError(t, os.WriteFile(path, []byte("12345678901\n"), 0o600))
+
+		pidFile, err := AcquirePIDFile(path)
+		require.NoError(t, err)
+		require.Equal(t, 12345678901, pidFile.StalePID)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, strconv.Itoa(os.Getpid())+"\n", string(data))
+		require.NoError(t, pidFile.Release())
+	})
+
+	t.Run("directory doesn't exist", func(t *testing.T) {
+		_, err := AcquirePIDFile(filepath.Join(t.TempDir(), "unknown", "service.pid"))
+		require.ErrorContains(t, err, "open pid file")
+	})
+}
+
+func TestService_PIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.pid")
+	require.NoError(t, os.WriteFile(path, []byte("42\n"), 0o600))
+
+	logRecorder := logtest.NewRecorder()
+	service := NewWithOpts(logRecorder, newStagedMockUnit("srv", &eventsRecorder{}),
+		Opts{ShutdownSignals: []os.Signal{os.Interrupt}, PIDFile: path})
+	startErr := make(chan error, 1)
+	go func() { startErr <- service.Start() }()
+	require.Eventually(t, func() bool { return service.State() == LifecycleStateReady },
+		time.Second, time.Millisecond*10)
+	logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelWarn), logtest.FieldEquals("stale_pid", 42))
+
+	secondRecorder := logtest.NewRecorder()
+	second := NewWithOpts(secondRecorder, newStagedMockUnit("srv", &eventsRecorder{}),
+		Opts{ShutdownSignals: []os.Signal{os.Interrupt}, PIDFile: path})
+	err := second.Start()
+	require.ErrorIs(t, err, ErrAnotherInstanceRunning)
+	require.ErrorContains(t, err, "acquire pid file: another instance is already running")
+	require.Equal(t, LifecycleStateStopped, second.State())
+	logtest.RequireEntry(t, secondRecorder, logtest.HasLevel(log.LevelError),
+		logtest.HasMessage("failed to acquire pid file"))
+
+	service.Signals <- os.Interrupt
+	require.NoError(t, <-startErr)
+	require.NoFileExists(t, path)
+}
@@ -0,0 +1,194 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"time"
+
+	"github.com/acronis/go-appkit/log"
+)
+
+// OverlapPolicy defines what ScheduledWorker does when the next run is due while the previous one is still in progress.
+type OverlapPolicy int
+
+// Overlap policies.
+const (
+	// OverlapSkip skips the run if the previous one is still in progress.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue postpones the run until the previous one finishes.
+	// At most one run is queued, i.e., several overdue runs are coalesced into a single one.
+	OverlapQueue
+	// OverlapAllow starts the run concurrently with the previous one.
+	OverlapAllow
+)
+
+// ScheduledWorkerOpts contains optional parameters for constructing ScheduledWorker.
+type ScheduledWorkerOpts struct {
+	// Jitter is a maximum random delay that is added to every scheduled time.
+	// It helps to spread the load when the same schedule is used by many replicas.
+	Jitter time.Duration
+
+	// OverlapPolicy defines what to do when the next run is due while the previous one is still in progress.
+	// OverlapSkip is used by default.
+	OverlapPolicy OverlapPolicy
+
+	// Clock is used for getting the current time and creating timers. The real clock is used by default.
+	Clock Clock
+}
+
+// ScheduledWorker represents a worker that runs underlying worker according to the schedule
+// (e.g., cron expression parsed by ParseCronSchedule).
+// Errors returned by the underlying worker are logged, ErrPeriodicWorkerStop stops the ScheduledWorker.
+// Panics in the underlying worker are logged and re-raised on the goroutine that calls Run
+// (so Supervisor may recover and restart the ScheduledWorker) after all started runs finish.
+type ScheduledWorker struct {
+	worker        Worker
+	schedule      Schedule
+	logger        log.FieldLogger
+	jitter        time.Duration
+	overlapPolicy OverlapPolicy
+	clock         Clock
+}
+
+// NewScheduledWorker creates a new instance of ScheduledWorker.
+func NewScheduledWorker(worker Worker, schedule Schedule, logger log.FieldLogger) *ScheduledWorker {
+	return NewScheduledWorkerWithOpts(worker, schedule, logger, ScheduledWorkerOpts{})
+}
+
+// NewScheduledWorkerWithOpts creates a new instance of ScheduledWorker
+// with an ability to specify different optional parameters.
+func NewScheduledWorkerWithOpts(
+	worker Worker, schedule Schedule, logger log.FieldLogger, opts ScheduledWorkerOpts,
+) *ScheduledWorker {
+	if opts.Clock == nil {
+		opts.Clock = NewRealClock()
+	}
+	return &ScheduledWorker{
+		worker:        worker,
+		schedule:      schedule,
+		logger:        logger,
+		jitter:        opts.Jitter,
+		overlapPolicy: opts.OverlapPolicy,
+		clock:         opts.Clock,
+	}
+}
+
+// Run runs ScheduledWorker loop. It returns when the context is canceled, the underlying worker returns
+// ErrPeriodicWorkerStop or the schedule has no more activation times. All started runs are awaited before returning.
+func (sw *ScheduledWorker) Run(ctx context.Context) error {
+	sw.logger.Infof("running scheduled worker (schedule=%v, jitter=%s)...", sw.schedule, sw.jitter)
+
+	runCtx, runCtxCancel := context.WithCancel(ctx)
+	runDone := make(chan scheduledRunResult)
+	running := 0
+	queued := false
+
+	startRun := func() {
+		running++
+		go func() {
+			runDone <- sw.runWorker(runCtx)
+		}()
+	}
+
+	defer func() {
+		runCtxCancel()
+		for ; running > 0; running-- {
+			<-runDone
+		}
+		sw.logger.Info("scheduled worker stopped")
+	}()
+
+	var timer Timer
+	var timerC <-chan time.Time
+	scheduleNext := func() {
+		now := sw.clock.Now()
+		next := sw.schedule.Next(now)
+		if next.IsZero() {
+			timer, timerC = nil, nil
+			return
+		}
+		delay := next.Sub(now)
+		if sw.jitter > 0 {
+			delay += rand.N(sw.jitter) //nolint:gosec // Cryptographically secure random is not needed for jitter.
+		}
+		timer = sw.clock.NewTimer(delay)
+		timerC = timer.C()
+	}
+	scheduleNext()
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		if timerC == nil && running == 0 {
+			sw.logger.Info("scheduled worker has no more activation times")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-timerC:
+			switch {
+			case running == 0 || sw.overlapPolicy == OverlapAllow:
+				startRun()
+			case sw.overlapPolicy == OverlapQueue:
+				queued = true
+			default:
+				sw.logger.Warn("scheduled run is skipped since the previous one is still in progress")
+			}
+			scheduleNext()
+
+		case res := <-runDone:
+			running--
+			if res.panicked {
+				panic(res.panicValue)
+			}
+			if errors.Is(res.err, ErrPeriodicWorkerStop) {
+				return nil
+			}
+			if queued {
+				queued = false
+				startRun()
+			}
+		}
+	}
+}
+
+// scheduledRunResult is a result of the single run of the underlying worker.
+type scheduledRunResult struct {
+	err        error
+	panicked   bool
+	panicValue any
+}
+
+// runWorker runs the underlying worker once. It's called in a separate goroutine,
+// so the panic is recovered here and returned to Run to be re-raised there.
+func (sw *ScheduledWorker) runWorker(ctx context.Context) (res scheduledRunResult) {
+	defer func() {
+		if p := recover(); p != nil {
+			const logStackSize = 8192
+			stack := make([]byte, logStackSize)
+			stack = stack[:runtime.Stack(stack, false)]
+			sw.logger.Error(fmt.Sprintf("panic: %+v", p), log.Bytes("stack", stack))
+			res = scheduledRunResult{panicked: true, panicValue: p}
+		}
+	}()
+	res.err = sw.worker.Run(ctx)
+	if res.err != nil && !errors.Is(res.err, ErrPeriodicWorkerStop) {
+		sw.logger.Error("scheduled worker finished with error", log.Error(res.err))
+	}
+	return res
+}
@@ -0,0 +1,250 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/acronis/go-appkit/log"
+	"github.com/acronis/go-appkit/log/logtest"
+	"github.com/acronis/go-appkit/retry"
+	"github.com/acronis/go-appkit/service"
+	"github.com/acronis/go-appkit/service/servicetest"
+)
+
+type scheduledWorkerTestCtx struct {
+	clock   *servicetest.FakeClock
+	started chan time.Time
+	release chan struct{}
+	runErr  chan error
+	cancel  context.CancelFunc
+}
+
+func runScheduledWorker(
+	t *testing.T, expr string, opts service.ScheduledWorkerOpts, logger log.FieldLogger, workerErr error,
+) *scheduledWorkerTestCtx {
+	t.Helper()
+	tc := &scheduledWorkerTestCtx{
+		clock:   servicetest.NewFakeClock(time.Date(2024, 3, 10, 10, 7, 0, 0, time.UTC)),
+		started: make(chan time.Time, 10),
+		release: make(chan struct{}),
+		runErr:  make(chan error, 1),
+	}
+	opts.Clock = tc.clock
+	worker := service.WorkerFunc(func(ctx context.Context) error {
+		tc.started <- tc.clock.Now()
+		select {
+		case <-tc.release:
+		case <-ctx.Done():
+		}
+		return workerErr
+	})
+	sched, err := service.ParseCronScheduleWithOpts(expr, service.CronScheduleOpts{Location: time.UTC})
+	require.NoError(t, err)
+	scheduledWorker := service.NewScheduledWorkerWithOpts(worker, sched, logger, opts)
+
+	var ctx context.Context
+	ctx, tc.cancel = context.WithCancel(context.Background())
+	go func() {
+		tc.runErr <- scheduledWorker.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		tc.cancel()
+		close(tc.release)
+		select {
+		case <-tc.runErr:
+		case <-time.After(time.Second):
+			t.Error("scheduled worker is not stopped")
+		}
+	})
+	return tc
+}
+
+// advanceTo waits until the scheduled worker creates a timer and moves the fake time.
+func (tc *scheduledWorkerTestCtx) advanceTo(hour, minute int) {
+	tc.clock.BlockUntilActiveTimers(1)
+	tc.clock.Set(time.Date(2024, 3, 10, hour, minute, 0, 0, time.UTC))
+}
+
+func (tc *scheduledWorkerTestCtx) requireStarted(t *testing.T, hour, minute int) {
+	t.Helper()
+	select {
+	case startedAt := <-tc.started:
+		require.Equal(t, time.Date(2024, 3, 10, hour, minute, 0, 0, time.UTC), startedAt)
+	case <-time.After(time.Second):
+		require.Fail(t, "worker is not started")
+	}
+}
+
+func (tc *scheduledWorkerTestCtx) requireNotStarted(t *testing.T) {
+	t.Helper()
+	select {
+	case <-tc.started:
+		require.Fail(t, "worker is started unexpectedly")
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestScheduledWorker_Run(t *testing.T) {
+	// OverlapQueue is used in the tests where the previous run is finished before the next one,
+	// so they don't depend on when the scheduled worker handles the completion of the previous run.
+
+	t.Run("runs according to schedule", func(t *testing.T) {
+		tc := runScheduledWorker(t, "*/15 * * * *",
+			service.ScheduledWorkerOpts{OverlapPolicy: service.OverlapQueue}, log.NewDisabledLogger(), nil)
+
+		tc.advanceTo(10, 14)
+		tc.requireNotStarted(t)
+
+		tc.advanceTo(10, 15)
+		tc.requireStarted(t, 10, 15)
+		tc.release <- struct{}{}
+
+		tc.advanceTo(10, 30)
+		tc.requireStarted(t, 10, 30)
+	})
+
+	t.Run("overlapping run is skipped", func(t *testing.T) {
+		logRecorder := logtest.NewRecorder()
+		tc := runScheduledWorker(t, "*/15 * * * *", service.ScheduledWorkerOpts{}, logRecorder, nil)
+
+		tc.advanceTo(10, 15)
+		tc.requireStarted(t, 10, 15)
+
+		tc.advanceTo(10, 30)
+		tc.requireNotStarted(t)
+		logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelWarn),
+			logtest.HasMessage("scheduled run is skipped since the previous one is still in progress"))
+
+		tc.release <- struct{}{}
+		tc.requireNotStarted(t)
+
+		tc.advanceTo(10, 45)
+		tc.requireStarted(t, 10, 45)
+	})
+
+	t.Run("overlapping run is queued", func(t *testing.T) {
+		tc := runScheduledWorker(t, "*/15 * * * *",
+			service.ScheduledWorkerOpts{OverlapPolicy: service.OverlapQueue}, log.NewDisabledLogger(), nil)
+
+		tc.advanceTo(10, 15)
+		tc.requireStarted(t, 10, 15)
+
+		tc.advanceTo(10, 30)
+		tc.advanceTo(10, 45) // Coalesced with the previous overdue run.
+		tc.requireNotStarted(t)
+
+		tc.release <- struct{}{}
+		tc.requireStarted(t, 10, 45)
+
+		tc.release <- struct{}{}
+		tc.requireNotStarted(t)
+	})
+
+	t.Run("overlapping run is allowed", func(t *testing.T) {
+		tc := runScheduledWorker(t, "*/15 * * * *",
+			service.ScheduledWorkerOpts{OverlapPolicy: service.OverlapAllow}, log.NewDisabledLogger(), nil)
+
+		tc.advanceTo(10, 15)
+		tc.requireStarted(t, 10, 15)
+
+		tc.advanceTo(10, 30)
+		tc.requireStarted(t, 10, 30)
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		tc := runScheduledWorker(t, "*/15 * * * *",
+			service.ScheduledWorkerOpts{Jitter: time.Minute}, log.NewDisabledLogger(), nil)
+
+		tc.advanceTo(10, 14)
+		tc.requireNotStarted(t)
+
+		tc.advanceTo(10, 16)
+		tc.requireStarted(t, 10, 16)
+	})
+
+	t.Run("stop by worker", func(t *testing.T) {
+		tc := runScheduledWorker(t, "*/15 * * * *",
+			service.ScheduledWorkerOpts{}, log.NewDisabledLogger(), service.ErrPeriodicWorkerStop)
+
+		tc.advanceTo(10, 15)
+		tc.requireStarted(t, 10, 15)
+		tc.release <- struct{}{}
+
+		select {
+		case err := <-tc.runErr:
+			require.NoError(t, err)
+			tc.runErr <- err // For the cleanup.
+		case <-time.After(time.Second):
+			require.Fail(t, "scheduled worker is not stopped")
+		}
+	})
+
+	t.Run("errors are logged", func(t *testing.T) {
+		logRecorder := logtest.NewRecorder()
+		tc := runScheduledWorker(t, "*/15 * * * *",
+			service.ScheduledWorkerOpts{OverlapPolicy: service.OverlapQueue}, logRecorder, errors.New("internal error"))
+
+		tc.advanceTo(10, 15)
+		tc.requireStarted(t, 10, 15)
+		tc.release <- struct{}{}
+
+		tc.advanceTo(10, 30)
+		tc.requireStarted(t, 10, 30)
+		logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelError),
+			logtest.HasMessage("scheduled worker finished with error"), logtest.FieldEquals("error", "internal error"))
+	})
+}
+
+func TestScheduledWorker_PanicIsRecoveredBySupervisor(t *testing.T) {
+	clock := servicetest.NewFakeClock(time.Date(2024, 3, 10, 10, 7, 0, 0, time.UTC))
+	var runs atomic.Int32
+	worker := service.WorkerFunc(func(ctx context.Context) error {
+		if runs.Add(1) == 1 {
+			panic("boom")
+		}
+		return service.ErrPeriodicWorkerStop
+	})
+	sched, err := service.ParseCronScheduleWithOpts("*/15 * * * *", service.CronScheduleOpts{Location: time.UTC})
+	require.NoError(t, err)
+	logRecorder := logtest.NewRecorder()
+	scheduledWorker := service.NewScheduledWorkerWithOpts(worker, sched, logRecorder, service.ScheduledWorkerOpts{Clock: clock})
+	metrics := service.NewSupervisorPrometheusMetrics()
+	supervisor := service.NewSupervisorWithOpts(scheduledWorker, logRecorder, service.SupervisorOpts{
+		BackoffPolicy:    retry.NewConstantBackoffPolicy(time.Millisecond, 0),
+		MetricsCollector: metrics,
+	})
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- supervisor.Run(context.Background()) }()
+
+	clock.BlockUntilActiveTimers(1)
+	clock.Set(time.Date(2024, 3, 10, 10, 15, 0, 0, time.UTC))
+
+	// The scheduled worker is restarted by the supervisor and waits for the next activation time.
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.RestartsTotal.WithLabelValues("", service.RestartReasonPanic)) == 1
+	}, time.Second, time.Millisecond*5)
+	clock.BlockUntilActiveTimers(1)
+	clock.Set(time.Date(2024, 3, 10, 10, 30, 0, 0, time.UTC))
+
+	select {
+	case err = <-runErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.Fail(t, "supervisor is not stopped")
+	}
+	require.Equal(t, int32(2), runs.Load())
+	logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelError), logtest.HasMessage("panic: boom"))
+}
@@ -8,45 +8,118 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/acronis/go-appkit/log"
 )
 
+// ErrShutdownTimeoutExceeded is an error that occurs when the service unit is not stopped gracefully
+// within the shutdown timeout, so it's stopped non-gracefully.
+var ErrShutdownTimeoutExceeded = errors.New("shutdown timeout exceeded")
+
+// ErrForcedShutdown is an error that occurs when the second shutdown signal is received
+// while the service is being stopped gracefully, so the unit is stopped non-gracefully.
+var ErrForcedShutdown = errors.New("forced shutdown")
+
 // Opts represents an options for Service.
 type Opts struct {
 	ShutdownSignals []os.Signal
+
+	// ReloadSignals are OS signals that trigger reloading of the unit (see Reloadable).
+	ReloadSignals []os.Signal
+
+	// OnStateChange is called every time the lifecycle state of the service changes.
+	OnStateChange func(state LifecycleState)
+
+	// DrainDelay is a time to wait after the service is marked as draining (and so not ready) and before the unit
+	// is stopped. It gives load balancers (e.g., Kubernetes endpoints controller) time to stop routing new traffic
+	// to the service. Zero value means no delay.
+	DrainDelay time.Duration
+
+	// SystemdNotifier is used for sending notifications about the lifecycle state to systemd
+	// (READY=1, STOPPING=1, STATUS=) and periodic WATCHDOG=1 messages if the watchdog is enabled.
+	// Nil value means that systemd is not notified.
+	SystemdNotifier *SystemdNotifier
+
+	// ShutdownTimeout limits the whole shutdown process including DrainDelay.
+	// If the unit is not stopped gracefully within this timeout, it's stopped non-gracefully.
+	// The graceful stop is not awaited in this case, so Stop(false) is called concurrently
+	// with Stop(true) that is still in progress, and the unit must support it (see Unit.Stop).
+	// Zero value means no timeout.
+	ShutdownTimeout time.Duration
+
+	// PIDFile is a path of the PID file that is created and exclusively locked when the service is started
+	// and removed when it's stopped. If the file is locked by another instance, the service fails to start
+	// with an error that wraps ErrAnotherInstanceRunning. Empty value means that the PID file is not used.
+	PIDFile string
+
+	// LifecycleListeners are subscribed to the lifecycle events of the service and its units (see Service.Subscribe).
+	// Listeners that have MustRegister and Unregister methods (e.g., LifecyclePrometheusMetrics)
+	// are registered when the service is started and unregistered when it's stopped.
+	LifecycleListeners []LifecycleListener
 }
 
 // Service represents a service which can register metrics in Prometheus client,
 // start unit and stop it in a graceful way by OS signal.
+// It also maintains the lifecycle state that may be used for liveness and readiness probes
+// (see httpserver.NewLivenessHealthCheck and httpserver.NewReadinessHealthCheck).
 type Service struct {
-	Unit    Unit
-	Signals chan os.Signal
-	Logger  log.FieldLogger
-	Opts    Opts
+	Unit          Unit
+	Signals       chan os.Signal
+	ReloadSignals chan os.Signal
+	Logger        log.FieldLogger
+	Opts          Opts
+
+	state  atomic.Int32
+	events *LifecycleEvents
 }
 
+var _ LifecycleStateProvider = (*Service)(nil)
+
 // New creates new Service which will start and stop passing unit.
+// If the process is run by systemd with Type=notify, systemd is notified about the service lifecycle.
 func New(logger log.FieldLogger, unit Unit) *Service {
+	systemdNotifier, err := NewSystemdNotifierFromEnv()
+	if err != nil {
+		logger.Warn("failed to configure systemd watchdog, systemd will not be notified", log.Error(err))
+	}
 	return NewWithOpts(logger, unit, Opts{
 		ShutdownSignals: []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		ReloadSignals:   []os.Signal{syscall.SIGHUP},
+		SystemdNotifier: systemdNotifier,
 	})
 }
 
 // NewWithOpts is a more configurable version of New.
 func NewWithOpts(logger log.FieldLogger, unit Unit, opts Opts) *Service {
+	events := NewLifecycleEvents()
+	for _, listener := range opts.LifecycleListeners {
+		events.Subscribe(listener)
+	}
 	return &Service{
-		Signals: make(chan os.Signal, 1),
-		Unit:    unit,
-		Logger:  logger,
-		Opts:    opts,
+		Signals:       make(chan os.Signal, 1),
+		ReloadSignals: make(chan os.Signal, 1),
+		Unit:          unit,
+		Logger:        logger,
+		Opts:          opts,
+		events:        events,
 	}
 }
 
+// Subscribe adds the listener of the lifecycle events of the service and its units.
+// Units inside CompositeUnit and StagedCompositeUnit are reported separately (use NewNamedUnit to name them).
+// The returned function removes the listener.
+// Nothing is subscribed if the Service is not created by New or NewWithOpts.
+func (s *Service) Subscribe(listener LifecycleListener) (unsubscribe func()) {
+	return s.events.Subscribe(listener)
+}
+
 // Start wraps StartContext using the background context.
 func (s *Service) Start() error {
 	return s.StartContext(context.Background())
@@ -54,33 +127,236 @@ func (s *Service) Start() error {
 
 // StartContext starts service unit in the separate goroutine and
 // blocks until fatal error occurs or any of the OS shutting down signals are received.
+// If any of the reload signals is received, the unit is reloaded (see Reload).
+//
+// The lifecycle state is changed to LifecycleStateReady when the unit becomes ready (see ReadinessNotifier),
+// to LifecycleStateDraining right before the graceful stop begins, and to LifecycleStateStopped at the end.
+//
+// When the shutdown begins, the service waits for Opts.DrainDelay and then stops the unit gracefully.
+// If the shutdown is not completed within Opts.ShutdownTimeout or one more shutdown signal is received
+// in the meantime, the unit is stopped non-gracefully.
 func (s *Service) StartContext(ctx context.Context) error {
+	if s.Opts.PIDFile != "" {
+		pidFile, err := s.acquirePIDFile()
+		if err != nil {
+			s.setState(LifecycleStateStopped)
+			return err
+		}
+		defer func() {
+			if releaseErr := pidFile.Release(); releaseErr != nil {
+				s.Logger.Error("failed to release pid file", log.Error(releaseErr))
+			}
+		}()
+	}
+
 	if mr, ok := s.Unit.(MetricsRegisterer); ok {
 		mr.MustRegisterMetrics()
 		defer mr.UnregisterMetrics()
 	}
+	if sls, ok := s.Unit.(StopLoggerSetter); ok {
+		sls.SetStopLogger(s.Logger)
+	}
+	for _, listener := range s.Opts.LifecycleListeners {
+		if r, ok := listener.(interface {
+			MustRegister()
+			Unregister()
+		}); ok {
+			r.MustRegister()
+			defer r.Unregister()
+		}
+	}
+	if les, ok := s.Unit.(LifecycleEventsSetter); ok {
+		les.SetLifecycleEvents(s.events)
+	}
+
+	s.setState(LifecycleStateStarting)
+	defer s.setState(LifecycleStateStopped)
+
+	if s.Opts.SystemdNotifier != nil && s.Opts.SystemdNotifier.WatchdogInterval() > 0 {
+		watchdogDone := make(chan struct{})
+		defer close(watchdogDone)
+		go s.runSystemdWatchdog(watchdogDone)
+	}
 
 	fatalError := make(chan error, 1)
 
-	go s.Unit.Start(fatalError)
+	go startUnit(s.Unit, fatalError, s.unitEvents())
 
 	signal.Notify(s.Signals, s.Opts.ShutdownSignals...)
+	if len(s.Opts.ReloadSignals) != 0 {
+		signal.Notify(s.ReloadSignals, s.Opts.ReloadSignals...)
+		defer signal.Stop(s.ReloadSignals)
+	}
+
+	ready := unitReady(s.Unit)
+	for {
+		select {
+		case <-ready:
+			ready = nil
+			s.setState(LifecycleStateReady)
+		case <-ctx.Done():
+			s.Logger.Info("context is canceled, service will be stopped")
+			return s.stopGracefully()
+		case err := <-fatalError:
+			s.Logger.Error("service fatal error", log.Error(err))
+			return fmt.Errorf("fatal error: %w", err)
+		case sig := <-s.Signals:
+			s.Logger.Info("service got signal", log.String("signal", sig.String()))
+			return s.stopGracefully()
+		case sig := <-s.ReloadSignals:
+			s.Logger.Info("service got reload signal", log.String("signal", sig.String()))
+			_ = s.Reload()
+		}
+	}
+}
+
+func (s *Service) stopGracefully() error {
+	startTime := time.Now()
+	s.setState(LifecycleStateDraining)
+
+	var deadline <-chan time.Time
+	if s.Opts.ShutdownTimeout > 0 {
+		timer := time.NewTimer(s.Opts.ShutdownTimeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	if s.Opts.DrainDelay > 0 {
+		s.Logger.Info("service is draining before stop", log.Duration("drain_delay", s.Opts.DrainDelay))
+		drainTimer := time.NewTimer(s.Opts.DrainDelay)
+		defer drainTimer.Stop()
+		select {
+		case <-drainTimer.C:
+		case <-deadline:
+			return s.stopForcibly(ErrShutdownTimeoutExceeded, startTime)
+		case sig := <-s.Signals:
+			s.Logger.Warn("service got signal during draining", log.String("signal", sig.String()))
+			return s.stopForcibly(ErrForcedShutdown, startTime)
+		}
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- stopUnit(s.Unit, true, nil, s.unitEvents())
+	}()
 
 	select {
-	case <-ctx.Done():
-		s.Logger.Info("context is canceled, service will be stopped")
-		if err := s.Unit.Stop(true); err != nil {
+	case err := <-stopped:
+		if err != nil {
+			s.Logger.Error("service is stopped gracefully with error",
+				log.Duration("duration", time.Since(startTime)), log.Error(err))
 			return fmt.Errorf("stop service gracefully: %w", err)
 		}
-	case err := <-fatalError:
-		s.Logger.Error("service fatal error", log.Error(err))
-		return fmt.Errorf("fatal error: %w", err)
+		s.Logger.Info("service is stopped gracefully", log.Duration("duration", time.Since(startTime)))
+		return nil
+	case <-deadline:
+		return s.stopForcibly(ErrShutdownTimeoutExceeded, startTime)
 	case sig := <-s.Signals:
-		s.Logger.Info("service got signal", log.String("signal", sig.String()))
-		if err := s.Unit.Stop(true); err != nil {
-			return fmt.Errorf("stop service gracefully: %w", err)
-		}
+		s.Logger.Warn("service got signal during graceful stop", log.String("signal", sig.String()))
+		return s.stopForcibly(ErrForcedShutdown, startTime)
 	}
+}
 
+func (s *Service) stopForcibly(reason error, startTime time.Time) error {
+	s.Logger.Warn("service will be stopped non-gracefully", log.Error(reason))
+	if err := stopUnit(s.Unit, false, nil, s.unitEvents()); err != nil {
+		s.Logger.Error("service is stopped non-gracefully with error",
+			log.Duration("duration", time.Since(startTime)), log.Error(err))
+		return fmt.Errorf("stop service non-gracefully: %w", errors.Join(reason, err))
+	}
+	s.Logger.Info("service is stopped non-gracefully", log.Duration("duration", time.Since(startTime)))
+	return fmt.Errorf("stop service non-gracefully: %w", reason)
+}
+
+// Reload calls the Reload method of the unit if it implements Reloadable and logs the outcome.
+// CompositeUnit and StagedCompositeUnit reload all their units that implement Reloadable.
+// If systemd is notified (see Opts.SystemdNotifier), RELOADING=1 is sent before the reload,
+// and messages for the current lifecycle state (e.g., READY=1) are sent after it.
+func (s *Service) Reload() error {
+	r, ok := s.Unit.(Reloadable)
+	if !ok {
+		s.Logger.Warn("service unit doesn't support reloading")
+		return nil
+	}
+	s.notifySystemd(SystemdNotifyReloading, SystemdNotifyStatus("reloading"))
+	defer func() { s.notifySystemd(systemdMessagesForState(s.State())...) }()
+	if err := r.Reload(); err != nil {
+		s.Logger.Error("service reload failed", log.Error(err))
+		return fmt.Errorf("reload service: %w", err)
+	}
+	s.Logger.Info("service is reloaded")
 	return nil
 }
+
+// State returns the current lifecycle state of the service.
+func (s *Service) State() LifecycleState {
+	return LifecycleState(s.state.Load())
+}
+
+func (s *Service) setState(state LifecycleState) {
+	if LifecycleState(s.state.Swap(int32(state))) == state {
+		return
+	}
+	s.Logger.Debug("service lifecycle state is changed", log.String("state", state.String()))
+	if s.Opts.OnStateChange != nil {
+		s.Opts.OnStateChange(state)
+	}
+	s.notifySystemd(systemdMessagesForState(state)...)
+	s.events.Publish(LifecycleEvent{Type: LifecycleEventServiceStateChanged, State: state})
+}
+
+func (s *Service) acquirePIDFile() (*PIDFile, error) {
+	pidFile, err := AcquirePIDFile(s.Opts.PIDFile)
+	if err != nil {
+		s.Logger.Error("failed to acquire pid file", log.String("pid_file", s.Opts.PIDFile), log.Error(err))
+		return nil, fmt.Errorf("acquire pid file: %w", err)
+	}
+	if pidFile.StalePID != 0 {
+		s.Logger.Warn("stale pid file is found, the previous instance was not stopped gracefully",
+			log.String("pid_file", pidFile.Path()), log.Int("stale_pid", pidFile.StalePID))
+	}
+	return pidFile, nil
+}
+
+// unitEvents returns the dispatcher for publishing lifecycle events of the service unit.
+// Composite units publish events of their units by themselves, so nil is returned for them.
+func (s *Service) unitEvents() *LifecycleEvents {
+	if isCompositeUnit(s.Unit) {
+		return nil
+	}
+	return s.events
+}
+
+func isCompositeUnit(unit Unit) bool {
+	switch u := unit.(type) {
+	case *NamedUnit:
+		return isCompositeUnit(u.Unit)
+	case LifecycleEventsSetter:
+		return true
+	}
+	return false
+}
+
+func (s *Service) notifySystemd(messages ...string) {
+	if s.Opts.SystemdNotifier == nil {
+		return
+	}
+	if err := s.Opts.SystemdNotifier.Notify(messages...); err != nil {
+		s.Logger.Warn("failed to notify systemd", log.Strings("messages", messages), log.Error(err))
+	}
+}
+
+// runSystemdWatchdog sends WATCHDOG=1 messages to systemd twice per the watchdog interval
+// (as recommended in sd_watchdog_enabled(3)) until the done channel is closed.
+func (s *Service) runSystemdWatchdog(done <-chan struct{}) {
+	ticker := time.NewTicker(s.Opts.SystemdNotifier.WatchdogInterval() / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.notifySystemd(SystemdNotifyWatchdog)
+		case <-done:
+			return
+		}
+	}
+}
@@ -8,13 +8,17 @@ package service
 
 import (
 	"context"
+	"errors"
 	"os"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/acronis/go-appkit/log"
 	"github.com/acronis/go-appkit/log/logtest"
 )
 
@@ -55,3 +59,238 @@ func TestService_StartContext(t *testing.T) {
 	require.NoError(t, waitTrue(func() bool { return atomic.LoadInt32(&runningCounter) == 0 }, time.Second*3))
 	require.Equal(t, 1, mockUnit.stopGracefullyCalled)
 }
+
+type stateCheckingUnit struct {
+	*stagedMockUnit
+	svc           *Service
+	stateAtStop   LifecycleState
+	stateAtStopMu sync.Mutex
+}
+
+func (u *stateCheckingUnit) Stop(gracefully bool) error {
+	u.stateAtStopMu.Lock()
+	u.stateAtStop = u.svc.State()
+	u.stateAtStopMu.Unlock()
+	return u.stagedMockUnit.Stop(gracefully)
+}
+
+func TestService_LifecycleState(t *testing.T) {
+	t.Run("graceful stop", func(t *testing.T) {
+		states := make(chan LifecycleState, 10)
+		mockUnit := &stateCheckingUnit{stagedMockUnit: newStagedMockUnit("srv", &eventsRecorder{})}
+		becomeReady := make(chan struct{})
+		mockUnit.readyFunc = func(u *stagedMockUnit) {
+			<-becomeReady
+			close(u.ready)
+		}
+		service := NewWithOpts(logtest.NewRecorder(), mockUnit, Opts{
+			ShutdownSignals: []os.Signal{os.Interrupt},
+			OnStateChange:   func(state LifecycleState) { states <- state },
+		})
+		mockUnit.svc = service
+		require.Equal(t, LifecycleStateNew, service.State())
+
+		startErr := make(chan error, 1)
+		go func() { startErr <- service.Start() }()
+
+		require.Equal(t, LifecycleStateStarting, <-states)
+		require.Never(t, func() bool { return service.State() != LifecycleStateStarting },
+			time.Millisecond*50, time.Millisecond*10)
+		close(becomeReady)
+		require.Equal(t, LifecycleStateReady, <-states)
+		require.True(t, service.State().IsReady())
+
+		service.Signals <- os.Interrupt
+		require.NoError(t, <-startErr)
+		require.Equal(t, LifecycleStateDraining, <-states)
+		require.Equal(t, LifecycleStateStopped, <-states)
+		require.Equal(t, LifecycleStateStopped, service.State())
+		require.False(t, service.State().IsAlive())
+
+		mockUnit.stateAtStopMu.Lock()
+		defer mockUnit.stateAtStopMu.Unlock()
+		require.Equal(t, LifecycleStateDraining, mockUnit.stateAtStop)
+	})
+
+	t.Run("fatal error", func(t *testing.T) {
+		failingUnit := newStagedMockUnit("srv", &eventsRecorder{})
+		failingUnit.startErr = errors.New("start error")
+		service := New(logtest.NewRecorder(), failingUnit)
+
+		require.ErrorContains(t, service.Start(), "start error")
+		require.Equal(t, LifecycleStateStopped, service.State())
+	})
+}
+
+// slowStopUnit is a unit which graceful stop is blocked until it's stopped non-gracefully.
+type slowStopUnit struct {
+	stopGracefullyCalled chan struct{}
+	stopForcibly         chan struct{}
+	stopForciblyOnce     sync.Once
+}
+
+func newSlowStopUnit() *slowStopUnit {
+	return &slowStopUnit{stopGracefullyCalled: make(chan struct{}, 1), stopForcibly: make(chan struct{})}
+}
+
+func (u *slowStopUnit) Start(fatalErr chan<- error) {
+	<-u.stopForcibly
+}
+
+func (u *slowStopUnit) Stop(gracefully bool) error {
+	if gracefully {
+		u.stopGracefullyCalled <- struct{}{}
+		<-u.stopForcibly
+		return nil
+	}
+	u.stopForciblyOnce.Do(func() { close(u.stopForcibly) })
+	return nil
+}
+
+func TestService_Shutdown(t *testing.T) {
+	t.Run("drain delay", func(t *testing.T) {
+		events := &eventsRecorder{}
+		unit := newStagedMockUnit("srv", events)
+		service := NewWithOpts(logtest.NewRecorder(), unit, Opts{
+			ShutdownSignals: []os.Signal{os.Interrupt},
+			DrainDelay:      time.Millisecond * 100,
+		})
+
+		startErr := make(chan error, 1)
+		go func() { startErr <- service.Start() }()
+		require.Eventually(t, func() bool { return service.State() == LifecycleStateReady },
+			time.Second, time.Millisecond*10)
+
+		service.Signals <- os.Interrupt
+		require.Eventually(t, func() bool { return service.State() == LifecycleStateDraining },
+			time.Second, time.Millisecond*10)
+		require.Never(t, func() bool { return len(events.get()) > 1 }, time.Millisecond*50, time.Millisecond*10)
+
+		require.NoError(t, <-startErr)
+		require.Equal(t, []string{"start srv", "stop srv"}, events.get())
+	})
+
+	t.Run("shutdown timeout exceeded", func(t *testing.T) {
+		logRecorder := logtest.NewRecorder()
+		unit := newSlowStopUnit()
+		service := NewWithOpts(logRecorder, unit, Opts{
+			ShutdownSignals: []os.Signal{os.Interrupt},
+			ShutdownTimeout: time.Millisecond * 50,
+		})
+
+		startErr := make(chan error, 1)
+		go func() { startErr <- service.Start() }()
+		service.Signals <- os.Interrupt
+
+		err := <-startErr
+		require.ErrorIs(t, err, ErrShutdownTimeoutExceeded)
+		require.EqualError(t, err, "stop service non-gracefully: shutdown timeout exceeded")
+		require.Len(t, unit.stopGracefullyCalled, 1)
+		logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelWarn),
+			logtest.HasMessage("service will be stopped non-gracefully"))
+	})
+
+	t.Run("shutdown timeout exceeded during drain delay", func(t *testing.T) {
+		unit := newSlowStopUnit()
+		service := NewWithOpts(logtest.NewRecorder(), unit, Opts{
+			ShutdownSignals: []os.Signal{os.Interrupt},
+			DrainDelay:      time.Second * 10,
+			ShutdownTimeout: time.Millisecond * 50,
+		})
+
+		startErr := make(chan error, 1)
+		go func() { startErr <- service.Start() }()
+		service.Signals <- os.Interrupt
+
+		require.ErrorIs(t, <-startErr, ErrShutdownTimeoutExceeded)
+		require.Empty(t, unit.stopGracefullyCalled)
+	})
+
+	t.Run("second signal forces shutdown", func(t *testing.T) {
+		unit := newSlowStopUnit()
+		service := NewWithOpts(logtest.NewRecorder(), unit, Opts{ShutdownSignals: []os.Signal{os.Interrupt}})
+
+		startErr := make(chan error, 1)
+		go func() { startErr <- service.Start() }()
+		service.Signals <- os.Interrupt
+		<-unit.stopGracefullyCalled
+		service.Signals <- os.Interrupt
+
+		err := <-startErr
+		require.ErrorIs(t, err, ErrForcedShutdown)
+		require.Equal(t, LifecycleStateStopped, service.State())
+	})
+
+	t.Run("stop durations are logged", func(t *testing.T) {
+		logRecorder := logtest.NewRecorder()
+		events := &eventsRecorder{}
+		db := newStagedMockUnit("db", events)
+		server := newStagedMockUnit("server", events)
+		server.stopErr = errors.New("server: stop error")
+		unit := NewStagedCompositeUnit(NewStage("storage", db), NewStage("api", NewCompositeUnit(server)))
+		service := NewWithOpts(logRecorder, unit, Opts{ShutdownSignals: []os.Signal{os.Interrupt}})
+
+		startErr := make(chan error, 1)
+		go func() { startErr <- service.Start() }()
+		require.Eventually(t, func() bool { return service.State() == LifecycleStateReady },
+			time.Second, time.Millisecond*10)
+		service.Signals <- os.Interrupt
+		require.EqualError(t, <-startErr, "stop service gracefully: server: stop error")
+
+		logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelInfo), logtest.HasMessage("unit is stopped"),
+			logtest.FieldEquals("stage", "storage"), logtest.FieldEquals("unit", "*service.stagedMockUnit"),
+			logtest.FieldEquals("gracefully", true), logtest.HasField("duration"))
+		logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelError),
+			logtest.HasMessage("unit is stopped with error"), logtest.FieldEquals("error", "server: stop error"))
+		logtest.RequireEntriesCount(t, logRecorder, 2, logtest.HasMessage("stage is stopped"))
+		logtest.RequireEntry(t, logRecorder, logtest.HasMessage("service is stopped gracefully with error"),
+			logtest.HasField("duration"))
+	})
+}
+
+type reloadableMockUnit struct {
+	*stagedMockUnit
+	reloadErr    error
+	reloadCalled atomic.Int32
+}
+
+func (u *reloadableMockUnit) Reload() error {
+	u.reloadCalled.Add(1)
+	return u.reloadErr
+}
+
+func TestService_Reload(t *testing.T) {
+	logRecorder := logtest.NewRecorder()
+	events := &eventsRecorder{}
+	cfg := &reloadableMockUnit{stagedMockUnit: newStagedMockUnit("cfg", events)}
+	logs := &reloadableMockUnit{stagedMockUnit: newStagedMockUnit("logs", events), reloadErr: errors.New("reopen error")}
+	server := newStagedMockUnit("server", events)
+	unit := NewStagedCompositeUnit(NewStage("base", NewCompositeUnit(cfg, logs)), NewStage("api", server))
+	service := NewWithOpts(logRecorder, unit, Opts{
+		ShutdownSignals: []os.Signal{os.Interrupt},
+		ReloadSignals:   []os.Signal{syscall.SIGHUP},
+	})
+
+	startErr := make(chan error, 1)
+	go func() { startErr <- service.Start() }()
+	require.Eventually(t, func() bool { return service.State() == LifecycleStateReady },
+		time.Second, time.Millisecond*10)
+
+	service.ReloadSignals <- syscall.SIGHUP
+	require.Eventually(t, func() bool { return cfg.reloadCalled.Load() == 1 && logs.reloadCalled.Load() == 1 },
+		time.Second, time.Millisecond*10)
+	require.Eventually(t, func() bool {
+		return len(logRecorder.FindEntries(logtest.HasMessage("service reload failed"))) == 1
+	}, time.Second, time.Millisecond*10)
+	logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelError),
+		logtest.HasMessage("service reload failed"), logtest.FieldEquals("error", "reopen error"))
+	require.Equal(t, LifecycleStateReady, service.State())
+
+	logs.reloadErr = nil
+	require.NoError(t, service.Reload())
+	require.Equal(t, int32(2), cfg.reloadCalled.Load())
+	logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelInfo), logtest.HasMessage("service is reloaded"))
+
+	service.Signals <- os.Interrupt
+	require.NoError(t, <-startErr)
+}
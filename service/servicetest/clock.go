@@ -0,0 +1,123 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package servicetest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/acronis/go-appkit/service"
+)
+
+// FakeClock is an implementation of service.Clock that allows controlling time in tests.
+// Time doesn't pass by itself, it's moved forward by Advance and Set methods that fire all expired timers.
+type FakeClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+var _ service.Clock = (*FakeClock)(nil)
+
+// NewFakeClock creates a new FakeClock with the given current time.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the current fake time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer creates a new timer that fires when the fake time reaches the current fake time plus d.
+func (c *FakeClock) NewTimer(d time.Duration) service.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, deadline: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance moves the fake time forward by d and fires all expired timers.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	now := c.now.Add(d)
+	c.mu.Unlock()
+	c.Set(now)
+}
+
+// Set sets the fake time and fires all expired timers.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+	active := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(now) {
+			active = append(active, t)
+			continue
+		}
+		t.ch <- now
+	}
+	for i := len(active); i < len(c.timers); i++ {
+		c.timers[i] = nil
+	}
+	c.timers = active
+}
+
+// ActiveTimers returns the number of timers that are not fired and not stopped yet.
+func (c *FakeClock) ActiveTimers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntilActiveTimers blocks until the number of active timers is at least n.
+// It's useful for waiting until the tested code creates a timer before advancing the time.
+func (c *FakeClock) BlockUntilActiveTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *FakeClock) removeTimer(t *fakeTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.timers {
+		if c.timers[i] == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.clock.removeTimer(t)
+}
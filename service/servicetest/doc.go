@@ -0,0 +1,8 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+// Package servicetest provides utilities for testing services, units and workers from the service package.
+package servicetest
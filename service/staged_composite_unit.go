@@ -0,0 +1,345 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/acronis/go-appkit/log"
+)
+
+// ErrStageStartTimeoutExceeded is an error that occurs when units of the StagedCompositeUnit's stage
+// don't become ready within the stage's start timeout.
+var ErrStageStartTimeoutExceeded = errors.New("stage start timeout exceeded")
+
+// ErrStageStopTimeoutExceeded is an error that occurs when units of the StagedCompositeUnit's stage
+// don't stop within the stage's stop timeout.
+var ErrStageStopTimeoutExceeded = errors.New("stage stop timeout exceeded")
+
+// errUnitFailed is an internal error that interrupts the waiting for the stage readiness.
+var errUnitFailed = errors.New("unit failed")
+
+// Stage represents a group of units that are started and stopped together within StagedCompositeUnit.
+type Stage struct {
+	// Name is used in error messages.
+	Name string
+
+	// Units are started and stopped concurrently within the stage.
+	Units []Unit
+
+	// StartTimeout limits the time of waiting for readiness of all units in the stage.
+	// Zero value means no timeout.
+	StartTimeout time.Duration
+
+	// StopTimeout limits the time of stopping all units in the stage.
+	// Zero value means no timeout.
+	StopTimeout time.Duration
+}
+
+// NewStage creates a new stage with the given name and units.
+func NewStage(name string, units ...Unit) Stage {
+	return Stage{Name: name, Units: units}
+}
+
+// StagedCompositeUnit represents a composition of service units that are started stage by stage
+// and stopped in the reverse order.
+// It allows to express dependencies between units, e.g., a database pool is started in the first stage,
+// a cache warmer in the second one, and an HTTP server in the last one, so the HTTP server is stopped
+// before the database pool.
+type StagedCompositeUnit struct {
+	Stages []Stage
+
+	// StopLogger is used for logging how long each stage and unit take to stop. Nothing is logged if it's nil.
+	StopLogger log.FieldLogger
+
+	// Events is used for publishing lifecycle events of the units. Nothing is published if it's nil.
+	Events *LifecycleEvents
+
+	mu            sync.Mutex
+	startedStages int
+	stopped       bool
+
+	readyOnce sync.Once
+	ready     chan struct{}
+}
+
+var _ ReadinessNotifier = (*StagedCompositeUnit)(nil)
+var _ StopLoggerSetter = (*StagedCompositeUnit)(nil)
+var _ LifecycleEventsSetter = (*StagedCompositeUnit)(nil)
+var _ Reloadable = (*StagedCompositeUnit)(nil)
+
+// NewStagedCompositeUnit creates a new staged composite unit.
+func NewStagedCompositeUnit(stages ...Stage) *StagedCompositeUnit {
+	return &StagedCompositeUnit{Stages: stages}
+}
+
+// Start launches stages one by one. Units within a stage are started concurrently, each in its own goroutine.
+// The next stage is started only when all units of the current stage are ready.
+// A unit is considered ready when the channel returned by its Ready method is closed (see ReadinessNotifier).
+// Units that don't implement ReadinessNotifier are considered ready when their Start methods return,
+// so a unit whose Start blocks for the unit's lifetime must implement ReadinessNotifier (as WorkerUnit does)
+// if it's not in the last stage. Otherwise, the following stages are never started.
+// The method blocks until all Start method invocations return.
+//
+// If any unit writes to its error channel or a stage doesn't become ready within its start timeout,
+// the method stops all started units (non-gracefully) in the reverse order by calling Stop(false).
+// A CompositeUnitError - potentially including errors from the stop operations - is then sent to the provided channel.
+func (scu *StagedCompositeUnit) Start(fatalError chan<- error) {
+	var unitsNum int
+	for i := range scu.Stages {
+		unitsNum += len(scu.Stages[i].Units)
+	}
+
+	fatalErrs := make([]chan error, 0, unitsNum)
+	failed := make(chan struct{}, unitsNum)
+	var startsWG sync.WaitGroup
+
+	allStagesReady := true
+	for i := range scu.Stages {
+		if !scu.beginStageStart() {
+			allStagesReady = false
+			break
+		}
+		stage := &scu.Stages[i]
+		readyChans := make([]<-chan struct{}, 0, len(stage.Units))
+		for _, unit := range stage.Units {
+			unitFatalErr := make(chan error, 1)
+			fatalErrs = append(fatalErrs, unitFatalErr)
+			startReturned := make(chan struct{})
+			startsWG.Add(1)
+			go func(unit Unit) {
+				defer startsWG.Done()
+				startUnit(unit, unitFatalErr, scu.Events)
+				if len(unitFatalErr) != 0 {
+					failed <- struct{}{}
+				}
+				close(startReturned)
+			}(unit)
+			if rn, ok := readinessNotifier(unit); ok {
+				readyChans = append(readyChans, rn.Ready())
+			} else {
+				readyChans = append(readyChans, startReturned)
+			}
+		}
+		if err := waitStageReady(stage, readyChans, failed); err != nil {
+			if errors.Is(err, errUnitFailed) {
+				err = nil
+			}
+			scu.stopOnFailure(err, fatalErrs, fatalError)
+			return
+		}
+	}
+
+	if allStagesReady {
+		close(scu.readyChan())
+	}
+
+	allStarted := make(chan struct{})
+	go func() {
+		startsWG.Wait()
+		close(allStarted)
+	}()
+
+	select {
+	case <-allStarted:
+	case <-failed:
+		scu.stopOnFailure(nil, fatalErrs, fatalError)
+	}
+}
+
+// Ready returns a channel that is closed when units of all stages are ready.
+func (scu *StagedCompositeUnit) Ready() <-chan struct{} {
+	return scu.readyChan()
+}
+
+func (scu *StagedCompositeUnit) readyChan() chan struct{} {
+	scu.readyOnce.Do(func() {
+		scu.ready = make(chan struct{})
+	})
+	return scu.ready
+}
+
+func (scu *StagedCompositeUnit) beginStageStart() bool {
+	scu.mu.Lock()
+	defer scu.mu.Unlock()
+	if scu.stopped {
+		return false
+	}
+	scu.startedStages++
+	return true
+}
+
+// readinessNotifier returns the ReadinessNotifier of the unit.
+// NamedUnit always implements ReadinessNotifier, so the wrapped unit is checked instead.
+func readinessNotifier(unit Unit) (ReadinessNotifier, bool) {
+	if nu, ok := unit.(*NamedUnit); ok {
+		if _, ok = readinessNotifier(nu.Unit); !ok {
+			return nil, false
+		}
+		return nu, true
+	}
+	rn, ok := unit.(ReadinessNotifier)
+	return rn, ok
+}
+
+func waitStageReady(stage *Stage, readyChans []<-chan struct{}, failed <-chan struct{}) error {
+	var timeout <-chan time.Time
+	if stage.StartTimeout > 0 {
+		timer := time.NewTimer(stage.StartTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for _, ready := range readyChans {
+		select {
+		case <-ready:
+		case <-failed:
+			return errUnitFailed
+		case <-timeout:
+			return fmt.Errorf("stage %q: %w", stage.Name, ErrStageStartTimeoutExceeded)
+		}
+	}
+	return nil
+}
+
+func (scu *StagedCompositeUnit) stopOnFailure(startErr error, fatalErrs []chan error, fatalError chan<- error) {
+	stopErr := scu.Stop(false)
+
+	var errs []error
+	if startErr != nil {
+		errs = append(errs, startErr)
+	}
+	for _, fatalErr := range fatalErrs {
+		select {
+		case err := <-fatalErr:
+			errs = append(errs, err)
+		default:
+		}
+	}
+	if stopErr != nil {
+		errs = append(errs, stopErr.(*CompositeUnitError).UnitErrors...)
+	}
+	if len(errs) > 0 {
+		fatalError <- &CompositeUnitError{errs}
+	}
+}
+
+// Stop stops started stages in the reverse order. Units within a stage are stopped concurrently.
+// If a stage doesn't stop within its stop timeout, ErrStageStopTimeoutExceeded is recorded and the next stage is stopped.
+// Stages that are not started yet will not be started after calling this method.
+// Errors that occurred while stopping the units are collected and single CompositeUnitError is returned.
+func (scu *StagedCompositeUnit) Stop(gracefully bool) error {
+	scu.mu.Lock()
+	scu.stopped = true
+	startedStages := scu.startedStages
+	scu.mu.Unlock()
+
+	var errs []error
+	for i := startedStages - 1; i >= 0; i-- {
+		errs = append(errs, scu.stopStage(&scu.Stages[i], gracefully)...)
+	}
+	if len(errs) > 0 {
+		return &CompositeUnitError{errs}
+	}
+	return nil
+}
+
+func (scu *StagedCompositeUnit) stopStage(stage *Stage, gracefully bool) []error {
+	var logger log.FieldLogger
+	if scu.StopLogger != nil {
+		logger = scu.StopLogger.With(log.String("stage", stage.Name))
+		startTime := time.Now()
+		defer func() {
+			logger.Info("stage is stopped", log.Duration("duration", time.Since(startTime)))
+		}()
+	}
+	results := make(chan error, len(stage.Units))
+	for _, unit := range stage.Units {
+		go func(unit Unit) {
+			results <- stopUnit(unit, gracefully, logger, scu.Events)
+		}(unit)
+	}
+
+	var timeout <-chan time.Time
+	if stage.StopTimeout > 0 {
+		timer := time.NewTimer(stage.StopTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var errs []error
+	for i := 0; i < len(stage.Units); i++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case <-timeout:
+			return append(errs, fmt.Errorf("stage %q: %w", stage.Name, ErrStageStopTimeoutExceeded))
+		}
+	}
+	return errs
+}
+
+// Reload reloads units of all stages that implement Reloadable one by one in the order of stages.
+// Errors that occurred while reloading the units are collected and single CompositeUnitError is returned.
+func (scu *StagedCompositeUnit) Reload() error {
+	var errs []error
+	for i := range scu.Stages {
+		errs = append(errs, reloadUnits(scu.Stages[i].Units)...)
+	}
+	if len(errs) > 0 {
+		return &CompositeUnitError{errs}
+	}
+	return nil
+}
+
+// SetStopLogger sets the logger for logging how long each stage and unit take to stop if no logger is set yet.
+// The logger is also passed to the nested units that implement StopLoggerSetter.
+func (scu *StagedCompositeUnit) SetStopLogger(logger log.FieldLogger) {
+	if scu.StopLogger == nil {
+		scu.StopLogger = logger
+	}
+	for i := range scu.Stages {
+		setStopLogger(scu.Stages[i].Units, logger)
+	}
+}
+
+// SetLifecycleEvents sets the dispatcher for publishing lifecycle events of the units if no dispatcher is set yet.
+// The dispatcher is also passed to the nested units that implement LifecycleEventsSetter.
+func (scu *StagedCompositeUnit) SetLifecycleEvents(events *LifecycleEvents) {
+	if scu.Events == nil {
+		scu.Events = events
+	}
+	for i := range scu.Stages {
+		setLifecycleEvents(scu.Stages[i].Units, events)
+	}
+}
+
+// MustRegisterMetrics registers metrics in Prometheus client and panics if any error occurs.
+func (scu *StagedCompositeUnit) MustRegisterMetrics() {
+	for i := range scu.Stages {
+		for _, unit := range scu.Stages[i].Units {
+			if mr, ok := unit.(MetricsRegisterer); ok {
+				mr.MustRegisterMetrics()
+			}
+		}
+	}
+}
+
+// UnregisterMetrics unregisters metrics in Prometheus client.
+func (scu *StagedCompositeUnit) UnregisterMetrics() {
+	for i := range scu.Stages {
+		for _, unit := range scu.Stages[i].Units {
+			if mr, ok := unit.(MetricsRegisterer); ok {
+				mr.UnregisterMetrics()
+			}
+		}
+	}
+}
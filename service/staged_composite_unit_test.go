@@ -0,0 +1,265 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type eventsRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *eventsRecorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *eventsRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+type stagedMockUnit struct {
+	name      string
+	events    *eventsRecorder
+	ready     chan struct{}
+	readyFunc func(u *stagedMockUnit) // is called in Start, closes ready channel by default
+	startErr  error
+	stopErr   error
+	stopDelay time.Duration
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+func newStagedMockUnit(name string, events *eventsRecorder) *stagedMockUnit {
+	return &stagedMockUnit{
+		name:   name,
+		events: events,
+		ready:  make(chan struct{}),
+		stop:   make(chan struct{}),
+		readyFunc: func(u *stagedMockUnit) {
+			close(u.ready)
+		},
+	}
+}
+
+func (u *stagedMockUnit) Start(fatalError chan<- error) {
+	u.events.add("start " + u.name)
+	if u.startErr != nil {
+		fatalError <- u.startErr
+		return
+	}
+	u.readyFunc(u)
+	<-u.stop
+}
+
+func (u *stagedMockUnit) Ready() <-chan struct{} {
+	return u.ready
+}
+
+func (u *stagedMockUnit) Stop(gracefully bool) error {
+	time.Sleep(u.stopDelay)
+	u.events.add("stop " + u.name)
+	u.stopOnce.Do(func() { close(u.stop) })
+	return u.stopErr
+}
+
+// plainStagedUnit doesn't implement ReadinessNotifier, its Start performs initialization and returns.
+type plainStagedUnit struct {
+	name      string
+	events    *eventsRecorder
+	initDelay time.Duration
+}
+
+func (u *plainStagedUnit) Start(fatalError chan<- error) {
+	time.Sleep(u.initDelay)
+	u.events.add("start " + u.name)
+}
+
+func (u *plainStagedUnit) Stop(gracefully bool) error {
+	u.events.add("stop " + u.name)
+	return nil
+}
+
+func TestStagedCompositeUnit_StartAndStop(t *testing.T) {
+	t.Run("stages of units without readiness notification are started in order", func(t *testing.T) {
+		events := &eventsRecorder{}
+		worker := NewWorkerUnit(WorkerFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		}))
+		unit := NewStagedCompositeUnit(
+			NewStage("storage", &plainStagedUnit{name: "db", events: events, initDelay: time.Millisecond * 50}),
+			NewStage("cache", NewNamedUnit("warmer", &plainStagedUnit{name: "warmer", events: events}), worker),
+			NewStage("api", &plainStagedUnit{name: "server", events: events}),
+		)
+
+		startExit := make(chan struct{})
+		fatalErr := make(chan error, 1)
+		go func() {
+			defer close(startExit)
+			unit.Start(fatalErr)
+		}()
+
+		select {
+		case <-unit.Ready():
+		case <-time.After(time.Second):
+			require.Fail(t, "waiting readiness of the staged unit is timed out")
+		}
+		require.Equal(t, []string{"start db", "start warmer", "start server"}, events.get())
+
+		require.NoError(t, unit.Stop(true))
+		select {
+		case <-startExit:
+		case <-time.After(time.Second):
+			require.Fail(t, "waiting finish of Start() is timed out")
+		}
+		require.Empty(t, fatalErr)
+	})
+
+	t.Run("stages are started in order and stopped in reverse order", func(t *testing.T) {
+		events := &eventsRecorder{}
+		db := newStagedMockUnit("db", events)
+		db.readyFunc = func(u *stagedMockUnit) {
+			time.Sleep(time.Millisecond * 50)
+			events.add("ready db")
+			close(u.ready)
+		}
+		warmer := newStagedMockUnit("warmer", events)
+		server := newStagedMockUnit("server", events)
+
+		unit := NewStagedCompositeUnit(
+			NewStage("storage", db),
+			NewStage("cache", warmer),
+			NewStage("api", server),
+		)
+
+		startExit := make(chan struct{})
+		fatalErr := make(chan error, 1)
+		go func() {
+			defer close(startExit)
+			unit.Start(fatalErr)
+		}()
+
+		require.Eventually(t, func() bool { return len(events.get()) == 4 }, time.Second, time.Millisecond*10)
+		require.NoError(t, unit.Stop(true))
+
+		select {
+		case <-startExit:
+		case <-time.After(time.Second):
+			require.Fail(t, "waiting finish of Start() is timed out")
+		}
+		require.Empty(t, fatalErr)
+		require.Equal(t, []string{
+			"start db", "ready db", "start warmer", "start server",
+			"stop server", "stop warmer", "stop db",
+		}, events.get())
+	})
+
+	t.Run("stage start timeout exceeded", func(t *testing.T) {
+		events := &eventsRecorder{}
+		db := newStagedMockUnit("db", events)
+		db.stopErr = errors.New("db: stop error")
+		hanging := newStagedMockUnit("hanging", events)
+		hanging.readyFunc = func(u *stagedMockUnit) {}
+		server := newStagedMockUnit("server", events)
+
+		unit := NewStagedCompositeUnit(
+			NewStage("storage", db),
+			Stage{Name: "hanging", Units: []Unit{hanging}, StartTimeout: time.Millisecond * 50},
+			NewStage("api", server),
+		)
+
+		fatalErr := make(chan error, 1)
+		unit.Start(fatalErr)
+
+		require.Len(t, fatalErr, 1)
+		err := <-fatalErr
+		var cuErr *CompositeUnitError
+		require.ErrorAs(t, err, &cuErr)
+		require.Len(t, cuErr.UnitErrors, 2)
+		require.ErrorIs(t, err, ErrStageStartTimeoutExceeded)
+		require.EqualError(t, err, `stage "hanging": stage start timeout exceeded; db: stop error`)
+		require.Equal(t, []string{"start db", "start hanging", "stop hanging", "stop db"}, events.get())
+	})
+
+	t.Run("unit fails during start", func(t *testing.T) {
+		events := &eventsRecorder{}
+		db := newStagedMockUnit("db", events)
+		failing := newStagedMockUnit("failing", events)
+		failing.startErr = errors.New("failing: start error")
+		server := newStagedMockUnit("server", events)
+
+		unit := NewStagedCompositeUnit(
+			NewStage("storage", db),
+			NewStage("failing", failing),
+			NewStage("api", server),
+		)
+
+		fatalErr := make(chan error, 1)
+		unit.Start(fatalErr)
+
+		require.Len(t, fatalErr, 1)
+		require.EqualError(t, <-fatalErr, "failing: start error")
+		require.Equal(t, []string{"start db", "start failing", "stop failing", "stop db"}, events.get())
+	})
+
+	t.Run("stage stop timeout exceeded", func(t *testing.T) {
+		events := &eventsRecorder{}
+		db := newStagedMockUnit("db", events)
+		slow := newStagedMockUnit("slow", events)
+		slow.stopDelay = time.Millisecond * 200
+
+		unit := NewStagedCompositeUnit(
+			NewStage("storage", db),
+			Stage{Name: "slow", Units: []Unit{slow}, StopTimeout: time.Millisecond * 20},
+		)
+
+		go unit.Start(make(chan error, 1))
+		require.Eventually(t, func() bool { return len(events.get()) == 2 }, time.Second, time.Millisecond*10)
+
+		err := unit.Stop(true)
+		require.ErrorIs(t, err, ErrStageStopTimeoutExceeded)
+		require.EqualError(t, err, `stage "slow": stage stop timeout exceeded`)
+		require.Equal(t, []string{"start db", "start slow", "stop db"}, events.get())
+	})
+
+	t.Run("stop before start", func(t *testing.T) {
+		events := &eventsRecorder{}
+		unit := NewStagedCompositeUnit(NewStage("storage", newStagedMockUnit("db", events)))
+
+		require.NoError(t, unit.Stop(true))
+		fatalErr := make(chan error, 1)
+		unit.Start(fatalErr)
+		require.Empty(t, fatalErr)
+		require.Empty(t, events.get())
+	})
+}
+
+func TestStagedCompositeUnit_Metrics(t *testing.T) {
+	var runningCounter int32
+	unit1 := newMockUnit("unit1", &runningCounter, false)
+	unit2 := newMockUnit("unit2", &runningCounter, false)
+	unit := NewStagedCompositeUnit(NewStage("first", unit1), NewStage("second", unit2))
+
+	unit.MustRegisterMetrics()
+	unit.UnregisterMetrics()
+	for _, u := range []*mockUnit{unit1, unit2} {
+		require.Equal(t, 1, u.mustRegisterMetricsCalled)
+		require.Equal(t, 1, u.unregisterMetricsCalled)
+	}
+}
@@ -0,0 +1,242 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/acronis/go-appkit/log"
+	"github.com/acronis/go-appkit/retry"
+)
+
+// ErrRestartPolicyExhausted is an error that is returned by Supervisor when the worker cannot be restarted anymore.
+var ErrRestartPolicyExhausted = errors.New("restart policy exhausted")
+
+// RestartPolicy defines when Supervisor restarts the underlying worker.
+type RestartPolicy int
+
+// Restart policies.
+const (
+	// RestartOnFailure restarts the worker only if it returns an error or panics.
+	RestartOnFailure RestartPolicy = iota
+	// RestartAlways restarts the worker even if it returns without an error.
+	RestartAlways
+	// RestartNever never restarts the worker, the error is returned as is.
+	RestartNever
+)
+
+// Restart reasons that are used in logs and metrics.
+const (
+	RestartReasonError     = "error"
+	RestartReasonPanic     = "panic"
+	RestartReasonCompleted = "completed"
+)
+
+// Default values for SupervisorOpts.
+const (
+	DefaultSupervisorBackoffInitialInterval = time.Second
+	DefaultSupervisorBackoffMaxInterval     = time.Minute
+)
+
+// SupervisorOpts contains optional parameters for constructing Supervisor.
+type SupervisorOpts struct {
+	// Name is used in logs and metrics for identifying the worker.
+	Name string
+
+	// RestartPolicy defines when the worker is restarted. RestartOnFailure is used by default.
+	RestartPolicy RestartPolicy
+
+	// MaxRestarts limits the number of restarts within RestartWindow.
+	// If RestartWindow is zero, MaxRestarts limits the total number of restarts.
+	// Zero value means no limit.
+	MaxRestarts   int
+	RestartWindow time.Duration
+
+	// BackoffPolicy defines delays between restarts. If the backoff returns backoff.Stop, the policy is exhausted.
+	// The backoff is reset every time the worker returns without an error.
+	// By default, exponential backoff (from 1 second up to 1 minute) without limits is used.
+	BackoffPolicy retry.Policy
+
+	// MetricsCollector is used for collecting restart metrics.
+	MetricsCollector SupervisorMetricsCollector
+}
+
+// Supervisor is a Worker that runs the underlying worker and restarts it according to the restart policy.
+// The error is returned (and escalated to a fatal one if Supervisor is wrapped by WorkerUnit)
+// only when the policy is exhausted.
+type Supervisor struct {
+	worker           Worker
+	logger           log.FieldLogger
+	name             string
+	restartPolicy    RestartPolicy
+	maxRestarts      int
+	restartWindow    time.Duration
+	backoffPolicy    retry.Policy
+	metricsCollector SupervisorMetricsCollector
+}
+
+// NewSupervisor creates a new instance of Supervisor with default options.
+func NewSupervisor(worker Worker, logger log.FieldLogger) *Supervisor {
+	return NewSupervisorWithOpts(worker, logger, SupervisorOpts{})
+}
+
+// NewSupervisorWithOpts creates a new instance of Supervisor
+// with an ability to specify different optional parameters.
+func NewSupervisorWithOpts(worker Worker, logger log.FieldLogger, opts SupervisorOpts) *Supervisor {
+	if opts.Name != "" {
+		logger = logger.With(log.String("worker", opts.Name))
+	}
+	if opts.BackoffPolicy == nil {
+		opts.BackoffPolicy = retry.PolicyFunc(func() backoff.BackOff {
+			eb := backoff.NewExponentialBackOff()
+			eb.InitialInterval = DefaultSupervisorBackoffInitialInterval
+			eb.MaxInterval = DefaultSupervisorBackoffMaxInterval
+			eb.MaxElapsedTime = 0
+			eb.Reset()
+			return eb
+		})
+	}
+	if opts.MetricsCollector == nil {
+		opts.MetricsCollector = disabledSupervisorMetrics{}
+	}
+	return &Supervisor{
+		worker:           worker,
+		logger:           logger,
+		name:             opts.Name,
+		restartPolicy:    opts.RestartPolicy,
+		maxRestarts:      opts.MaxRestarts,
+		restartWindow:    opts.RestartWindow,
+		backoffPolicy:    opts.BackoffPolicy,
+		metricsCollector: opts.MetricsCollector,
+	}
+}
+
+// NewSupervisorUnit creates a new WorkerUnit that runs the worker under Supervisor.
+// Supervisor's metrics are registered and unregistered by the unit.
+func NewSupervisorUnit(worker Worker, logger log.FieldLogger, opts SupervisorOpts) *WorkerUnit {
+	supervisor := NewSupervisorWithOpts(worker, logger, opts)
+	return NewWorkerUnitWithOpts(supervisor, WorkerUnitOpts{MetricsRegisterer: supervisor})
+}
+
+// Run runs the underlying worker and restarts it according to the restart policy until the context is canceled.
+func (s *Supervisor) Run(ctx context.Context) error {
+	bo := s.backoffPolicy.NewBackOff()
+	restarts := 0
+	var restartTimes []time.Time // Used only if both MaxRestarts and RestartWindow are set.
+
+	for {
+		panicked, err := s.runWorker(ctx)
+		if ctx.Err() != nil {
+			return err
+		}
+
+		var reason string
+		switch {
+		case panicked:
+			reason = RestartReasonPanic
+		case err != nil:
+			reason = RestartReasonError
+		default:
+			if s.restartPolicy != RestartAlways {
+				return nil
+			}
+			reason = RestartReasonCompleted
+			bo.Reset()
+		}
+
+		if err != nil {
+			s.logger.Error("supervised worker failed", log.Error(err))
+			if s.restartPolicy == RestartNever {
+				return err
+			}
+		}
+
+		now := time.Now()
+		if s.maxRestarts > 0 {
+			recentRestarts := restarts
+			if s.restartWindow > 0 {
+				restartTimes = dropRestartTimesBefore(restartTimes, now.Add(-s.restartWindow))
+				recentRestarts = len(restartTimes)
+			}
+			if recentRestarts >= s.maxRestarts {
+				return s.makeExhaustedError(fmt.Sprintf("max restarts (%d) reached", s.maxRestarts), err)
+			}
+		}
+
+		delay := bo.NextBackOff()
+		if delay == backoff.Stop {
+			return s.makeExhaustedError("backoff stopped", err)
+		}
+
+		s.logger.Warn("restarting supervised worker",
+			log.String("reason", reason), log.Duration("delay", delay), log.Int("restarts", restarts))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		restarts++
+		if s.maxRestarts > 0 && s.restartWindow > 0 {
+			restartTimes = append(restartTimes, now)
+		}
+		s.metricsCollector.IncRestarts(s.name, reason)
+	}
+}
+
+func (s *Supervisor) runWorker(ctx context.Context) (panicked bool, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			const logStackSize = 8192
+			stack := make([]byte, logStackSize)
+			stack = stack[:runtime.Stack(stack, false)]
+			s.logger.Error(fmt.Sprintf("supervised worker panic: %+v", p), log.Bytes("stack", stack))
+			err = fmt.Errorf("worker panic: %v", p)
+			panicked = true
+		}
+	}()
+	return false, s.worker.Run(ctx)
+}
+
+func (s *Supervisor) makeExhaustedError(details string, lastErr error) error {
+	s.logger.Error("supervised worker cannot be restarted", log.String("details", details), log.Error(lastErr))
+	if lastErr == nil {
+		return fmt.Errorf("%w: %s", ErrRestartPolicyExhausted, details)
+	}
+	return fmt.Errorf("%w: %s: %w", ErrRestartPolicyExhausted, details, lastErr)
+}
+
+func dropRestartTimesBefore(restartTimes []time.Time, threshold time.Time) []time.Time {
+	i := 0
+	for i < len(restartTimes) && restartTimes[i].Before(threshold) {
+		i++
+	}
+	return restartTimes[i:]
+}
+
+// MustRegisterMetrics registers Supervisor's metrics if the metrics collector supports registration.
+func (s *Supervisor) MustRegisterMetrics() {
+	if r, ok := s.metricsCollector.(interface{ MustRegister() }); ok {
+		r.MustRegister()
+	}
+}
+
+// UnregisterMetrics unregisters Supervisor's metrics if the metrics collector supports registration.
+func (s *Supervisor) UnregisterMetrics() {
+	if r, ok := s.metricsCollector.(interface{ Unregister() }); ok {
+		r.Unregister()
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/acronis/go-appkit/internal/libinfo"
+)
+
+// SupervisorMetricsCollector represents a collector of metrics for Supervisor.
+type SupervisorMetricsCollector interface {
+	// IncRestarts increments the total number of worker restarts.
+	IncRestarts(workerName string, reason string)
+}
+
+// SupervisorPrometheusMetricsOpts represents options for SupervisorPrometheusMetrics.
+type SupervisorPrometheusMetricsOpts struct {
+	// Namespace is a namespace for metrics. It will be prepended to all metric names.
+	Namespace string
+
+	// ConstLabels is a set of labels that will be applied to all metrics.
+	ConstLabels prometheus.Labels
+}
+
+// SupervisorPrometheusMetrics represents a Prometheus metrics for Supervisor.
+type SupervisorPrometheusMetrics struct {
+	RestartsTotal *prometheus.CounterVec
+}
+
+// NewSupervisorPrometheusMetrics creates a new instance of SupervisorPrometheusMetrics with default options.
+func NewSupervisorPrometheusMetrics() *SupervisorPrometheusMetrics {
+	return NewSupervisorPrometheusMetricsWithOpts(SupervisorPrometheusMetricsOpts{})
+}
+
+// NewSupervisorPrometheusMetricsWithOpts creates a new instance of SupervisorPrometheusMetrics with the provided options.
+func NewSupervisorPrometheusMetricsWithOpts(opts SupervisorPrometheusMetricsOpts) *SupervisorPrometheusMetrics {
+	restartsTotal := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "supervised_worker_restarts_total",
+			Help:        "Number of supervised worker restarts.",
+			ConstLabels: libinfo.AddPrometheusLibVersionLabel(opts.ConstLabels),
+		},
+		[]string{"worker", "reason"},
+	)
+	return &SupervisorPrometheusMetrics{RestartsTotal: restartsTotal}
+}
+
+// IncRestarts increments the total number of worker restarts.
+func (pm *SupervisorPrometheusMetrics) IncRestarts(workerName string, reason string) {
+	pm.RestartsTotal.WithLabelValues(workerName, reason).Inc()
+}
+
+// MustRegister does registration of metrics collector in Prometheus and panics if any error occurs.
+func (pm *SupervisorPrometheusMetrics) MustRegister() {
+	prometheus.MustRegister(pm.RestartsTotal)
+}
+
+// Unregister cancels registration of metrics collector in Prometheus.
+func (pm *SupervisorPrometheusMetrics) Unregister() {
+	prometheus.Unregister(pm.RestartsTotal)
+}
+
+type disabledSupervisorMetrics struct{}
+
+func (disabledSupervisorMetrics) IncRestarts(string, string) {}
@@ -0,0 +1,182 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/acronis/go-appkit/log"
+	"github.com/acronis/go-appkit/log/logtest"
+	"github.com/acronis/go-appkit/retry"
+)
+
+func TestSupervisor_Run(t *testing.T) {
+	errWorker := errors.New("worker error")
+	constantBackoff := retry.NewConstantBackoffPolicy(time.Millisecond, 0)
+
+	t.Run("restart on failure until success", func(t *testing.T) {
+		var calls atomic.Int32
+		worker := WorkerFunc(func(ctx context.Context) error {
+			if calls.Inc() < 3 {
+				return errWorker
+			}
+			return nil
+		})
+		metrics := NewSupervisorPrometheusMetrics()
+		logRecorder := logtest.NewRecorder()
+		supervisor := NewSupervisorWithOpts(worker, logRecorder, SupervisorOpts{
+			Name:             "consumer",
+			BackoffPolicy:    constantBackoff,
+			MetricsCollector: metrics,
+		})
+
+		require.NoError(t, supervisor.Run(context.Background()))
+		require.Equal(t, int32(3), calls.Load())
+		require.Equal(t, 2.0, testutil.ToFloat64(metrics.RestartsTotal.WithLabelValues("consumer", RestartReasonError)))
+		logtest.RequireEntriesCount(t, logRecorder, 2,
+			logtest.HasMessage("restarting supervised worker"), logtest.FieldEquals("worker", "consumer"))
+	})
+
+	t.Run("restart on panic", func(t *testing.T) {
+		var calls atomic.Int32
+		worker := WorkerFunc(func(ctx context.Context) error {
+			if calls.Inc() == 1 {
+				panic("oops")
+			}
+			return nil
+		})
+		metrics := NewSupervisorPrometheusMetrics()
+		supervisor := NewSupervisorWithOpts(worker, log.NewDisabledLogger(), SupervisorOpts{
+			BackoffPolicy:    constantBackoff,
+			MetricsCollector: metrics,
+		})
+
+		require.NoError(t, supervisor.Run(context.Background()))
+		require.Equal(t, int32(2), calls.Load())
+		require.Equal(t, 1.0, testutil.ToFloat64(metrics.RestartsTotal.WithLabelValues("", RestartReasonPanic)))
+	})
+
+	t.Run("never restart", func(t *testing.T) {
+		var calls atomic.Int32
+		worker := WorkerFunc(func(ctx context.Context) error {
+			calls.Inc()
+			return errWorker
+		})
+		supervisor := NewSupervisorWithOpts(worker, log.NewDisabledLogger(), SupervisorOpts{
+			RestartPolicy: RestartNever,
+		})
+
+		require.ErrorIs(t, supervisor.Run(context.Background()), errWorker)
+		require.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("max restarts reached", func(t *testing.T) {
+		var calls atomic.Int32
+		worker := WorkerFunc(func(ctx context.Context) error {
+			calls.Inc()
+			return errWorker
+		})
+		supervisor := NewSupervisorWithOpts(worker, log.NewDisabledLogger(), SupervisorOpts{
+			MaxRestarts:   3,
+			RestartWindow: time.Minute,
+			BackoffPolicy: constantBackoff,
+		})
+
+		err := supervisor.Run(context.Background())
+		require.ErrorIs(t, err, ErrRestartPolicyExhausted)
+		require.ErrorIs(t, err, errWorker)
+		require.EqualError(t, err, "restart policy exhausted: max restarts (3) reached: worker error")
+		require.Equal(t, int32(4), calls.Load())
+	})
+
+	t.Run("restarts outside window are not counted", func(t *testing.T) {
+		var calls atomic.Int32
+		worker := WorkerFunc(func(ctx context.Context) error {
+			if calls.Inc() > 5 {
+				return nil
+			}
+			time.Sleep(time.Millisecond * 30)
+			return errWorker
+		})
+		supervisor := NewSupervisorWithOpts(worker, log.NewDisabledLogger(), SupervisorOpts{
+			MaxRestarts:   1,
+			RestartWindow: time.Millisecond * 20,
+			BackoffPolicy: constantBackoff,
+		})
+
+		require.NoError(t, supervisor.Run(context.Background()))
+		require.Equal(t, int32(6), calls.Load())
+	})
+
+	t.Run("backoff stopped", func(t *testing.T) {
+		var calls atomic.Int32
+		worker := WorkerFunc(func(ctx context.Context) error {
+			calls.Inc()
+			return errWorker
+		})
+		supervisor := NewSupervisorWithOpts(worker, log.NewDisabledLogger(), SupervisorOpts{
+			BackoffPolicy: retry.NewConstantBackoffPolicy(time.Millisecond, 2),
+		})
+
+		err := supervisor.Run(context.Background())
+		require.ErrorIs(t, err, ErrRestartPolicyExhausted)
+		require.ErrorIs(t, err, errWorker)
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("always restart until context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var calls atomic.Int32
+		worker := WorkerFunc(func(ctx context.Context) error {
+			if calls.Inc() == 5 {
+				cancel()
+			}
+			return nil
+		})
+		metrics := NewSupervisorPrometheusMetrics()
+		supervisor := NewSupervisorWithOpts(worker, log.NewDisabledLogger(), SupervisorOpts{
+			RestartPolicy:    RestartAlways,
+			BackoffPolicy:    constantBackoff,
+			MetricsCollector: metrics,
+		})
+
+		require.NoError(t, supervisor.Run(ctx))
+		require.Equal(t, int32(5), calls.Load())
+		require.Equal(t, 4.0, testutil.ToFloat64(metrics.RestartsTotal.WithLabelValues("", RestartReasonCompleted)))
+	})
+}
+
+func TestSupervisorUnit(t *testing.T) {
+	errWorker := errors.New("worker error")
+	metrics := NewSupervisorPrometheusMetrics()
+	unit := NewSupervisorUnit(WorkerFunc(func(ctx context.Context) error {
+		return errWorker
+	}), log.NewDisabledLogger(), SupervisorOpts{
+		MaxRestarts:      2,
+		BackoffPolicy:    retry.NewConstantBackoffPolicy(time.Millisecond, 0),
+		MetricsCollector: metrics,
+	})
+
+	unit.MustRegisterMetrics()
+	defer unit.UnregisterMetrics()
+
+	fatalErr := make(chan error, 1)
+	unit.Start(fatalErr)
+	require.Len(t, fatalErr, 1)
+	require.ErrorIs(t, <-fatalErr, ErrRestartPolicyExhausted)
+	require.NoError(t, unit.Stop(true))
+	require.Equal(t, 2.0, testutil.ToFloat64(metrics.RestartsTotal.WithLabelValues("", RestartReasonError)))
+}
@@ -0,0 +1,125 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables that are set by systemd for services with Type=notify.
+const (
+	SystemdNotifySocketEnvVar = "NOTIFY_SOCKET"
+	SystemdWatchdogUSecEnvVar = "WATCHDOG_USEC"
+	SystemdWatchdogPIDEnvVar  = "WATCHDOG_PID"
+)
+
+// Messages that may be sent to systemd (see sd_notify(3)).
+const (
+	SystemdNotifyReady     = "READY=1"
+	SystemdNotifyStopping  = "STOPPING=1"
+	SystemdNotifyReloading = "RELOADING=1"
+	SystemdNotifyWatchdog  = "WATCHDOG=1"
+)
+
+// SystemdNotifyStatus returns a message that describes the service status in a free-form string.
+func SystemdNotifyStatus(status string) string {
+	return "STATUS=" + status
+}
+
+// SystemdNotifier sends notifications about the service state to systemd over the unix datagram socket.
+// It may be used with Service (see Opts.SystemdNotifier) that sends READY=1, STOPPING=1 and STATUS= messages
+// according to its lifecycle state and periodic WATCHDOG=1 messages while it's running.
+type SystemdNotifier struct {
+	socketAddr       *net.UnixAddr
+	watchdogInterval time.Duration
+}
+
+// NewSystemdNotifier creates a new SystemdNotifier that sends notifications to the socket by the given path.
+// Paths starting with "@" are treated as abstract namespace sockets.
+// Zero watchdogInterval means that the watchdog is disabled.
+func NewSystemdNotifier(socketPath string, watchdogInterval time.Duration) *SystemdNotifier {
+	return &SystemdNotifier{
+		socketAddr:       &net.UnixAddr{Name: socketPath, Net: "unixgram"},
+		watchdogInterval: watchdogInterval,
+	}
+}
+
+// NewSystemdNotifierFromEnv creates a new SystemdNotifier using the environment variables set by systemd.
+// It returns nil if the NOTIFY_SOCKET environment variable is not set, i.e., the process is not run by systemd
+// or the service type is not "notify".
+func NewSystemdNotifierFromEnv() (*SystemdNotifier, error) {
+	socketPath := os.Getenv(SystemdNotifySocketEnvVar)
+	if socketPath == "" {
+		return nil, nil
+	}
+	watchdogInterval, err := systemdWatchdogIntervalFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewSystemdNotifier(socketPath, watchdogInterval), nil
+}
+
+func systemdWatchdogIntervalFromEnv() (time.Duration, error) {
+	usecStr := os.Getenv(SystemdWatchdogUSecEnvVar)
+	if usecStr == "" {
+		return 0, nil
+	}
+	if pidStr := os.Getenv(SystemdWatchdogPIDEnvVar); pidStr != "" {
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			return 0, fmt.Errorf("parse %s environment variable: %w", SystemdWatchdogPIDEnvVar, err)
+		}
+		if pid != os.Getpid() {
+			return 0, nil // Watchdog is intended for another process.
+		}
+	}
+	usec, err := strconv.ParseInt(usecStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s environment variable: %w", SystemdWatchdogUSecEnvVar, err)
+	}
+	if usec <= 0 {
+		return 0, fmt.Errorf("%s environment variable must be positive, got %d", SystemdWatchdogUSecEnvVar, usec)
+	}
+	return time.Duration(usec) * time.Microsecond, nil
+}
+
+// Notify sends the given messages (e.g., SystemdNotifyReady) to systemd in a single datagram.
+func (n *SystemdNotifier) Notify(messages ...string) error {
+	conn, err := net.DialUnix(n.socketAddr.Net, nil, n.socketAddr)
+	if err != nil {
+		return fmt.Errorf("dial systemd notify socket: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err = conn.Write([]byte(strings.Join(messages, "\n"))); err != nil {
+		return fmt.Errorf("write to systemd notify socket: %w", err)
+	}
+	return nil
+}
+
+// WatchdogInterval returns the interval within which systemd expects WATCHDOG=1 messages.
+// Zero value means that the watchdog is disabled.
+func (n *SystemdNotifier) WatchdogInterval() time.Duration {
+	return n.watchdogInterval
+}
+
+// systemdMessagesForState returns messages that should be sent to systemd when the service changes its state.
+func systemdMessagesForState(state LifecycleState) []string {
+	status := SystemdNotifyStatus(state.String())
+	switch state {
+	case LifecycleStateReady:
+		return []string{SystemdNotifyReady, status}
+	case LifecycleStateDraining:
+		return []string{SystemdNotifyStopping, status}
+	default:
+		return []string{status}
+	}
+}
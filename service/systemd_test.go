@@ -0,0 +1,142 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/acronis/go-appkit/log/logtest"
+)
+
+func listenSystemdNotifySocket(t *testing.T) (socketPath string, messages <-chan string) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "sdnotify") // t.TempDir() may exceed the max length of the unix socket path.
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	socketPath = filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	msgs := make(chan string, 100)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, readErr := conn.Read(buf)
+			if readErr != nil {
+				return
+			}
+			msgs <- string(buf[:n])
+		}
+	}()
+	return socketPath, msgs
+}
+
+func requireSystemdMessage(t *testing.T, messages <-chan string, want string) {
+	t.Helper()
+	select {
+	case msg := <-messages:
+		require.Equal(t, want, msg)
+	case <-time.After(time.Second):
+		require.Failf(t, "systemd message is not received", "expected %q", want)
+	}
+}
+
+func TestNewSystemdNotifierFromEnv(t *testing.T) {
+	t.Run("socket is not set", func(t *testing.T) {
+		t.Setenv(SystemdNotifySocketEnvVar, "")
+		notifier, err := NewSystemdNotifierFromEnv()
+		require.NoError(t, err)
+		require.Nil(t, notifier)
+	})
+
+	t.Run("watchdog is enabled", func(t *testing.T) {
+		t.Setenv(SystemdNotifySocketEnvVar, "@notify")
+		t.Setenv(SystemdWatchdogUSecEnvVar, "3000000")
+		t.Setenv(SystemdWatchdogPIDEnvVar, strconv.Itoa(os.Getpid()))
+		notifier, err := NewSystemdNotifierFromEnv()
+		require.NoError(t, err)
+		require.Equal(t, 3*time.Second, notifier.WatchdogInterval())
+	})
+
+	t.Run("watchdog is intended for another process", func(t *testing.T) {
+		t.Setenv(SystemdNotifySocketEnvVar, "@notify")
+		t.Setenv(SystemdWatchdogUSecEnvVar, "3000000")
+		t.Setenv(SystemdWatchdogPIDEnvVar, strconv.Itoa(os.Getpid()+1))
+		notifier, err := NewSystemdNotifierFromEnv()
+		require.NoError(t, err)
+		require.Zero(t, notifier.WatchdogInterval())
+	})
+
+	t.Run("invalid watchdog interval", func(t *testing.T) {
+		t.Setenv(SystemdNotifySocketEnvVar, "@notify")
+		t.Setenv(SystemdWatchdogUSecEnvVar, "abc")
+		_, err := NewSystemdNotifierFromEnv()
+		require.ErrorContains(t, err, "parse WATCHDOG_USEC environment variable")
+	})
+}
+
+func TestSystemdNotifier_Notify(t *testing.T) {
+	socketPath, messages := listenSystemdNotifySocket(t)
+	notifier := NewSystemdNotifier(socketPath, 0)
+
+	require.NoError(t, notifier.Notify(SystemdNotifyReady, SystemdNotifyStatus("ready")))
+	requireSystemdMessage(t, messages, "READY=1\nSTATUS=ready")
+
+	require.ErrorContains(t, NewSystemdNotifier(socketPath+".unknown", 0).Notify(SystemdNotifyReady),
+		"dial systemd notify socket")
+}
+
+func TestService_SystemdNotifications(t *testing.T) {
+	socketPath, messages := listenSystemdNotifySocket(t)
+	t.Setenv(SystemdNotifySocketEnvVar, socketPath)
+	t.Setenv(SystemdWatchdogUSecEnvVar, "")
+
+	service := New(logtest.NewRecorder(), &reloadableMockUnit{stagedMockUnit: newStagedMockUnit("srv", &eventsRecorder{})})
+	require.NotNil(t, service.Opts.SystemdNotifier)
+
+	startErr := make(chan error, 1)
+	go func() { startErr <- service.Start() }()
+	requireSystemdMessage(t, messages, "STATUS=starting")
+	requireSystemdMessage(t, messages, "READY=1\nSTATUS=ready")
+
+	require.NoError(t, service.Reload())
+	requireSystemdMessage(t, messages, "RELOADING=1\nSTATUS=reloading")
+	requireSystemdMessage(t, messages, "READY=1\nSTATUS=ready")
+
+	service.Signals <- os.Interrupt
+	require.NoError(t, <-startErr)
+	requireSystemdMessage(t, messages, "STOPPING=1\nSTATUS=draining")
+	requireSystemdMessage(t, messages, "STATUS=stopped")
+}
+
+func TestService_SystemdWatchdog(t *testing.T) {
+	socketPath, messages := listenSystemdNotifySocket(t)
+	unit := newStagedMockUnit("srv", &eventsRecorder{})
+	service := NewWithOpts(logtest.NewRecorder(), unit, Opts{
+		ShutdownSignals: []os.Signal{os.Interrupt},
+		SystemdNotifier: NewSystemdNotifier(socketPath, time.Millisecond*40),
+	})
+
+	startErr := make(chan error, 1)
+	go func() { startErr <- service.Start() }()
+	requireSystemdMessage(t, messages, "STATUS=starting")
+	requireSystemdMessage(t, messages, "READY=1\nSTATUS=ready")
+	requireSystemdMessage(t, messages, "WATCHDOG=1")
+	requireSystemdMessage(t, messages, "WATCHDOG=1")
+
+	service.Signals <- os.Interrupt
+	require.NoError(t, <-startErr)
+}
@@ -6,6 +6,13 @@ Released under MIT license.
 
 package service
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/acronis/go-appkit/log"
+)
+
 // Unit represents a service unit that can be started and stopped.
 // Each Unit is a distinct component within a service, with its own lifecycle.
 type Unit interface {
@@ -24,6 +31,9 @@ type Unit interface {
 	//
 	// If 'gracefully' is true, the unit should attempt a clean shutdown.
 	// Note that this method may be called even if Start has failed or was never called.
+	// It also may be called with 'gracefully' set to false while the graceful stop is still in progress
+	// (e.g., when Service's shutdown timeout is exceeded or one more shutdown signal is received),
+	// so concurrent calls must be safe, and the non-graceful one should interrupt the graceful one.
 	Stop(gracefully bool) error
 }
 
@@ -32,3 +42,82 @@ type MetricsRegisterer interface {
 	MustRegisterMetrics()
 	UnregisterMetrics()
 }
+
+// ReadinessNotifier is an interface for units that can notify when they are ready to serve (e.g., a server
+// starts listening or a connection pool is established) while their Start method may still be blocked.
+type ReadinessNotifier interface {
+	// Ready returns a channel that is closed when the unit becomes ready.
+	Ready() <-chan struct{}
+}
+
+// Reloadable is an interface for units that can be reloaded without restarting (e.g., re-read configuration
+// or reopen log files after rotation). Service calls the Reload method when any of reload signals is received.
+type Reloadable interface {
+	Reload() error
+}
+
+// reloadUnits calls the Reload method of all units that implement Reloadable and collects errors.
+func reloadUnits(units []Unit) []error {
+	var errs []error
+	for _, unit := range units {
+		if r, ok := unit.(Reloadable); ok {
+			if err := r.Reload(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// StopLoggerSetter is an interface for composite units that can log how long each of their units takes to stop.
+// Service passes its own logger to the unit if it implements this interface.
+type StopLoggerSetter interface {
+	// SetStopLogger sets the logger if no logger is set yet.
+	SetStopLogger(logger log.FieldLogger)
+}
+
+// unitName returns a name of the unit that is used in logs.
+// Units that implement fmt.Stringer are named by their String method, others by their type.
+func unitName(unit Unit) string {
+	if s, ok := unit.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", unit)
+}
+
+// stopUnit stops the unit, logs how long it took if the logger is not nil,
+// and publishes LifecycleEventUnitStopped event if events are not nil.
+func stopUnit(unit Unit, gracefully bool, logger log.FieldLogger, events *LifecycleEvents) error {
+	if logger == nil && events == nil {
+		return unit.Stop(gracefully)
+	}
+	startTime := time.Now()
+	err := unit.Stop(gracefully)
+	duration := time.Since(startTime)
+	name := unitName(unit)
+	events.Publish(LifecycleEvent{
+		Type: LifecycleEventUnitStopped, Unit: name, Duration: duration, Gracefully: gracefully, Err: err,
+	})
+	if logger == nil {
+		return err
+	}
+	fields := []log.Field{
+		log.String("unit", name),
+		log.Bool("gracefully", gracefully),
+		log.Duration("duration", duration),
+	}
+	if err != nil {
+		logger.Error("unit is stopped with error", append(fields, log.Error(err))...)
+		return err
+	}
+	logger.Info("unit is stopped", fields...)
+	return nil
+}
+
+func setStopLogger(units []Unit, logger log.FieldLogger) {
+	for _, unit := range units {
+		if sls, ok := unit.(StopLoggerSetter); ok {
+			sls.SetStopLogger(logger)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"runtime"
 	"time"
 
@@ -39,12 +40,26 @@ type PeriodicWorker struct {
 	initialDelay      time.Duration
 	intervalDelay     time.Duration
 	intervalDelayFunc func(worker Worker, err error) time.Duration
+	jitter            time.Duration
+	name              string
+	events            *LifecycleEvents
 }
 
 // PeriodicWorkerOpts contains optional parameters for constructing PeriodicWorker.
 type PeriodicWorkerOpts struct {
 	InitialDelay      time.Duration
 	IntervalDelayFunc func(worker Worker, err error) time.Duration
+
+	// Jitter is a maximum random delay that is added to every interval delay.
+	// Use ScheduledWorker for running the worker according to the cron-style schedule.
+	Jitter time.Duration
+
+	// Name is used in lifecycle events. The type name of the underlying worker is used by default.
+	Name string
+
+	// LifecycleEvents is used for publishing LifecycleEventWorkerRunFinished event after every run
+	// (see LifecyclePrometheusMetrics). Nothing is published if it's nil.
+	LifecycleEvents *LifecycleEvents
 }
 
 // NewPeriodicWorker creates a new instance of PeriodicWorker with constant delays.
@@ -62,6 +77,9 @@ func NewPeriodicWorkerWithOpts(
 		initialDelay:      opts.InitialDelay,
 		intervalDelay:     intervalDelay,
 		intervalDelayFunc: opts.IntervalDelayFunc,
+		jitter:            opts.Jitter,
+		name:              opts.Name,
+		events:            opts.LifecycleEvents,
 		logger:            logger,
 	}
 }
@@ -96,7 +114,7 @@ func (pw *PeriodicWorker) Run(ctx context.Context) (resErr error) {
 		case <-timer.C:
 		}
 
-		err := pw.worker.Run(ctx)
+		err := pw.runWorker(ctx)
 		if err != nil {
 			if errors.Is(err, ErrPeriodicWorkerStop) {
 				return nil
@@ -108,8 +126,32 @@ func (pw *PeriodicWorker) Run(ctx context.Context) (resErr error) {
 		if pw.intervalDelayFunc != nil {
 			nextDelay = pw.intervalDelayFunc(pw.worker, err)
 		}
+		if pw.jitter > 0 {
+			nextDelay += rand.N(pw.jitter) //nolint:gosec // Cryptographically secure random is not needed for jitter.
+		}
 
 		timer.Stop()
 		timer = time.NewTimer(nextDelay)
 	}
 }
+
+// runWorker runs the underlying worker once and publishes LifecycleEventWorkerRunFinished event.
+func (pw *PeriodicWorker) runWorker(ctx context.Context) (err error) {
+	if pw.events == nil {
+		return pw.worker.Run(ctx)
+	}
+	name := pw.name
+	if name == "" {
+		name = fmt.Sprintf("%T", pw.worker)
+	}
+	startTime := time.Now()
+	panicked := true
+	defer func() {
+		pw.events.Publish(LifecycleEvent{
+			Type: LifecycleEventWorkerRunFinished, Unit: name, Duration: time.Since(startTime), Err: err, Panicked: panicked,
+		})
+	}()
+	err = pw.worker.Run(ctx)
+	panicked = false
+	return err
+}
@@ -0,0 +1,330 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/acronis/go-appkit/log"
+)
+
+// ErrWorkerPoolQueueFull is an error that is returned by WorkerPool.TrySubmit when the job queue is full.
+var ErrWorkerPoolQueueFull = errors.New("worker pool queue is full")
+
+// ErrWorkerPoolStopped is an error that is returned when a job is submitted to the stopped WorkerPool.
+var ErrWorkerPoolStopped = errors.New("worker pool is stopped")
+
+// ErrWorkerPoolStopTimeoutExceeded is an error that occurs when WorkerPool's graceful stop timeout is exceeded.
+var ErrWorkerPoolStopTimeoutExceeded = errors.New("worker pool stop timeout exceeded")
+
+// Job failure reasons that are used in WorkerPool's metrics.
+const (
+	JobFailureReasonError   = "error"
+	JobFailureReasonTimeout = "timeout"
+	JobFailureReasonPanic   = "panic"
+)
+
+// Job represents a unit of work that is processed by WorkerPool.
+type Job interface {
+	Run(ctx context.Context) error
+}
+
+// JobFunc is an adapter to allow the use of ordinary functions as Job.
+type JobFunc func(ctx context.Context) error
+
+// Run is a part of Job interface.
+func (f JobFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+// DefaultWorkerPoolName is a default name of WorkerPool that is used in logs and metrics.
+const DefaultWorkerPoolName = "default"
+
+// WorkerPoolOpts contains optional parameters for constructing WorkerPool.
+type WorkerPoolOpts struct {
+	// Name is used in logs and metrics. DefaultWorkerPoolName is used if empty.
+	Name string
+
+	// QueueSize is a maximum number of jobs waiting for processing.
+	// If zero, the queue size is equal to the number of workers.
+	QueueSize int
+
+	// JobTimeout limits the time of processing each job. Zero value means no timeout.
+	JobTimeout time.Duration
+
+	// GracefulStopTimeout limits the time of processing the remaining jobs on graceful stop.
+	// When it's exceeded, contexts of the jobs in progress are canceled and the queued jobs are dropped.
+	// Zero value means no timeout.
+	GracefulStopTimeout time.Duration
+
+	// MetricsCollector collects metrics of the worker pool (e.g., WorkerPoolPrometheusMetrics).
+	MetricsCollector WorkerPoolMetricsCollector
+}
+
+type queuedJob struct {
+	ctx context.Context
+	job Job
+}
+
+// WorkerPool is a service unit that owns a fixed number of goroutines (workers) processing jobs
+// from a bounded in-memory queue.
+//
+// Each job is run with the context that carries values of the context passed on the submission,
+// but is canceled only on the job timeout or on the non-graceful stop of the pool.
+// A panic in a job is recovered, logged and counted as the job failure (JobFailureReasonPanic),
+// so it doesn't crash the process, and the worker continues processing other jobs.
+type WorkerPool struct {
+	name                string
+	concurrency         int
+	logger              log.FieldLogger
+	jobTimeout          time.Duration
+	gracefulStopTimeout time.Duration
+	metricsCollector    WorkerPoolMetricsCollector
+
+	queue chan queuedJob
+
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+
+	started atomic.Bool
+
+	mu       sync.RWMutex
+	stopped  bool
+	stopping chan struct{} // closed at the beginning of Stop to interrupt blocked submissions
+	drain    chan struct{} // closed when no more jobs can be submitted
+	stopOnce sync.Once
+	done     chan struct{} // closed when all workers exit
+}
+
+var _ Unit = (*WorkerPool)(nil)
+var _ MetricsRegisterer = (*WorkerPool)(nil)
+
+// NewWorkerPool creates a new WorkerPool with the given number of workers.
+func NewWorkerPool(concurrency int, logger log.FieldLogger) *WorkerPool {
+	return NewWorkerPoolWithOpts(concurrency, logger, WorkerPoolOpts{})
+}
+
+// NewWorkerPoolWithOpts creates a new WorkerPool with the given number of workers
+// and an ability to specify different optional parameters.
+func NewWorkerPoolWithOpts(concurrency int, logger log.FieldLogger, opts WorkerPoolOpts) *WorkerPool {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if opts.Name == "" {
+		opts.Name = DefaultWorkerPoolName
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = concurrency
+	}
+	if opts.MetricsCollector == nil {
+		opts.MetricsCollector = disabledWorkerPoolMetrics{}
+	}
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	return &WorkerPool{
+		name:                opts.Name,
+		concurrency:         concurrency,
+		logger:              logger.With(log.String("worker_pool", opts.Name)),
+		jobTimeout:          opts.JobTimeout,
+		gracefulStopTimeout: opts.GracefulStopTimeout,
+		metricsCollector:    opts.MetricsCollector,
+		queue:               make(chan queuedJob, opts.QueueSize),
+		ctx:                 ctx,
+		ctxCancel:           ctxCancel,
+		stopping:            make(chan struct{}),
+		drain:               make(chan struct{}),
+		done:                make(chan struct{}),
+	}
+}
+
+// Submit adds the job to the queue. If the queue is full, it blocks until there is free space in the queue,
+// the context is canceled or the pool is stopped.
+func (p *WorkerPool) Submit(ctx context.Context, job Job) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return ErrWorkerPoolStopped
+	}
+	select {
+	case p.queue <- queuedJob{ctx: ctx, job: job}:
+		p.metricsCollector.SetQueueLength(p.name, len(p.queue))
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.stopping:
+		return ErrWorkerPoolStopped
+	}
+}
+
+// TrySubmit adds the job to the queue without blocking. It returns ErrWorkerPoolQueueFull if the queue is full.
+// The context is used only for passing its values to the job.
+func (p *WorkerPool) TrySubmit(ctx context.Context, job Job) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return ErrWorkerPoolStopped
+	}
+	select {
+	case p.queue <- queuedJob{ctx: ctx, job: job}:
+		p.metricsCollector.SetQueueLength(p.name, len(p.queue))
+		return nil
+	default:
+		return ErrWorkerPoolQueueFull
+	}
+}
+
+// Start runs workers. It blocks until all workers exit after stopping the pool.
+func (p *WorkerPool) Start(fatalError chan<- error) {
+	if !p.started.CompareAndSwap(false, true) {
+		return
+	}
+
+	p.logger.Infof("starting worker pool (concurrency=%d, queueSize=%d)...", p.concurrency, cap(p.queue))
+	var workersWG sync.WaitGroup
+	workersWG.Add(p.concurrency)
+	for i := 0; i < p.concurrency; i++ {
+		go func() {
+			defer workersWG.Done()
+			p.runWorker()
+		}()
+	}
+	workersWG.Wait()
+	close(p.done)
+	p.logger.Info("worker pool is stopped")
+}
+
+func (p *WorkerPool) runWorker() {
+	for {
+		select {
+		case qj := <-p.queue:
+			p.processOrDropJob(qj)
+		case <-p.drain:
+			p.drainQueue()
+			return
+		}
+	}
+}
+
+// drainQueue processes the remaining jobs on graceful stop or drops them on non-graceful one.
+func (p *WorkerPool) drainQueue() {
+	for {
+		select {
+		case qj := <-p.queue:
+			p.processOrDropJob(qj)
+		default:
+			return
+		}
+	}
+}
+
+func (p *WorkerPool) processOrDropJob(qj queuedJob) {
+	if p.ctx.Err() != nil {
+		p.metricsCollector.SetQueueLength(p.name, len(p.queue))
+		p.logger.Warn("queued job is dropped since the worker pool is stopped non-gracefully")
+		return
+	}
+	p.processJob(qj)
+}
+
+func (p *WorkerPool) processJob(qj queuedJob) {
+	p.metricsCollector.SetQueueLength(p.name, len(p.queue))
+	p.metricsCollector.IncJobsInProgress(p.name)
+	startTime := time.Now()
+	defer func() {
+		p.metricsCollector.DecJobsInProgress(p.name)
+		p.metricsCollector.ObserveJobDuration(p.name, time.Since(startTime))
+	}()
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(qj.ctx))
+	defer cancel()
+	stopCancelOnPoolStop := context.AfterFunc(p.ctx, cancel)
+	defer stopCancelOnPoolStop()
+	if p.jobTimeout > 0 {
+		var timeoutCancel context.CancelFunc
+		ctx, timeoutCancel = context.WithTimeout(ctx, p.jobTimeout)
+		defer timeoutCancel()
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			const logStackSize = 8192
+			stack := make([]byte, logStackSize)
+			stack = stack[:runtime.Stack(stack, false)]
+			p.logger.Error(fmt.Sprintf("panic: %+v", rec), log.Bytes("stack", stack))
+			p.metricsCollector.IncJobFailures(p.name, JobFailureReasonPanic)
+		}
+	}()
+
+	if err := qj.job.Run(ctx); err != nil {
+		reason := JobFailureReasonError
+		if errors.Is(err, context.DeadlineExceeded) && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			reason = JobFailureReasonTimeout
+		}
+		p.logger.Error("worker pool job finished with error", log.Error(err), log.String("reason", reason))
+		p.metricsCollector.IncJobFailures(p.name, reason)
+	}
+}
+
+// Stop stops the worker pool. No more jobs can be submitted after calling this method.
+//
+// If gracefully is true, the method blocks until all queued jobs and jobs in progress are processed,
+// but not longer than GracefulStopTimeout (ErrWorkerPoolStopTimeoutExceeded is returned in this case).
+// Otherwise, contexts of the jobs in progress are canceled, queued jobs are dropped, and the method returns immediately.
+func (p *WorkerPool) Stop(gracefully bool) error {
+	p.stopOnce.Do(func() {
+		close(p.stopping)
+		p.mu.Lock()
+		p.stopped = true
+		p.mu.Unlock()
+		if !gracefully {
+			// Context is canceled before closing the drain channel, so workers drop the queued jobs.
+			p.ctxCancel()
+		}
+		close(p.drain)
+	})
+	if !gracefully {
+		p.ctxCancel()
+		return nil
+	}
+	if !p.started.Load() {
+		return nil // Queued jobs will be processed if Start is called later.
+	}
+
+	var timeout <-chan time.Time
+	if p.gracefulStopTimeout > 0 {
+		timer := time.NewTimer(p.gracefulStopTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-p.done:
+		p.ctxCancel()
+		return nil
+	case <-timeout:
+		p.ctxCancel()
+		return ErrWorkerPoolStopTimeoutExceeded
+	}
+}
+
+// MustRegisterMetrics registers WorkerPool's metrics if the metrics collector supports registration.
+func (p *WorkerPool) MustRegisterMetrics() {
+	if r, ok := p.metricsCollector.(interface{ MustRegister() }); ok {
+		r.MustRegister()
+	}
+}
+
+// UnregisterMetrics unregisters WorkerPool's metrics if the metrics collector supports registration.
+func (p *WorkerPool) UnregisterMetrics() {
+	if r, ok := p.metricsCollector.(interface{ Unregister() }); ok {
+		r.Unregister()
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/acronis/go-appkit/internal/libinfo"
+)
+
+// DefaultWorkerPoolJobDurationBuckets is default buckets into which observations of processing jobs are counted.
+var DefaultWorkerPoolJobDurationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 150, 300, 600}
+
+// WorkerPoolMetricsCollector represents a collector of metrics for WorkerPool.
+type WorkerPoolMetricsCollector interface {
+	// SetQueueLength sets the current number of jobs waiting in the queue.
+	SetQueueLength(poolName string, length int)
+	// IncJobsInProgress increments the number of jobs that are being processed.
+	IncJobsInProgress(poolName string)
+	// DecJobsInProgress decrements the number of jobs that are being processed.
+	DecJobsInProgress(poolName string)
+	// ObserveJobDuration observes the duration of the job processing.
+	ObserveJobDuration(poolName string, duration time.Duration)
+	// IncJobFailures increments the total number of failed jobs.
+	IncJobFailures(poolName string, reason string)
+}
+
+// WorkerPoolPrometheusMetricsOpts represents options for WorkerPoolPrometheusMetrics.
+type WorkerPoolPrometheusMetricsOpts struct {
+	// Namespace is a namespace for metrics. It will be prepended to all metric names.
+	Namespace string
+
+	// DurationBuckets is a list of buckets into which observations of processing jobs are counted.
+	// DefaultWorkerPoolJobDurationBuckets is used if nil.
+	DurationBuckets []float64
+
+	// ConstLabels is a set of labels that will be applied to all metrics.
+	ConstLabels prometheus.Labels
+}
+
+// WorkerPoolPrometheusMetrics represents a Prometheus metrics for WorkerPool.
+type WorkerPoolPrometheusMetrics struct {
+	QueueLength    *prometheus.GaugeVec
+	JobsInProgress *prometheus.GaugeVec
+	JobDuration    *prometheus.HistogramVec
+	JobFailures    *prometheus.CounterVec
+}
+
+// NewWorkerPoolPrometheusMetrics creates a new instance of WorkerPoolPrometheusMetrics with default options.
+func NewWorkerPoolPrometheusMetrics() *WorkerPoolPrometheusMetrics {
+	return NewWorkerPoolPrometheusMetricsWithOpts(WorkerPoolPrometheusMetricsOpts{})
+}
+
+// NewWorkerPoolPrometheusMetricsWithOpts creates a new instance of WorkerPoolPrometheusMetrics with the provided options.
+func NewWorkerPoolPrometheusMetricsWithOpts(opts WorkerPoolPrometheusMetricsOpts) *WorkerPoolPrometheusMetrics {
+	durBuckets := opts.DurationBuckets
+	if durBuckets == nil {
+		durBuckets = DefaultWorkerPoolJobDurationBuckets
+	}
+	constLabels := libinfo.AddPrometheusLibVersionLabel(opts.ConstLabels)
+	return &WorkerPoolPrometheusMetrics{
+		QueueLength: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "worker_pool_queue_length",
+			Help:        "Number of jobs waiting in the worker pool queue.",
+			ConstLabels: constLabels,
+		}, []string{"pool"}),
+		JobsInProgress: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Name:        "worker_pool_jobs_in_progress",
+			Help:        "Number of jobs that are being processed by the worker pool.",
+			ConstLabels: constLabels,
+		}, []string{"pool"}),
+		JobDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Name:        "worker_pool_job_duration_seconds",
+			Help:        "A histogram of the worker pool job durations.",
+			Buckets:     durBuckets,
+			ConstLabels: constLabels,
+		}, []string{"pool"}),
+		JobFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "worker_pool_job_failures_total",
+			Help:        "Number of failed worker pool jobs.",
+			ConstLabels: constLabels,
+		}, []string{"pool", "reason"}),
+	}
+}
+
+// SetQueueLength sets the current number of jobs waiting in the queue.
+func (pm *WorkerPoolPrometheusMetrics) SetQueueLength(poolName string, length int) {
+	pm.QueueLength.WithLabelValues(poolName).Set(float64(length))
+}
+
+// IncJobsInProgress increments the number of jobs that are being processed.
+func (pm *WorkerPoolPrometheusMetrics) IncJobsInProgress(poolName string) {
+	pm.JobsInProgress.WithLabelValues(poolName).Inc()
+}
+
+// DecJobsInProgress decrements the number of jobs that are being processed.
+func (pm *WorkerPoolPrometheusMetrics) DecJobsInProgress(poolName string) {
+	pm.JobsInProgress.WithLabelValues(poolName).Dec()
+}
+
+// ObserveJobDuration observes the duration of the job processing.
+func (pm *WorkerPoolPrometheusMetrics) ObserveJobDuration(poolName string, duration time.Duration) {
+	pm.JobDuration.WithLabelValues(poolName).Observe(duration.Seconds())
+}
+
+// IncJobFailures increments the total number of failed jobs.
+func (pm *WorkerPoolPrometheusMetrics) IncJobFailures(poolName string, reason string) {
+	pm.JobFailures.WithLabelValues(poolName, reason).Inc()
+}
+
+// MustRegister does registration of metrics collector in Prometheus and panics if any error occurs.
+func (pm *WorkerPoolPrometheusMetrics) MustRegister() {
+	prometheus.MustRegister(pm.QueueLength, pm.JobsInProgress, pm.JobDuration, pm.JobFailures)
+}
+
+// Unregister cancels registration of metrics collector in Prometheus.
+func (pm *WorkerPoolPrometheusMetrics) Unregister() {
+	prometheus.Unregister(pm.QueueLength)
+	prometheus.Unregister(pm.JobsInProgress)
+	prometheus.Unregister(pm.JobDuration)
+	prometheus.Unregister(pm.JobFailures)
+}
+
+type disabledWorkerPoolMetrics struct{}
+
+func (disabledWorkerPoolMetrics) SetQueueLength(string, int)               {}
+func (disabledWorkerPoolMetrics) IncJobsInProgress(string)                 {}
+func (disabledWorkerPoolMetrics) DecJobsInProgress(string)                 {}
+func (disabledWorkerPoolMetrics) ObserveJobDuration(string, time.Duration) {}
+func (disabledWorkerPoolMetrics) IncJobFailures(string, string)            {}
@@ -0,0 +1,236 @@
+/*
+Copyright © 2024 Acronis International GmbH.
+
+Released under MIT license.
+*/
+
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/acronis/go-appkit/log"
+	"github.com/acronis/go-appkit/log/logtest"
+)
+
+type ctxKey struct{}
+
+func startWorkerPool(t *testing.T, pool *WorkerPool) <-chan struct{} {
+	t.Helper()
+	startExit := make(chan struct{})
+	go func() {
+		defer close(startExit)
+		pool.Start(make(chan error, 1))
+	}()
+	return startExit
+}
+
+func requireClosed(t *testing.T, ch <-chan struct{}, msg string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		require.Fail(t, msg)
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	t.Run("jobs are processed with bounded concurrency", func(t *testing.T) {
+		pool := NewWorkerPool(2, log.NewDisabledLogger())
+		startExit := startWorkerPool(t, pool)
+
+		var inProgress, maxInProgress, processed atomic.Int32
+		for i := 0; i < 10; i++ {
+			require.NoError(t, pool.Submit(context.Background(), JobFunc(func(ctx context.Context) error {
+				cur := inProgress.Add(1)
+				defer inProgress.Add(-1)
+				for {
+					prevMax := maxInProgress.Load()
+					if cur <= prevMax || maxInProgress.CompareAndSwap(prevMax, cur) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond * 10)
+				processed.Add(1)
+				return nil
+			})))
+		}
+
+		require.NoError(t, pool.Stop(true))
+		requireClosed(t, startExit, "worker pool is not stopped")
+		require.Equal(t, int32(10), processed.Load())
+		require.LessOrEqual(t, maxInProgress.Load(), int32(2))
+		require.ErrorIs(t, pool.Submit(context.Background(), JobFunc(func(ctx context.Context) error { return nil })),
+			ErrWorkerPoolStopped)
+	})
+
+	t.Run("try submit to full queue", func(t *testing.T) {
+		pool := NewWorkerPoolWithOpts(1, log.NewDisabledLogger(), WorkerPoolOpts{QueueSize: 1})
+		release := make(chan struct{})
+		blockingJob := JobFunc(func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+		require.NoError(t, pool.TrySubmit(context.Background(), blockingJob))
+		require.ErrorIs(t, pool.TrySubmit(context.Background(), blockingJob), ErrWorkerPoolQueueFull)
+
+		submitCtx, submitCancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+		defer submitCancel()
+		require.ErrorIs(t, pool.Submit(submitCtx, blockingJob), context.DeadlineExceeded)
+
+		startExit := startWorkerPool(t, pool)
+		close(release)
+		require.NoError(t, pool.Stop(true))
+		requireClosed(t, startExit, "worker pool is not stopped")
+	})
+
+	t.Run("job context", func(t *testing.T) {
+		pool := NewWorkerPoolWithOpts(1, log.NewDisabledLogger(), WorkerPoolOpts{JobTimeout: time.Millisecond * 20})
+		startExit := startWorkerPool(t, pool)
+
+		jobErrs := make(chan error, 1)
+		submitCtx, submitCancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "value"))
+		require.NoError(t, pool.Submit(submitCtx, JobFunc(func(ctx context.Context) error {
+			if ctx.Value(ctxKey{}) != "value" {
+				jobErrs <- errors.New("context value is lost")
+				return nil
+			}
+			<-ctx.Done()
+			jobErrs <- ctx.Err()
+			return nil
+		})))
+		submitCancel() // Canceling of the submission context must not cancel the job.
+
+		select {
+		case err := <-jobErrs:
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+		case <-time.After(time.Second):
+			require.Fail(t, "job is not finished")
+		}
+		require.NoError(t, pool.Stop(true))
+		requireClosed(t, startExit, "worker pool is not stopped")
+	})
+
+	t.Run("non-graceful stop", func(t *testing.T) {
+		logRecorder := logtest.NewRecorder()
+		pool := NewWorkerPoolWithOpts(1, logRecorder, WorkerPoolOpts{QueueSize: 2})
+		startExit := startWorkerPool(t, pool)
+
+		jobStarted := make(chan struct{})
+		var canceled atomic.Bool
+		require.NoError(t, pool.Submit(context.Background(), JobFunc(func(ctx context.Context) error {
+			close(jobStarted)
+			<-ctx.Done()
+			canceled.Store(true)
+			return nil
+		})))
+		<-jobStarted
+		var droppedJobRun atomic.Bool
+		require.NoError(t, pool.Submit(context.Background(), JobFunc(func(ctx context.Context) error {
+			droppedJobRun.Store(true)
+			return nil
+		})))
+
+		require.NoError(t, pool.Stop(false))
+		requireClosed(t, startExit, "worker pool is not stopped")
+		require.True(t, canceled.Load())
+		require.False(t, droppedJobRun.Load())
+		logtest.RequireEntry(t, logRecorder,
+			logtest.HasMessage("queued job is dropped since the worker pool is stopped non-gracefully"))
+	})
+
+	t.Run("graceful stop timeout exceeded", func(t *testing.T) {
+		pool := NewWorkerPoolWithOpts(1, log.NewDisabledLogger(), WorkerPoolOpts{GracefulStopTimeout: time.Millisecond * 20})
+		startExit := startWorkerPool(t, pool)
+
+		jobStarted := make(chan struct{})
+		require.NoError(t, pool.Submit(context.Background(), JobFunc(func(ctx context.Context) error {
+			close(jobStarted)
+			<-ctx.Done()
+			return nil
+		})))
+		<-jobStarted
+
+		require.ErrorIs(t, pool.Stop(true), ErrWorkerPoolStopTimeoutExceeded)
+		requireClosed(t, startExit, "worker pool is not stopped")
+	})
+
+	t.Run("stop before start", func(t *testing.T) {
+		pool := NewWorkerPool(1, log.NewDisabledLogger())
+		require.NoError(t, pool.Stop(true))
+		requireClosed(t, startWorkerPool(t, pool), "worker pool is not stopped")
+	})
+}
+
+func TestWorkerPool_FailuresAndMetrics(t *testing.T) {
+	logRecorder := logtest.NewRecorder()
+	metrics := NewWorkerPoolPrometheusMetrics()
+	pool := NewWorkerPoolWithOpts(2, logRecorder, WorkerPoolOpts{
+		Name:             "uploads",
+		QueueSize:        10,
+		JobTimeout:       time.Millisecond * 20,
+		MetricsCollector: metrics,
+	})
+	pool.MustRegisterMetrics()
+	defer pool.UnregisterMetrics()
+
+	jobs := []JobFunc{
+		func(ctx context.Context) error { return nil },
+		func(ctx context.Context) error { return errors.New("internal error") },
+		func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	for _, job := range jobs {
+		require.NoError(t, pool.Submit(context.Background(), job))
+	}
+	require.Equal(t, 3.0, testutil.ToFloat64(metrics.QueueLength.WithLabelValues("uploads")))
+
+	startExit := startWorkerPool(t, pool)
+	require.Eventually(t, func() bool {
+		return testutil.CollectAndCount(metrics.JobFailures) == 2
+	}, time.Second, time.Millisecond*10)
+	require.NoError(t, pool.Stop(true))
+	requireClosed(t, startExit, "worker pool is not stopped")
+
+	require.Equal(t, 0.0, testutil.ToFloat64(metrics.QueueLength.WithLabelValues("uploads")))
+	require.Equal(t, 0.0, testutil.ToFloat64(metrics.JobsInProgress.WithLabelValues("uploads")))
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.JobFailures.WithLabelValues("uploads", JobFailureReasonError)))
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.JobFailures.WithLabelValues("uploads", JobFailureReasonTimeout)))
+	require.Equal(t, 1, testutil.CollectAndCount(metrics.JobDuration))
+
+	logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelError),
+		logtest.HasMessage("worker pool job finished with error"), logtest.FieldEquals("reason", JobFailureReasonTimeout))
+}
+
+func TestWorkerPool_JobPanic(t *testing.T) {
+	logRecorder := logtest.NewRecorder()
+	metrics := NewWorkerPoolPrometheusMetrics()
+	pool := NewWorkerPoolWithOpts(1, logRecorder, WorkerPoolOpts{Name: "uploads", MetricsCollector: metrics})
+	startExit := startWorkerPool(t, pool)
+
+	require.NoError(t, pool.Submit(context.Background(), JobFunc(func(ctx context.Context) error {
+		panic("job panic")
+	})))
+	nextJobDone := make(chan struct{})
+	require.NoError(t, pool.Submit(context.Background(), JobFunc(func(ctx context.Context) error {
+		close(nextJobDone)
+		return nil
+	})))
+	requireClosed(t, nextJobDone, "job after the panicking one is not processed")
+
+	require.NoError(t, pool.Stop(true))
+	requireClosed(t, startExit, "worker pool is not stopped")
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.JobFailures.WithLabelValues("uploads", JobFailureReasonPanic)))
+	require.Equal(t, 0.0, testutil.ToFloat64(metrics.JobsInProgress.WithLabelValues("uploads")))
+	logtest.RequireEntry(t, logRecorder, logtest.HasLevel(log.LevelError), logtest.HasMessage("panic: job panic"),
+		logtest.HasField("stack"), logtest.FieldEquals("worker_pool", "uploads"))
+}
@@ -9,6 +9,7 @@ package service
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 )
 
@@ -20,8 +21,11 @@ type WorkerUnit struct {
 	start             func(fatalError chan<- error)
 	stop              func(gracefully bool) error
 	metricsRegisterer MetricsRegisterer
+	ready             chan struct{}
 }
 
+var _ ReadinessNotifier = (*WorkerUnit)(nil)
+
 // WorkerUnitOpts contains optional parameters for constructing PeriodicWorker.
 type WorkerUnitOpts struct {
 	MetricsRegisterer   MetricsRegisterer
@@ -38,8 +42,11 @@ func NewWorkerUnit(worker Worker) *WorkerUnit {
 func NewWorkerUnitWithOpts(worker Worker, opts WorkerUnitOpts) *WorkerUnit {
 	ctx, ctxCancel := context.WithCancel(context.Background())
 	stopDone := make(chan struct{}, 1)
+	ready := make(chan struct{})
+	var readyOnce sync.Once
 
 	start := func(fatalError chan<- error) {
+		readyOnce.Do(func() { close(ready) })
 		if err := worker.Run(ctx); err != nil {
 			fatalError <- err
 		}
@@ -63,7 +70,7 @@ func NewWorkerUnitWithOpts(worker Worker, opts WorkerUnitOpts) *WorkerUnit {
 		return nil
 	}
 
-	return &WorkerUnit{start: start, stop: stop, metricsRegisterer: opts.MetricsRegisterer}
+	return &WorkerUnit{start: start, stop: stop, metricsRegisterer: opts.MetricsRegisterer, ready: ready}
 }
 
 // Start starts (call Run() method) underlying Worker.
@@ -71,6 +78,12 @@ func (u *WorkerUnit) Start(fatalError chan<- error) {
 	u.start(fatalError)
 }
 
+// Ready returns a channel that is closed when the underlying Worker is being run.
+// Since Start blocks until Run returns, it allows starting units that depend on the worker (see StagedCompositeUnit).
+func (u *WorkerUnit) Ready() <-chan struct{} {
+	return u.ready
+}
+
 // Stop stops underlying Worker.
 func (u *WorkerUnit) Stop(gracefully bool) error {
 	return u.stop(gracefully)